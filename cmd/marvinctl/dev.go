@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joaoh82/marvinblockchain/dev"
+	"github.com/spf13/cobra"
+)
+
+// devCmd represents the dev command
+var devCmd = &cobra.Command{
+	Use:   "dev",
+	Short: "Run a development node",
+	Long: `Run a single process development node with pre-funded accounts.
+A block is sealed as soon as a transaction enters the mempool, or on a fixed interval when a block time is given.
+The node serves the JSON-RPC API, including the eth_ methods, on the RPC address.`,
+	Example: "Usage: marvinctl dev --accounts 5 --block-time 2s",
+	Run: func(cmd *cobra.Command, args []string) {
+		config := dev.DefaultConfig
+		config.Mnemonic, _ = cmd.Flags().GetString("mnemonic")
+		config.Accounts, _ = cmd.Flags().GetInt("accounts")
+		config.Balance, _ = cmd.Flags().GetUint64("balance")
+		config.BlockTime, _ = cmd.Flags().GetDuration("block-time")
+		config.RPCAddr, _ = cmd.Flags().GetString("rpc-addr")
+		config.ChainID, _ = cmd.Flags().GetString("chain-id")
+
+		node, err := dev.NewNode(config)
+		if err != nil {
+			fmt.Println("Error creating dev node:", err)
+			return
+		}
+
+		fmt.Println("mnemonic:", config.Mnemonic)
+		fmt.Println("accounts:")
+		for i, account := range node.Accounts() {
+			fmt.Printf("(%d) address: %s private key: %s balance: %d\n", i, account.PublicKey().Address(), account, config.Balance)
+		}
+
+		if err := node.Start(); err != nil {
+			fmt.Println("Error starting dev node:", err)
+			return
+		}
+		defer node.Stop()
+		if node.RPC() != nil {
+			fmt.Println("rpc:", node.RPC().Addr())
+		}
+
+		// Run until interrupted
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+	},
+}
+
+func init() {
+	devCmd.Flags().String("mnemonic", dev.DefaultMnemonic, "The mnemonic the pre-funded accounts are derived from")
+	devCmd.Flags().Int("accounts", dev.DefaultAccounts, "The number of pre-funded accounts")
+	devCmd.Flags().Uint64("balance", dev.DefaultBalance, "The genesis balance of every account")
+	devCmd.Flags().Duration("block-time", 0, "Seal blocks on this interval instead of on every transaction")
+	devCmd.Flags().String("rpc-addr", dev.DefaultConfig.RPCAddr, "The address of the JSON-RPC server, empty to disable it")
+	devCmd.Flags().String("chain-id", dev.DefaultChainID, "The chain ID the eth_chainId of the RPC is derived from")
+}
@@ -30,6 +30,7 @@ var versionCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(addressCmd)
+	rootCmd.AddCommand(devCmd)
 	rootCmd.SetVersionTemplate("marvinclt v0.0.1 -- HEAD")
 	addressCmd.AddCommand(addressCreateCmd)
 	addressCmd.AddCommand(mnemonicAddressRestoreCmd)
@@ -0,0 +1,75 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+)
+
+// ErrUnsupportedTransaction is returned by engines for transaction types they do not handle
+var ErrUnsupportedTransaction = errors.New("transaction type is not supported by the consensus engine")
+
+// StateReader gives read access to the account state
+type StateReader interface {
+	// Balance returns the balance of the account
+	Balance(address crypto.Address) uint64
+	// Nonce returns the nonce of the last transaction sent by the account
+	Nonce(address crypto.Address) int64
+	// Get returns the consensus engine data stored under the key, or nil
+	Get(key string) []byte
+}
+
+// State is the account state consensus engines modify when applying blocks
+type State interface {
+	StateReader
+	// AddBalance credits the account
+	AddBalance(address crypto.Address, amount uint64) error
+	// SubBalance debits the account, failing if the balance is not enough
+	SubBalance(address crypto.Address, amount uint64) error
+	// Set stores consensus engine data under the key, a nil value deletes the key
+	Set(key string, value []byte)
+}
+
+// ChainReader gives consensus engines read access to the headers and the state of the blockchain
+type ChainReader interface {
+	Height() int
+	GetHeaderByHeight(height int) (*proto.Header, error)
+	// State returns the account state at the last block of the blockchain
+	State() StateReader
+}
+
+// Engine is a consensus engine. The blockchain delegates to it every consensus specific rule:
+// how headers are prepared and verified, how blocks are sealed and how producers are rewarded.
+type Engine interface {
+	// Prepare initializes the consensus fields of a new header on top of the parent.
+	// The parent is nil for the genesis header.
+	Prepare(chain ChainReader, parent *proto.Header, header *proto.Header) error
+
+	// VerifyHeader checks the consensus rules of a header on top of the parent.
+	// It does not require the header to be part of the chain, so header chains can be verified before the blocks are downloaded.
+	VerifyHeader(chain ChainReader, parent *proto.Header, header *proto.Header) error
+
+	// VerifyBlock checks the consensus rules that require the full block, like the block signer
+	VerifyBlock(chain ChainReader, b *proto.Block) error
+
+	// Seal seals the block so it is valid under the consensus rules, signing it with the signer key
+	Seal(chain ChainReader, b *proto.Block, signer *crypto.PrivateKey) error
+
+	// ApplyTransaction applies the state changes of a special (non transfer and non coinbase) transaction.
+	// The blockchain already charged the fee and checked the nonce of the transaction.
+	ApplyTransaction(chain ChainReader, state State, header *proto.Header, tx *proto.Transaction) error
+
+	// Finalize applies the end of block state changes after all the transactions of the block were applied.
+	// The genesis block is finalized too, so engines can initialize their state.
+	Finalize(chain ChainReader, state State, b *proto.Block) error
+
+	// Reward returns the block reward the producer of the block at the given height can claim with the coinbase transaction
+	Reward(height uint64) uint64
+}
+
+// UnsupportedTransaction returns the error for a transaction type the engine does not handle
+func UnsupportedTransaction(tx *proto.Transaction) error {
+	return fmt.Errorf("%w: %s", ErrUnsupportedTransaction, tx.GetType())
+}
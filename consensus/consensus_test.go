@@ -0,0 +1,15 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsupportedTransaction(t *testing.T) {
+	err := UnsupportedTransaction(&proto.Transaction{Type: proto.TxType_TX_TYPE_VOTE})
+	assert.True(t, errors.Is(err, ErrUnsupportedTransaction))
+	assert.Contains(t, err.Error(), "TX_TYPE_VOTE")
+}
@@ -0,0 +1,351 @@
+package finality
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// voteDomain separates the checkpoint vote signatures from the other signatures of a validator key
+const voteDomain = "marvin/checkpoint-vote"
+
+// DefaultCheckpointInterval is the number of blocks between two checkpoints
+const DefaultCheckpointInterval = 10
+
+// ErrInvalidVote is wrapped by the errors of votes that can never be tallied, as opposed to votes
+// for a checkpoint not known yet or for another block, which may be valid on another fork
+var ErrInvalidVote = errors.New("invalid checkpoint vote")
+
+// errKnownVote is returned when the vote of a validator for a checkpoint is already tallied
+var errKnownVote = errors.New("checkpoint vote already tallied")
+
+// Chain is the blockchain the finality gadget finalizes checkpoints of
+type Chain interface {
+	consensus.ChainReader
+	FinalizedHeight() int
+	SetFinalized(height int, hash []byte) error
+	OnBlockAdded(handler func(b *proto.Block))
+}
+
+// ValidatorSet provides the voting weight of each validator, keyed by public key
+type ValidatorSet interface {
+	Weights(chain consensus.ChainReader) (map[string]uint64, error)
+}
+
+// StaticValidatorSet is a fixed validator set where every validator has the same weight
+type StaticValidatorSet [][]byte
+
+// Weights returns a weight of one for every validator
+func (s StaticValidatorSet) Weights(chain consensus.ChainReader) (map[string]uint64, error) {
+	weights := make(map[string]uint64, len(s))
+	for _, publicKey := range s {
+		weights[string(publicKey)] = 1
+	}
+	return weights, nil
+}
+
+// Gadget is a BFT finality layer on top of the consensus engine. Every interval blocks there is a checkpoint
+// the validators sign votes for. A checkpoint whose block hash receives votes from more than two thirds
+// of the validators weight is finalized, and the blockchain refuses to replace it or any block below it.
+// The votes for a checkpoint are weighed with the validator set recorded when the checkpoint block was added,
+// so later changes of the validator set do not change who can finalize it.
+type Gadget struct {
+	lock       sync.Mutex
+	chain      Chain
+	validators ValidatorSet
+	interval   uint64
+	// votes holds the votes received for each checkpoint height, keyed by voter public key
+	votes map[uint64]map[string]*proto.CheckpointVote
+	// weights holds the validator weights of each checkpoint height not finalized yet, keyed by public key
+	weights map[uint64]map[string]uint64
+	// handlers are notified of every vote tallied
+	handlers []func(vote *proto.CheckpointVote)
+}
+
+// New creates a finality gadget for the chain with the given validator set and checkpoint interval
+func New(chain Chain, validators ValidatorSet, interval uint64) (*Gadget, error) {
+	if interval == 0 {
+		return nil, fmt.Errorf("checkpoint interval must be greater than zero")
+	}
+
+	g := &Gadget{
+		chain:      chain,
+		validators: validators,
+		interval:   interval,
+		votes:      make(map[uint64]map[string]*proto.CheckpointVote),
+		weights:    make(map[uint64]map[string]uint64),
+	}
+	// The validator set of a checkpoint at the top of the chain is the current one
+	if height := uint64(chain.Height()); g.IsCheckpoint(height) {
+		if err := g.recordWeights(height); err != nil {
+			return nil, err
+		}
+	}
+	chain.OnBlockAdded(g.onBlockAdded)
+
+	return g, nil
+}
+
+// onBlockAdded records the validator set of the checkpoint blocks, right after they are added to the chain
+func (g *Gadget) onBlockAdded(b *proto.Block) {
+	height := b.Header.GetHeight()
+	if !g.IsCheckpoint(height) {
+		return
+	}
+	if err := g.recordWeights(height); err != nil {
+		log.Error().Err(err).Uint64("height", height).Msg("failed to record the checkpoint validator set")
+	}
+}
+
+// recordWeights records the current validator weights as the weights of the checkpoint at the height
+func (g *Gadget) recordWeights(height uint64) error {
+	weights, err := g.validators.Weights(g.chain)
+	if err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.weights[height] = weights
+	return nil
+}
+
+// checkpointWeights returns the validator weights recorded for the checkpoint at the height
+func (g *Gadget) checkpointWeights(height uint64) (map[string]uint64, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	weights, ok := g.weights[height]
+	if !ok {
+		return nil, fmt.Errorf("validator set of checkpoint (%d) is not known", height)
+	}
+	return weights, nil
+}
+
+// IsCheckpoint returns true if the block at the given height is a checkpoint
+func (g *Gadget) IsCheckpoint(height uint64) bool {
+	return height > 0 && height%g.interval == 0
+}
+
+// LastCheckpoint returns the height of the last checkpoint in the chain, zero if there is none yet
+func (g *Gadget) LastCheckpoint() uint64 {
+	height := uint64(g.chain.Height())
+	return height - height%g.interval
+}
+
+// VoteHash returns the hash signed by a vote for the checkpoint block at the given height
+func VoteHash(height uint64, blockHash []byte) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, height)
+
+	hash := sha256.New()
+	hash.Write([]byte(voteDomain))
+	hash.Write(buf)
+	hash.Write(blockHash)
+	return hash.Sum(nil)
+}
+
+// SignVote creates a vote of the validator key for the checkpoint block at the given height
+func SignVote(privKey *crypto.PrivateKey, height uint64, blockHash []byte) (*proto.CheckpointVote, error) {
+	sig, err := privKey.Sign(VoteHash(height, blockHash))
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.CheckpointVote{
+		Height:    height,
+		BlockHash: blockHash,
+		PublicKey: privKey.PublicKey().Bytes(),
+		Signature: sig.Bytes(),
+	}, nil
+}
+
+// VerifyVote checks the signature of the vote
+func VerifyVote(vote *proto.CheckpointVote) error {
+	publicKey, err := crypto.PublicKeyFromBytes(vote.PublicKey)
+	if err != nil {
+		return err
+	}
+	sig, err := crypto.SignatureFromBytes(vote.Signature)
+	if err != nil {
+		return err
+	}
+	if !sig.Verify(publicKey, VoteHash(vote.Height, vote.BlockHash)) {
+		return fmt.Errorf("invalid checkpoint vote signature")
+	}
+	return nil
+}
+
+// Vote signs a vote for the last checkpoint of the chain with the validator key and adds it to the gadget
+func (g *Gadget) Vote(privKey *crypto.PrivateKey) (*proto.CheckpointVote, error) {
+	height := g.LastCheckpoint()
+	if height == 0 {
+		return nil, fmt.Errorf("there is no checkpoint to vote for")
+	}
+	header, err := g.chain.GetHeaderByHeight(int(height))
+	if err != nil {
+		return nil, err
+	}
+	hash, err := types.HashHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	vote, err := SignVote(privKey, height, hash)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := g.AddVote(vote); err != nil {
+		return nil, err
+	}
+	return vote, nil
+}
+
+// AddVote validates a checkpoint vote and tallies it, then notifies the vote handlers. It returns true if the vote
+// finalized the checkpoint. Votes for checkpoints at or below the finalized height and votes already tallied are ignored.
+func (g *Gadget) AddVote(vote *proto.CheckpointVote) (bool, error) {
+	if !g.IsCheckpoint(vote.Height) {
+		return false, fmt.Errorf("%w: height (%d) is not a checkpoint", ErrInvalidVote, vote.Height)
+	}
+	if vote.Height <= uint64(g.chain.FinalizedHeight()) {
+		return false, nil
+	}
+	if err := VerifyVote(vote); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidVote, err)
+	}
+
+	header, err := g.chain.GetHeaderByHeight(int(vote.Height))
+	if err != nil {
+		return false, err
+	}
+	hash, err := types.HashHeader(header)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(hash, vote.BlockHash) {
+		return false, fmt.Errorf("vote for block (%s) which is not the checkpoint at height (%d)", hex.EncodeToString(vote.BlockHash), vote.Height)
+	}
+
+	weights, err := g.checkpointWeights(vote.Height)
+	if err != nil {
+		return false, err
+	}
+	if weights[string(vote.PublicKey)] == 0 {
+		return false, fmt.Errorf("%w: vote from (%s) which is not a validator of checkpoint (%d)", ErrInvalidVote, hex.EncodeToString(vote.PublicKey), vote.Height)
+	}
+
+	finalized, err := g.tally(vote, weights)
+	if errors.Is(err, errKnownVote) {
+		// Voting again for the same checkpoint has no effect
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	g.lock.Lock()
+	handlers := g.handlers
+	g.lock.Unlock()
+	for _, handler := range handlers {
+		handler(vote)
+	}
+	return finalized, nil
+}
+
+// tally records the vote and finalizes the checkpoint once the votes reach the quorum.
+// A vote already recorded returns errKnownVote.
+func (g *Gadget) tally(vote *proto.CheckpointVote, weights map[string]uint64) (bool, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	votes, ok := g.votes[vote.Height]
+	if !ok {
+		votes = make(map[string]*proto.CheckpointVote)
+		g.votes[vote.Height] = votes
+	}
+	if _, ok := votes[string(vote.PublicKey)]; ok {
+		return false, errKnownVote
+	}
+	votes[string(vote.PublicKey)] = vote
+
+	if !hasQuorum(weights, votes) {
+		return false, nil
+	}
+	if err := g.chain.SetFinalized(int(vote.Height), vote.BlockHash); err != nil {
+		return false, err
+	}
+	g.prune(vote.Height)
+
+	log.Info().Fields(map[string]interface{}{
+		"height": vote.Height,
+		"votes":  len(votes),
+	}).Msg("checkpoint finalized")
+
+	return true, nil
+}
+
+// OnVote registers a handler called with every vote tallied, received or signed by the node,
+// so the votes can be relayed to the other validators
+func (g *Gadget) OnVote(handler func(vote *proto.CheckpointVote)) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.handlers = append(g.handlers, handler)
+}
+
+// Votes returns the votes received for the checkpoint at the given height
+func (g *Gadget) Votes(height uint64) []*proto.CheckpointVote {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	votes := make([]*proto.CheckpointVote, 0, len(g.votes[height]))
+	for _, vote := range g.votes[height] {
+		votes = append(votes, vote)
+	}
+	return votes
+}
+
+// prune discards the votes and the validator sets of the checkpoints at or below the finalized height
+func (g *Gadget) prune(finalized uint64) {
+	for height := range g.votes {
+		if height <= finalized {
+			delete(g.votes, height)
+		}
+	}
+	for height := range g.weights {
+		if height <= finalized {
+			delete(g.weights, height)
+		}
+	}
+}
+
+// hasQuorum returns true if the voters hold more than two thirds of the total weight of the validators.
+// A validator set whose total weight overflows never reaches the quorum.
+func hasQuorum(weights map[string]uint64, votes map[string]*proto.CheckpointVote) bool {
+	var total, voted, carry uint64
+	for publicKey, weight := range weights {
+		if total, carry = bits.Add64(total, weight, 0); carry != 0 {
+			return false
+		}
+		// The voted weight is at most the total weight, it cannot overflow
+		if _, ok := votes[publicKey]; ok {
+			voted += weight
+		}
+	}
+	// Compare 3 * voted > 2 * total on 128 bits so large stakes do not overflow
+	votedHi, votedLo := bits.Mul64(voted, 3)
+	totalHi, totalLo := bits.Mul64(total, 2)
+	return total > 0 && (votedHi > totalHi || (votedHi == totalHi && votedLo > totalLo))
+}
@@ -0,0 +1,248 @@
+package finality
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerifyVote(t *testing.T) {
+	privKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	vote, err := SignVote(privKey, 10, []byte("hash"))
+	assert.Nil(t, err)
+	assert.NoError(t, VerifyVote(vote))
+
+	vote.BlockHash = []byte("other hash")
+	assert.Error(t, VerifyVote(vote))
+}
+
+func TestGadgetFinalizesCheckpoint(t *testing.T) {
+	keys, validators := generateValidators(t, 4)
+	bc := newTestChain(t, keys[0], 0)
+
+	gadget, err := New(bc, validators, 5)
+	assert.Nil(t, err)
+	addBlocks(t, bc, keys[0], 7)
+	assert.True(t, gadget.IsCheckpoint(5))
+	assert.False(t, gadget.IsCheckpoint(0))
+	assert.False(t, gadget.IsCheckpoint(6))
+	assert.Equal(t, uint64(5), gadget.LastCheckpoint())
+
+	// Two out of four validators are not more than two thirds
+	for _, key := range keys[:2] {
+		_, err := gadget.Vote(key)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, gadget.Votes(5), 2)
+	assert.Equal(t, 0, bc.FinalizedHeight())
+
+	// Voting twice for the same checkpoint has no effect
+	_, err = gadget.Vote(keys[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, bc.FinalizedHeight())
+
+	_, err = gadget.Vote(keys[2])
+	assert.Nil(t, err)
+	assert.Equal(t, 5, bc.FinalizedHeight())
+	assert.Empty(t, gadget.Votes(5))
+
+	// Late votes for the finalized checkpoint are ignored
+	vote, err := gadget.Vote(keys[3])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), vote.Height)
+	assert.Equal(t, 5, bc.FinalizedHeight())
+}
+
+func TestGadgetRejectsInvalidVotes(t *testing.T) {
+	keys, validators := generateValidators(t, 4)
+	bc := newTestChain(t, keys[0], 5)
+
+	gadget, err := New(bc, validators, 5)
+	assert.Nil(t, err)
+
+	header, err := bc.GetHeaderByHeight(5)
+	assert.Nil(t, err)
+	hash, err := types.HashHeader(header)
+	assert.Nil(t, err)
+
+	// Not a checkpoint height
+	vote, err := SignVote(keys[0], 4, hash)
+	assert.Nil(t, err)
+	_, err = gadget.AddVote(vote)
+	assert.ErrorIs(t, err, ErrInvalidVote)
+
+	// Not the block at the checkpoint height, which may be the checkpoint of another fork
+	vote, err = SignVote(keys[0], 5, []byte("other hash"))
+	assert.Nil(t, err)
+	_, err = gadget.AddVote(vote)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidVote)
+
+	// Not a validator
+	outsider, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	vote, err = SignVote(outsider, 5, hash)
+	assert.Nil(t, err)
+	_, err = gadget.AddVote(vote)
+	assert.ErrorIs(t, err, ErrInvalidVote)
+
+	// Invalid signature
+	vote, err = SignVote(keys[0], 5, hash)
+	assert.Nil(t, err)
+	vote.Signature = make([]byte, len(vote.Signature))
+	_, err = gadget.AddVote(vote)
+	assert.ErrorIs(t, err, ErrInvalidVote)
+
+	// Checkpoint not in the chain yet
+	vote, err = SignVote(keys[0], 10, hash)
+	assert.Nil(t, err)
+	_, err = gadget.AddVote(vote)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidVote)
+
+	assert.Empty(t, gadget.Votes(5))
+	assert.Equal(t, 0, bc.FinalizedHeight())
+}
+
+// changingValidatorSet is a validator set replaced by the test
+type changingValidatorSet struct {
+	validators StaticValidatorSet
+}
+
+func (s *changingValidatorSet) Weights(chain consensus.ChainReader) (map[string]uint64, error) {
+	return s.validators.Weights(chain)
+}
+
+func TestGadgetWeighsCheckpointValidatorSet(t *testing.T) {
+	keys, validators := generateValidators(t, 4)
+	set := &changingValidatorSet{validators: validators[:3]}
+	bc := newTestChain(t, keys[0], 0)
+	gadget, err := New(bc, set, 5)
+	assert.Nil(t, err)
+	addBlocks(t, bc, keys[0], 5)
+
+	// The validator set changes after the checkpoint, the votes are still weighed with the checkpoint validators
+	set.validators = validators[3:]
+	addBlocks(t, bc, keys[0], 2)
+
+	_, err = gadget.Vote(keys[3])
+	assert.ErrorContains(t, err, "not a validator of checkpoint (5)")
+	for _, key := range keys[:2] {
+		_, err := gadget.Vote(key)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 0, bc.FinalizedHeight())
+	_, err = gadget.Vote(keys[2])
+	assert.Nil(t, err)
+	assert.Equal(t, 5, bc.FinalizedHeight())
+
+	// The next checkpoint is weighed with the new validator set
+	addBlocks(t, bc, keys[0], 3)
+	_, err = gadget.Vote(keys[0])
+	assert.Error(t, err)
+	_, err = gadget.Vote(keys[3])
+	assert.Nil(t, err)
+	assert.Equal(t, 10, bc.FinalizedHeight())
+}
+
+func TestGadgetNotifiesVotes(t *testing.T) {
+	keys, validators := generateValidators(t, 4)
+	bc := newTestChain(t, keys[0], 5)
+	gadget, err := New(bc, validators, 5)
+	assert.Nil(t, err)
+
+	var notified []*proto.CheckpointVote
+	gadget.OnVote(func(vote *proto.CheckpointVote) {
+		notified = append(notified, vote)
+	})
+
+	// Every vote is notified once, the votes ignored are not
+	vote, err := gadget.Vote(keys[0])
+	assert.Nil(t, err)
+	_, err = gadget.AddVote(vote)
+	assert.Nil(t, err)
+	invalid, err := SignVote(keys[1], 4, vote.BlockHash)
+	assert.Nil(t, err)
+	_, err = gadget.AddVote(invalid)
+	assert.Error(t, err)
+	assert.Equal(t, []*proto.CheckpointVote{vote}, notified)
+
+	// The vote finalizing the checkpoint is notified too, the late votes are not
+	for _, key := range keys[1:] {
+		_, err := gadget.Vote(key)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 5, bc.FinalizedHeight())
+	assert.Len(t, notified, 3)
+}
+
+func TestGadgetRequiresInterval(t *testing.T) {
+	_, err := New(newTestChain(t, nil, 0), StaticValidatorSet{}, 0)
+	assert.Error(t, err)
+}
+
+func TestHasQuorum(t *testing.T) {
+	weights := map[string]uint64{"a": 1, "b": 1, "c": 1}
+	assert.False(t, hasQuorum(weights, votesFrom("a", "b")))
+	assert.True(t, hasQuorum(weights, votesFrom("a", "b", "c")))
+
+	// Weighted validators
+	weights = map[string]uint64{"a": 70, "b": 20, "c": 10}
+	assert.True(t, hasQuorum(weights, votesFrom("a")))
+	assert.False(t, hasQuorum(weights, votesFrom("b", "c")))
+
+	// Large stakes do not overflow
+	weights = map[string]uint64{"a": 1 << 62, "b": 1 << 62, "c": 1 << 62}
+	assert.False(t, hasQuorum(weights, votesFrom("a", "b")))
+	assert.True(t, hasQuorum(weights, votesFrom("a", "b", "c")))
+
+	// A total weight overflowing never reaches the quorum
+	weights = map[string]uint64{"a": 1 << 63, "b": 1 << 63, "c": 1}
+	assert.False(t, hasQuorum(weights, votesFrom("a", "b", "c")))
+
+	assert.False(t, hasQuorum(map[string]uint64{}, votesFrom()))
+}
+
+// generateValidators generates validator keys and a static validator set with their public keys
+func generateValidators(t *testing.T, n int) ([]*crypto.PrivateKey, StaticValidatorSet) {
+	keys := make([]*crypto.PrivateKey, 0, n)
+	validators := make(StaticValidatorSet, 0, n)
+	for i := 0; i < n; i++ {
+		privateKey, err := crypto.GeneratePrivateKey()
+		assert.Nil(t, err)
+		keys = append(keys, privateKey)
+		validators = append(validators, privateKey.PublicKey().Bytes())
+	}
+	return keys, validators
+}
+
+// newTestChain creates a blockchain with the given number of blocks on top of the genesis block
+func newTestChain(t *testing.T, producer *crypto.PrivateKey, blocks int) *core.Blockchain {
+	bc := core.NewBlockchain(core.NewMemorystore())
+	addBlocks(t, bc, producer, blocks)
+	return bc
+}
+
+// addBlocks adds the given number of blocks produced by the producer to the blockchain
+func addBlocks(t *testing.T, bc *core.Blockchain, producer *crypto.PrivateKey, blocks int) {
+	for i := 0; i < blocks; i++ {
+		block, err := bc.BuildBlock(producer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, bc.AddBlock(block))
+	}
+}
+
+func votesFrom(voters ...string) map[string]*proto.CheckpointVote {
+	votes := make(map[string]*proto.CheckpointVote)
+	for _, voter := range voters {
+		votes[voter] = &proto.CheckpointVote{}
+	}
+	return votes
+}
@@ -0,0 +1,57 @@
+package instant
+
+import (
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// Engine is the "instant seal" consensus engine meant for development and testing.
+// Blocks are sealed by signing them, so any signer can produce a block immediately.
+type Engine struct {
+	rewards consensus.RewardSchedule
+}
+
+// New creates a new instant seal engine with the given reward schedule
+func New(rewards consensus.RewardSchedule) *Engine {
+	return &Engine{
+		rewards: rewards,
+	}
+}
+
+// Prepare does nothing, the instant seal engine has no consensus fields
+func (e *Engine) Prepare(chain consensus.ChainReader, parent *proto.Header, header *proto.Header) error {
+	return nil
+}
+
+// VerifyHeader accepts any header, the generic header rules are checked by the blockchain
+func (e *Engine) VerifyHeader(chain consensus.ChainReader, parent *proto.Header, header *proto.Header) error {
+	return nil
+}
+
+// VerifyBlock accepts any block signer
+func (e *Engine) VerifyBlock(chain consensus.ChainReader, b *proto.Block) error {
+	return nil
+}
+
+// Seal signs the block with the signer key
+func (e *Engine) Seal(chain consensus.ChainReader, b *proto.Block, signer *crypto.PrivateKey) error {
+	_, err := types.SignBlock(signer, b)
+	return err
+}
+
+// ApplyTransaction rejects special transactions, the instant seal engine has none
+func (e *Engine) ApplyTransaction(chain consensus.ChainReader, state consensus.State, header *proto.Header, tx *proto.Transaction) error {
+	return consensus.UnsupportedTransaction(tx)
+}
+
+// Finalize does nothing, the instant seal engine has no consensus state
+func (e *Engine) Finalize(chain consensus.ChainReader, state consensus.State, b *proto.Block) error {
+	return nil
+}
+
+// Reward returns the block reward at the given height
+func (e *Engine) Reward(height uint64) uint64 {
+	return e.rewards.Reward(height)
+}
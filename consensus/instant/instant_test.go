@@ -0,0 +1,40 @@
+package instant_test
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/consensus/instant"
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstantSeal(t *testing.T) {
+	engine := instant.New(consensus.RewardSchedule{InitialReward: 5})
+	bc := core.NewBlockchain(core.NewMemorystore(), core.WithEngine(engine))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	block, err := bc.BuildBlock(privateKey, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), block.Transactions[0].Value)
+	isValid, err := types.VerifyBlock(block)
+	assert.Nil(t, err)
+	assert.True(t, isValid)
+	assert.NoError(t, bc.AddBlock(block))
+}
+
+func TestInstantSealRejectsVotes(t *testing.T) {
+	bc := core.NewBlockchain(core.NewMemorystore(), core.WithEngine(instant.New(consensus.DefaultRewardSchedule)))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	vote := types.NewVoteTransaction(privateKey.PublicKey().Bytes(), privateKey.PublicKey().Bytes(), true, 1)
+	assert.Nil(t, types.SignTransaction(privateKey, vote))
+	block, err := bc.BuildBlock(privateKey, []*proto.Transaction{vote})
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(block))
+}
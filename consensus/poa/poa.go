@@ -0,0 +1,297 @@
+package poa
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+const (
+	// authoritiesKey is the state key of the current authorities public keys
+	authoritiesKey = "poa/authorities"
+	// votesKey is the state key of the votes on pending proposals
+	votesKey = "poa/votes"
+	// voteSize is the encoded size of a vote: candidate public key, authorize flag and voter public key
+	voteSize = crypto.PublicKeySize + 1 + crypto.PublicKeySize
+)
+
+// vote is a vote from an authority to add (authorize) or remove a candidate from the authorities
+type vote struct {
+	candidate []byte
+	authorize bool
+	voter     []byte
+}
+
+// Engine is the Proof-of-Authority consensus engine. The genesis block lists the authorities allowed to sign blocks.
+// Authorities sign blocks in round-robin turn and can vote to add or remove authorities,
+// a proposal is applied once more than half of the authorities voted for it.
+// The authorities and the pending votes are kept in the account state.
+type Engine struct {
+	rewards consensus.RewardSchedule
+	genesis [][]byte
+}
+
+// New creates a new Proof-of-Authority engine with the given initial authorities public keys and reward schedule
+func New(authorities [][]byte, rewards consensus.RewardSchedule) (*Engine, error) {
+	if len(authorities) == 0 {
+		return nil, fmt.Errorf("proof of authority requires at least one authority")
+	}
+
+	genesis := make([][]byte, 0, len(authorities))
+	for _, authority := range authorities {
+		if len(authority) != crypto.PublicKeySize {
+			return nil, fmt.Errorf("invalid authority public key length (%d)", len(authority))
+		}
+		if contains(genesis, authority) {
+			return nil, fmt.Errorf("duplicated authority (%s)", hex.EncodeToString(authority))
+		}
+		genesis = addAuthority(genesis, authority)
+	}
+
+	return &Engine{
+		rewards: rewards,
+		genesis: genesis,
+	}, nil
+}
+
+// EncodeAuthorities encodes the authorities public keys to be stored in the genesis block extra data
+func EncodeAuthorities(authorities [][]byte) []byte {
+	return bytes.Join(authorities, nil)
+}
+
+// DecodeAuthorities decodes the authorities public keys from the genesis block extra data
+func DecodeAuthorities(data []byte) ([][]byte, error) {
+	if len(data) == 0 || len(data)%crypto.PublicKeySize != 0 {
+		return nil, fmt.Errorf("invalid authorities data length (%d)", len(data))
+	}
+
+	authorities := make([][]byte, 0, len(data)/crypto.PublicKeySize)
+	for i := 0; i < len(data); i += crypto.PublicKeySize {
+		authorities = append(authorities, data[i:i+crypto.PublicKeySize])
+	}
+	return authorities, nil
+}
+
+// Prepare lists the initial authorities in the extra data of the genesis header
+func (poa *Engine) Prepare(chain consensus.ChainReader, parent *proto.Header, header *proto.Header) error {
+	if parent == nil {
+		header.ExtraData = EncodeAuthorities(poa.genesis)
+	}
+	return nil
+}
+
+// VerifyHeader checks that only the genesis header carries the authorities list
+func (poa *Engine) VerifyHeader(chain consensus.ChainReader, parent *proto.Header, header *proto.Header) error {
+	if len(header.ExtraData) != 0 {
+		return fmt.Errorf("only the genesis header can have extra data")
+	}
+	return nil
+}
+
+// VerifyBlock checks that the block is signed by the authority in turn
+func (poa *Engine) VerifyBlock(chain consensus.ChainReader, b *proto.Block) error {
+	signer := poa.InTurnSigner(chain, b.Header.GetHeight())
+	if !bytes.Equal(signer, b.PublicKey) {
+		return fmt.Errorf("block at height %d must be signed by authority (%s)", b.Header.GetHeight(), hex.EncodeToString(signer))
+	}
+	return nil
+}
+
+// Seal signs the block if the signer is the authority in turn
+func (poa *Engine) Seal(chain consensus.ChainReader, b *proto.Block, signer *crypto.PrivateKey) error {
+	if inTurn := poa.InTurnSigner(chain, b.Header.GetHeight()); !bytes.Equal(inTurn, signer.PublicKey().Bytes()) {
+		return fmt.Errorf("it is not the turn of (%s) to sign block at height %d", signer.PublicKey(), b.Header.GetHeight())
+	}
+	_, err := types.SignBlock(signer, b)
+	return err
+}
+
+// ApplyTransaction tallies a vote transaction and applies the proposal if it reached a majority
+func (poa *Engine) ApplyTransaction(chain consensus.ChainReader, state consensus.State, header *proto.Header, tx *proto.Transaction) error {
+	if !types.IsVote(tx) {
+		return consensus.UnsupportedTransaction(tx)
+	}
+
+	authorities := poa.authorities(state)
+	if err := validateVote(authorities, tx); err != nil {
+		return err
+	}
+
+	v := vote{candidate: tx.To, authorize: tx.Data[0] == 1, voter: tx.From}
+	votes := decodeVotes(state.Get(votesKey))
+	count := 1
+	for _, existing := range votes {
+		if bytes.Equal(existing.candidate, v.candidate) && existing.authorize == v.authorize {
+			// Voting again for the same proposal has no effect
+			if bytes.Equal(existing.voter, v.voter) {
+				return nil
+			}
+			count++
+		}
+	}
+	votes = append(votes, v)
+
+	if count > len(authorities)/2 {
+		// The proposal reached a majority, the votes for the candidate are discarded
+		votes = filterVotes(votes, func(existing vote) bool { return !bytes.Equal(existing.candidate, v.candidate) })
+		if v.authorize {
+			authorities = addAuthority(authorities, v.candidate)
+		} else {
+			authorities = removeAuthority(authorities, v.candidate)
+			// The votes of the removed authority on pending proposals are discarded
+			votes = filterVotes(votes, func(existing vote) bool { return !bytes.Equal(existing.voter, v.candidate) })
+		}
+		state.Set(authoritiesKey, EncodeAuthorities(authorities))
+	}
+	state.Set(votesKey, encodeVotes(votes))
+
+	return nil
+}
+
+// Finalize stores the initial authorities in the state when the genesis block is added
+func (poa *Engine) Finalize(chain consensus.ChainReader, state consensus.State, b *proto.Block) error {
+	if b.Header.GetHeight() == 0 {
+		state.Set(authoritiesKey, EncodeAuthorities(poa.genesis))
+	}
+	return nil
+}
+
+// Reward returns the block reward at the given height
+func (poa *Engine) Reward(height uint64) uint64 {
+	return poa.rewards.Reward(height)
+}
+
+// Authorities returns the public keys of the current authorities sorted in signing order
+func (poa *Engine) Authorities(chain consensus.ChainReader) [][]byte {
+	return poa.authorities(chain.State())
+}
+
+// IsAuthority returns true if the public key belongs to a current authority
+func (poa *Engine) IsAuthority(chain consensus.ChainReader, publicKey []byte) bool {
+	return contains(poa.Authorities(chain), publicKey)
+}
+
+// Weights returns a voting weight of one for every current authority
+func (poa *Engine) Weights(chain consensus.ChainReader) (map[string]uint64, error) {
+	authorities := poa.Authorities(chain)
+	weights := make(map[string]uint64, len(authorities))
+	for _, authority := range authorities {
+		weights[string(authority)] = 1
+	}
+	return weights, nil
+}
+
+// InTurnSigner returns the public key of the authority whose turn it is to sign the block at the given height
+func (poa *Engine) InTurnSigner(chain consensus.ChainReader, height uint64) []byte {
+	authorities := poa.Authorities(chain)
+	return authorities[height%uint64(len(authorities))]
+}
+
+// authorities returns the authorities in the state, or the genesis authorities before the genesis block is added
+func (poa *Engine) authorities(state consensus.StateReader) [][]byte {
+	authorities, err := DecodeAuthorities(state.Get(authoritiesKey))
+	if err != nil {
+		return poa.genesis
+	}
+	return authorities
+}
+
+// validateVote checks that the vote is cast by an authority and that the proposal can be applied
+func validateVote(authorities [][]byte, tx *proto.Transaction) error {
+	if !contains(authorities, tx.From) {
+		return fmt.Errorf("vote from (%s) which is not an authority", hex.EncodeToString(tx.From))
+	}
+	if len(tx.To) != crypto.PublicKeySize {
+		return fmt.Errorf("invalid vote candidate public key length (%d)", len(tx.To))
+	}
+	if len(tx.Data) != 1 || tx.Data[0] > 1 {
+		return fmt.Errorf("invalid vote data")
+	}
+
+	authorize := tx.Data[0] == 1
+	if authorize && contains(authorities, tx.To) {
+		return fmt.Errorf("vote to add (%s) which is already an authority", hex.EncodeToString(tx.To))
+	}
+	if !authorize && !contains(authorities, tx.To) {
+		return fmt.Errorf("vote to remove (%s) which is not an authority", hex.EncodeToString(tx.To))
+	}
+	if !authorize && len(authorities) == 1 {
+		return fmt.Errorf("the last authority cannot be removed")
+	}
+
+	return nil
+}
+
+func encodeVotes(votes []vote) []byte {
+	data := make([]byte, 0, len(votes)*voteSize)
+	for _, v := range votes {
+		authorize := byte(0)
+		if v.authorize {
+			authorize = 1
+		}
+		data = append(data, v.candidate...)
+		data = append(data, authorize)
+		data = append(data, v.voter...)
+	}
+	return data
+}
+
+func decodeVotes(data []byte) []vote {
+	votes := make([]vote, 0, len(data)/voteSize)
+	for i := 0; i+voteSize <= len(data); i += voteSize {
+		votes = append(votes, vote{
+			candidate: data[i : i+crypto.PublicKeySize],
+			authorize: data[i+crypto.PublicKeySize] == 1,
+			voter:     data[i+crypto.PublicKeySize+1 : i+voteSize],
+		})
+	}
+	return votes
+}
+
+func filterVotes(votes []vote, keep func(vote) bool) []vote {
+	filtered := votes[:0]
+	for _, v := range votes {
+		if keep(v) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+func contains(authorities [][]byte, publicKey []byte) bool {
+	for _, authority := range authorities {
+		if bytes.Equal(authority, publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// addAuthority returns the authorities with a new authority, sorted as the signing order
+func addAuthority(authorities [][]byte, publicKey []byte) [][]byte {
+	authority := make([]byte, len(publicKey))
+	copy(authority, publicKey)
+
+	added := append(append([][]byte{}, authorities...), authority)
+	sort.Slice(added, func(i, j int) bool {
+		return bytes.Compare(added[i], added[j]) < 0
+	})
+	return added
+}
+
+// removeAuthority returns the authorities without the given authority
+func removeAuthority(authorities [][]byte, publicKey []byte) [][]byte {
+	removed := make([][]byte, 0, len(authorities))
+	for _, authority := range authorities {
+		if !bytes.Equal(authority, publicKey) {
+			removed = append(removed, authority)
+		}
+	}
+	return removed
+}
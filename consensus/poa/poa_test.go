@@ -0,0 +1,156 @@
+package poa
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecodeAuthorities(t *testing.T) {
+	keys, authorities := generateAuthorities(t, 3)
+	assert.Len(t, keys, 3)
+
+	decoded, err := DecodeAuthorities(EncodeAuthorities(authorities))
+	assert.Nil(t, err)
+	assert.Equal(t, authorities, decoded)
+
+	_, err = DecodeAuthorities([]byte("invalid"))
+	assert.Error(t, err)
+}
+
+func TestProofOfAuthorityRoundRobin(t *testing.T) {
+	keys, authorities := generateAuthorities(t, 3)
+	engine, bc := newTestChain(t, authorities)
+	assert.Len(t, engine.Authorities(bc), 3)
+
+	for height := uint64(1); height <= 6; height++ {
+		signer := keys[string(engine.InTurnSigner(bc, height))]
+
+		// Any other authority is rejected
+		for _, key := range keys {
+			if key == signer {
+				continue
+			}
+			_, err := bc.BuildBlock(key, nil)
+			assert.Error(t, err)
+			assert.Error(t, bc.AddBlock(signedBlock(t, bc, key, nil)))
+		}
+
+		block, err := bc.BuildBlock(signer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, bc.AddBlock(block))
+	}
+
+	// A key which is not an authority is rejected
+	outsider, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(signedBlock(t, bc, outsider, nil)))
+}
+
+func TestProofOfAuthorityVotes(t *testing.T) {
+	keys, authorities := generateAuthorities(t, 3)
+	engine, bc := newTestChain(t, authorities)
+
+	candidate, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	candidateKey := candidate.PublicKey().Bytes()
+
+	// A vote from a key which is not an authority is rejected
+	vote := signedVote(t, bc, candidate, candidateKey, true)
+	block, err := bc.BuildBlock(keys[string(engine.InTurnSigner(bc, 1))], []*proto.Transaction{vote})
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(block))
+	assert.Equal(t, 0, bc.Height())
+
+	// The candidate is added after a majority (2 out of 3) of the authorities voted for it
+	addBlockWithVote(t, engine, bc, keys, keys[string(authorities[0])], candidateKey, true)
+	assert.False(t, engine.IsAuthority(bc, candidateKey))
+	addBlockWithVote(t, engine, bc, keys, keys[string(authorities[1])], candidateKey, true)
+	assert.True(t, engine.IsAuthority(bc, candidateKey))
+	assert.Len(t, engine.Authorities(bc), 4)
+	keys[string(candidateKey)] = candidate
+
+	// Voting to add an existing authority is rejected
+	vote = signedVote(t, bc, keys[string(authorities[0])], candidateKey, true)
+	block, err = bc.BuildBlock(keys[string(engine.InTurnSigner(bc, uint64(bc.Height()+1)))], []*proto.Transaction{vote})
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(block))
+
+	// Removing an authority requires a majority (3 out of 4) of the authorities
+	addBlockWithVote(t, engine, bc, keys, keys[string(authorities[0])], authorities[2], false)
+	addBlockWithVote(t, engine, bc, keys, keys[string(authorities[1])], authorities[2], false)
+	assert.True(t, engine.IsAuthority(bc, authorities[2]))
+	addBlockWithVote(t, engine, bc, keys, candidate, authorities[2], false)
+	assert.False(t, engine.IsAuthority(bc, authorities[2]))
+	assert.Len(t, engine.Authorities(bc), 3)
+}
+
+func TestProofOfAuthorityWeights(t *testing.T) {
+	_, authorities := generateAuthorities(t, 3)
+	engine, bc := newTestChain(t, authorities)
+
+	weights, err := engine.Weights(bc)
+	assert.Nil(t, err)
+	assert.Len(t, weights, 3)
+	for _, authority := range authorities {
+		assert.Equal(t, uint64(1), weights[string(authority)])
+	}
+}
+
+// generateAuthorities generates authority keys, returning them indexed by public key together with the public keys
+func generateAuthorities(t *testing.T, n int) (map[string]*crypto.PrivateKey, [][]byte) {
+	keys := make(map[string]*crypto.PrivateKey)
+	authorities := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		privateKey, err := crypto.GeneratePrivateKey()
+		assert.Nil(t, err)
+		keys[string(privateKey.PublicKey().Bytes())] = privateKey
+		authorities = append(authorities, privateKey.PublicKey().Bytes())
+	}
+	return keys, authorities
+}
+
+// signedVote creates a vote transaction signed by the voter with its next nonce
+func signedVote(t *testing.T, bc *core.Blockchain, voter *crypto.PrivateKey, candidate []byte, authorize bool) *proto.Transaction {
+	nonce := bc.State().Nonce(voter.PublicKey().Address()) + 1
+	vote := types.NewVoteTransaction(voter.PublicKey().Bytes(), candidate, authorize, nonce)
+	assert.Nil(t, types.SignTransaction(voter, vote))
+	return vote
+}
+
+// addBlockWithVote adds a block signed by the in turn authority including a vote from the voter
+func addBlockWithVote(t *testing.T, engine *Engine, bc *core.Blockchain, keys map[string]*crypto.PrivateKey, voter *crypto.PrivateKey, candidate []byte, authorize bool) {
+	signer := keys[string(engine.InTurnSigner(bc, uint64(bc.Height()+1)))]
+	block, err := bc.BuildBlock(signer, []*proto.Transaction{signedVote(t, bc, voter, candidate, authorize)})
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+}
+
+// newTestChain creates a Proof-of-Authority engine and a blockchain using it
+func newTestChain(t *testing.T, authorities [][]byte) (*Engine, *core.Blockchain) {
+	engine, err := New(authorities, consensus.DefaultRewardSchedule)
+	assert.Nil(t, err)
+	return engine, core.NewBlockchain(core.NewMemorystore(), core.WithEngine(engine))
+}
+
+// signedBlock builds a block with the instant seal engine, which signs it regardless of the authorities turn
+func signedBlock(t *testing.T, bc *core.Blockchain, signer *crypto.PrivateKey, txs []*proto.Transaction) *proto.Block {
+	devChain := core.NewBlockchain(core.NewMemorystore())
+	header, err := bc.GetHeaderByHeight(bc.Height())
+	assert.Nil(t, err)
+	prevHash, err := types.HashHeader(header)
+	assert.Nil(t, err)
+
+	block, err := devChain.BuildBlock(signer, txs)
+	assert.Nil(t, err)
+	block.Header.Height = header.Height + 1
+	block.Header.PrevBlockHash = prevHash
+	_, err = types.SignBlock(signer, block)
+	assert.Nil(t, err)
+	return block
+}
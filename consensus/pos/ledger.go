@@ -0,0 +1,106 @@
+package pos
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+	"sort"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// stakingKey is the state key of the staking ledger
+const stakingKey = "pos/staking"
+
+// loadLedger reads the staking ledger from the state
+func loadLedger(state consensus.StateReader) (*proto.StakingState, error) {
+	ledger := &proto.StakingState{}
+	if err := pb.Unmarshal(state.Get(stakingKey), ledger); err != nil {
+		return nil, errors.New("failed to unmarshal staking ledger")
+	}
+	return ledger, nil
+}
+
+// storeLedger writes the staking ledger to the state
+func storeLedger(state consensus.State, ledger *proto.StakingState) error {
+	data, err := pb.Marshal(ledger)
+	if err != nil {
+		return errors.New("failed to marshal staking ledger")
+	}
+	state.Set(stakingKey, data)
+	return nil
+}
+
+// findValidator returns the validator with the given public key, or nil
+func findValidator(ledger *proto.StakingState, publicKey []byte) *proto.Validator {
+	for _, validator := range ledger.Validators {
+		if bytes.Equal(validator.PublicKey, publicKey) {
+			return validator
+		}
+	}
+	return nil
+}
+
+// addValidator adds a validator keeping the validators sorted by public key
+func addValidator(ledger *proto.StakingState, publicKey []byte) *proto.Validator {
+	validator := &proto.Validator{PublicKey: publicKey}
+	ledger.Validators = append(ledger.Validators, validator)
+	sort.Slice(ledger.Validators, func(i, j int) bool {
+		return bytes.Compare(ledger.Validators[i].PublicKey, ledger.Validators[j].PublicKey) < 0
+	})
+	return validator
+}
+
+// findDelegation returns the delegation of the delegator address to the validator, or nil
+func findDelegation(validator *proto.Validator, delegator []byte) *proto.Delegation {
+	for _, delegation := range validator.Delegations {
+		if bytes.Equal(delegation.Delegator, delegator) {
+			return delegation
+		}
+	}
+	return nil
+}
+
+// removeEmptyDelegations removes the delegations without stake from the validator
+func removeEmptyDelegations(validator *proto.Validator) {
+	delegations := validator.Delegations[:0]
+	for _, delegation := range validator.Delegations {
+		if delegation.Amount > 0 {
+			delegations = append(delegations, delegation)
+		}
+	}
+	validator.Delegations = delegations
+}
+
+// validatorStake returns the total stake bonded to the validator
+func validatorStake(validator *proto.Validator) uint64 {
+	var stake uint64
+	for _, delegation := range validator.Delegations {
+		stake += delegation.Amount
+	}
+	return stake
+}
+
+// activeStake returns the total stake of the validators that are not jailed
+func activeStake(ledger *proto.StakingState) uint64 {
+	var stake uint64
+	for _, validator := range ledger.Validators {
+		if !validator.Jailed {
+			stake += validatorStake(validator)
+		}
+	}
+	return stake
+}
+
+// mulDiv returns a * b / c without overflowing the intermediate product, c must be greater than zero
+func mulDiv(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	if hi >= c {
+		// The result does not fit in 64 bits, only possible when b > c
+		return ^uint64(0)
+	}
+	quo, _ := bits.Div64(hi, lo, c)
+	return quo
+}
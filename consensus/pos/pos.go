@@ -0,0 +1,382 @@
+package pos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// GenesisValidator is a validator of the genesis block with its initial self bonded stake
+type GenesisValidator struct {
+	PublicKey []byte
+	Stake     uint64
+}
+
+// Config holds the Proof-of-Stake parameters
+type Config struct {
+	// Rewards is the block reward schedule
+	Rewards consensus.RewardSchedule
+	// Commission is the percentage of the block reward paid to the proposer through the coinbase transaction,
+	// the rest is distributed to the delegators of the proposer in proportion to their stake
+	Commission uint64
+	// UnbondingPeriod is the number of blocks after which unbonded stake is released
+	UnbondingPeriod uint64
+	// SlashPercent is the percentage of the stake slashed when a validator double signs
+	SlashPercent uint64
+	// Validators are the validators of the genesis block
+	Validators []GenesisValidator
+}
+
+// Engine is the Proof-of-Stake consensus engine. The proposer of each block is selected among the validators
+// with a probability proportional to their stake, using the previous block hash as the source of randomness.
+// The staking ledger (validators, delegations and unbondings) is kept in the account state.
+type Engine struct {
+	config  Config
+	genesis *proto.StakingState
+}
+
+// New creates a new Proof-of-Stake engine with the given configuration
+func New(config Config) (*Engine, error) {
+	if len(config.Validators) == 0 {
+		return nil, fmt.Errorf("proof of stake requires at least one genesis validator")
+	}
+	if config.Commission > 100 || config.SlashPercent > 100 {
+		return nil, fmt.Errorf("commission and slash percent must be at most 100")
+	}
+
+	genesis := &proto.StakingState{}
+	for _, v := range config.Validators {
+		if len(v.PublicKey) != crypto.PublicKeySize {
+			return nil, fmt.Errorf("invalid validator public key length (%d)", len(v.PublicKey))
+		}
+		if v.Stake == 0 {
+			return nil, fmt.Errorf("genesis validator (%s) has no stake", hex.EncodeToString(v.PublicKey))
+		}
+		if findValidator(genesis, v.PublicKey) != nil {
+			return nil, fmt.Errorf("duplicated validator (%s)", hex.EncodeToString(v.PublicKey))
+		}
+		address, err := types.AccountAddress(v.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		validator := addValidator(genesis, v.PublicKey)
+		validator.Delegations = []*proto.Delegation{{Delegator: address.Bytes(), Amount: v.Stake}}
+	}
+
+	return &Engine{
+		config:  config,
+		genesis: genesis,
+	}, nil
+}
+
+// Prepare records the genesis validators in the extra data of the genesis header
+func (e *Engine) Prepare(chain consensus.ChainReader, parent *proto.Header, header *proto.Header) error {
+	if parent != nil {
+		return nil
+	}
+	data, err := pb.Marshal(e.genesis)
+	if err != nil {
+		return err
+	}
+	header.ExtraData = data
+	return nil
+}
+
+// VerifyHeader checks that only the genesis header carries the validators
+func (e *Engine) VerifyHeader(chain consensus.ChainReader, parent *proto.Header, header *proto.Header) error {
+	if len(header.ExtraData) != 0 {
+		return fmt.Errorf("only the genesis header can have extra data")
+	}
+	return nil
+}
+
+// VerifyBlock checks that the block is signed by the selected proposer
+func (e *Engine) VerifyBlock(chain consensus.ChainReader, b *proto.Block) error {
+	proposer, err := e.Proposer(chain, b.Header.PrevBlockHash)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(proposer, b.PublicKey) {
+		return fmt.Errorf("block at height %d must be proposed by validator (%s)", b.Header.GetHeight(), hex.EncodeToString(proposer))
+	}
+	return nil
+}
+
+// Seal signs the block if the signer is the selected proposer
+func (e *Engine) Seal(chain consensus.ChainReader, b *proto.Block, signer *crypto.PrivateKey) error {
+	proposer, err := e.Proposer(chain, b.Header.PrevBlockHash)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(proposer, signer.PublicKey().Bytes()) {
+		return fmt.Errorf("(%s) is not the proposer of block at height %d", signer.PublicKey(), b.Header.GetHeight())
+	}
+	_, err = types.SignBlock(signer, b)
+	return err
+}
+
+// ApplyTransaction applies bond, unbond and evidence transactions to the staking ledger
+func (e *Engine) ApplyTransaction(chain consensus.ChainReader, state consensus.State, header *proto.Header, tx *proto.Transaction) error {
+	ledger, err := loadLedger(state)
+	if err != nil {
+		return err
+	}
+
+	switch tx.GetType() {
+	case proto.TxType_TX_TYPE_BOND:
+		err = e.bond(state, ledger, tx)
+	case proto.TxType_TX_TYPE_UNBOND:
+		err = e.unbond(ledger, header, tx)
+	case proto.TxType_TX_TYPE_EVIDENCE:
+		err = e.slash(ledger, header, tx)
+	default:
+		return consensus.UnsupportedTransaction(tx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return storeLedger(state, ledger)
+}
+
+// Finalize distributes the delegators share of the block reward and releases the matured unbondings.
+// For the genesis block it stores the genesis validators in the state.
+func (e *Engine) Finalize(chain consensus.ChainReader, state consensus.State, b *proto.Block) error {
+	height := b.Header.GetHeight()
+	if height == 0 {
+		return storeLedger(state, e.genesis)
+	}
+
+	ledger, err := loadLedger(state)
+	if err != nil {
+		return err
+	}
+	if err := e.distributeRewards(state, ledger, b); err != nil {
+		return err
+	}
+
+	pending := ledger.Unbondings[:0]
+	for _, unbonding := range ledger.Unbondings {
+		if unbonding.ReleaseHeight > height {
+			pending = append(pending, unbonding)
+			continue
+		}
+		delegator, err := crypto.AddressFromBytes(unbonding.Delegator)
+		if err != nil {
+			return err
+		}
+		if err := state.AddBalance(delegator, unbonding.Amount); err != nil {
+			return err
+		}
+	}
+	ledger.Unbondings = pending
+
+	return storeLedger(state, ledger)
+}
+
+// Reward returns the proposer commission of the block reward at the given height
+func (e *Engine) Reward(height uint64) uint64 {
+	return mulDiv(e.config.Rewards.Reward(height), e.config.Commission, 100)
+}
+
+// Validators returns the validators in the staking ledger
+func (e *Engine) Validators(chain consensus.ChainReader) ([]*proto.Validator, error) {
+	ledger, err := loadLedger(chain.State())
+	if err != nil {
+		return nil, err
+	}
+	return ledger.Validators, nil
+}
+
+// Weights returns the stake of every validator that is not jailed as its voting weight
+func (e *Engine) Weights(chain consensus.ChainReader) (map[string]uint64, error) {
+	ledger, err := loadLedger(chain.State())
+	if err != nil {
+		return nil, err
+	}
+	weights := make(map[string]uint64, len(ledger.Validators))
+	for _, validator := range ledger.Validators {
+		if stake := validatorStake(validator); !validator.Jailed && stake > 0 {
+			weights[string(validator.PublicKey)] = stake
+		}
+	}
+	return weights, nil
+}
+
+// Proposer returns the public key of the validator selected to propose the block on top of the block with the given hash.
+// Each validator that is not jailed is selected with a probability proportional to its stake.
+func (e *Engine) Proposer(chain consensus.ChainReader, prevBlockHash []byte) ([]byte, error) {
+	ledger, err := loadLedger(chain.State())
+	if err != nil {
+		return nil, err
+	}
+	total := activeStake(ledger)
+	if total == 0 {
+		return nil, fmt.Errorf("there are no validators with stake")
+	}
+
+	seed := sha256.Sum256(prevBlockHash)
+	target := binary.BigEndian.Uint64(seed[:8]) % total
+	for _, validator := range ledger.Validators {
+		if validator.Jailed {
+			continue
+		}
+		stake := validatorStake(validator)
+		if target < stake {
+			return validator.PublicKey, nil
+		}
+		target -= stake
+	}
+
+	return nil, fmt.Errorf("failed to select a proposer")
+}
+
+// bond moves the value of the transaction from the sender balance to its delegation to the validator
+func (e *Engine) bond(state consensus.State, ledger *proto.StakingState, tx *proto.Transaction) error {
+	if tx.Value == 0 {
+		return fmt.Errorf("bond amount must be greater than zero")
+	}
+	delegator, err := types.AccountAddress(tx.From)
+	if err != nil {
+		return err
+	}
+
+	validator := findValidator(ledger, tx.To)
+	if validator == nil {
+		// Only the owner of a public key can make it a validator by bonding to itself
+		if !bytes.Equal(tx.From, tx.To) {
+			return fmt.Errorf("(%s) is not a validator", hex.EncodeToString(tx.To))
+		}
+		validator = addValidator(ledger, tx.To)
+	}
+	if validator.Jailed {
+		return fmt.Errorf("validator (%s) is jailed", hex.EncodeToString(tx.To))
+	}
+
+	if err := state.SubBalance(delegator, tx.Value); err != nil {
+		return err
+	}
+	delegation := findDelegation(validator, delegator.Bytes())
+	if delegation == nil {
+		delegation = &proto.Delegation{Delegator: delegator.Bytes()}
+		validator.Delegations = append(validator.Delegations, delegation)
+	}
+	delegation.Amount += tx.Value
+
+	return nil
+}
+
+// unbond removes the value of the transaction from the sender delegation to the validator,
+// the stake is released to the sender balance after the unbonding period
+func (e *Engine) unbond(ledger *proto.StakingState, header *proto.Header, tx *proto.Transaction) error {
+	delegator, err := types.AccountAddress(tx.From)
+	if err != nil {
+		return err
+	}
+	validator := findValidator(ledger, tx.To)
+	if validator == nil {
+		return fmt.Errorf("(%s) is not a validator", hex.EncodeToString(tx.To))
+	}
+	delegation := findDelegation(validator, delegator.Bytes())
+	if delegation == nil || delegation.Amount < tx.Value || tx.Value == 0 {
+		return fmt.Errorf("invalid unbond amount %d", tx.Value)
+	}
+
+	delegation.Amount -= tx.Value
+	removeEmptyDelegations(validator)
+	if activeStake(ledger) == 0 {
+		return fmt.Errorf("the last active stake cannot be unbonded")
+	}
+
+	ledger.Unbondings = append(ledger.Unbondings, &proto.Unbonding{
+		Delegator:     delegator.Bytes(),
+		Validator:     validator.PublicKey,
+		Amount:        tx.Value,
+		Height:        header.GetHeight(),
+		ReleaseHeight: header.GetHeight() + e.config.UnbondingPeriod,
+	})
+
+	return nil
+}
+
+// slash verifies the double signing evidence of the transaction, slashes the stake of the validator,
+// including the stake unbonded since the offence, and jails it so it is no longer selected as proposer
+func (e *Engine) slash(ledger *proto.StakingState, header *proto.Header, tx *proto.Transaction) error {
+	evidence, err := types.DeserializeEvidence(tx.Data)
+	if err != nil {
+		return err
+	}
+	if err := types.VerifyEvidence(evidence); err != nil {
+		return err
+	}
+	offenceHeight := evidence.HeaderA.Height
+	if offenceHeight >= header.GetHeight() {
+		return fmt.Errorf("evidence of an offence at height %d is not in the past", offenceHeight)
+	}
+
+	validator := findValidator(ledger, evidence.PublicKey)
+	if validator == nil {
+		return fmt.Errorf("(%s) is not a validator", hex.EncodeToString(evidence.PublicKey))
+	}
+	if validator.Jailed {
+		return fmt.Errorf("validator (%s) is already jailed", hex.EncodeToString(evidence.PublicKey))
+	}
+
+	for _, delegation := range validator.Delegations {
+		delegation.Amount -= mulDiv(delegation.Amount, e.config.SlashPercent, 100)
+	}
+	removeEmptyDelegations(validator)
+	for _, unbonding := range ledger.Unbondings {
+		if bytes.Equal(unbonding.Validator, validator.PublicKey) && unbonding.Height >= offenceHeight {
+			unbonding.Amount -= mulDiv(unbonding.Amount, e.config.SlashPercent, 100)
+		}
+	}
+	validator.Jailed = true
+
+	if activeStake(ledger) == 0 {
+		return fmt.Errorf("the last active validator cannot be jailed")
+	}
+
+	return nil
+}
+
+// distributeRewards credits the delegators of the proposer with the block reward not paid as commission,
+// in proportion to their stake. The remainder of the division goes to the proposer.
+func (e *Engine) distributeRewards(state consensus.State, ledger *proto.StakingState, b *proto.Block) error {
+	height := b.Header.GetHeight()
+	share := e.config.Rewards.Reward(height) - e.Reward(height)
+	if share == 0 {
+		return nil
+	}
+	proposer, err := types.AccountAddress(b.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	var distributed uint64
+	// The proposer stake can be gone if it was unbonded or slashed in the block
+	if validator := findValidator(ledger, b.PublicKey); validator != nil && validatorStake(validator) > 0 {
+		stake := validatorStake(validator)
+		for _, delegation := range validator.Delegations {
+			amount := mulDiv(share, delegation.Amount, stake)
+			delegator, err := crypto.AddressFromBytes(delegation.Delegator)
+			if err != nil {
+				return err
+			}
+			if err := state.AddBalance(delegator, amount); err != nil {
+				return err
+			}
+			distributed += amount
+		}
+	}
+
+	return state.AddBalance(proposer, share-distributed)
+}
@@ -0,0 +1,185 @@
+package pos
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProposerSelection(t *testing.T) {
+	keys, validators := generateValidators(t, 1000, 1)
+	engine, bc := newTestChain(t, validators, 50, 1)
+
+	// The validator with most of the stake proposes most of the blocks
+	proposed := make(map[string]int)
+	for i := 0; i < 50; i++ {
+		proposer := nextProposer(t, engine, bc, keys)
+		proposed[string(proposer.PublicKey().Bytes())]++
+
+		// Any other validator is rejected
+		for _, key := range keys {
+			if key != proposer {
+				_, err := bc.BuildBlock(key, nil)
+				assert.Error(t, err)
+			}
+		}
+		addBlock(t, engine, bc, keys, nil)
+	}
+	assert.Greater(t, proposed[string(validators[0].PublicKey)], 40)
+}
+
+func TestBondAndUnbond(t *testing.T) {
+	keys, validators := generateValidators(t, 100)
+	engine, bc := newTestChain(t, validators, 50, 2)
+	validator := keys[string(validators[0].PublicKey)]
+	delegator, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	// The validator receives the commission and the whole delegators share of the reward
+	addBlock(t, engine, bc, keys, nil)
+	assert.Equal(t, uint64(100), balance(bc, validator))
+
+	transfer := &proto.Transaction{From: validator.PublicKey().Bytes(), To: delegator.PublicKey().Bytes(), Value: 40, Nonce: 1}
+	assert.Nil(t, types.SignTransaction(validator, transfer))
+	addBlock(t, engine, bc, keys, []*proto.Transaction{transfer})
+
+	// Bonding to a key which is not a validator is rejected
+	bond := types.NewBondTransaction(delegator.PublicKey().Bytes(), delegator.PublicKey().Bytes()[1:], 30, 1)
+	assert.Nil(t, types.SignTransaction(delegator, bond))
+	block, err := bc.BuildBlock(validator, []*proto.Transaction{bond})
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(block))
+
+	// The delegators share (50) of the block is distributed in proportion to the stake (100 and 30)
+	bond = types.NewBondTransaction(delegator.PublicKey().Bytes(), validator.PublicKey().Bytes(), 30, 1)
+	assert.Nil(t, types.SignTransaction(delegator, bond))
+	validatorBalance := balance(bc, validator)
+	addBlock(t, engine, bc, keys, []*proto.Transaction{bond})
+	assert.Equal(t, uint64(40-30+11), balance(bc, delegator))
+	assert.Equal(t, validatorBalance+50+38+1, balance(bc, validator))
+
+	// Unbonded stake stops earning rewards and is released after the unbonding period
+	unbond := types.NewUnbondTransaction(delegator.PublicKey().Bytes(), validator.PublicKey().Bytes(), 30, 2)
+	assert.Nil(t, types.SignTransaction(delegator, unbond))
+	addBlock(t, engine, bc, keys, []*proto.Transaction{unbond})
+	addBlock(t, engine, bc, keys, nil)
+	assert.Equal(t, uint64(21), balance(bc, delegator))
+	addBlock(t, engine, bc, keys, nil)
+	assert.Equal(t, uint64(51), balance(bc, delegator))
+
+	validatorsState, err := engine.Validators(bc)
+	assert.Nil(t, err)
+	assert.Len(t, validatorsState[0].Delegations, 1)
+}
+
+func TestSlashDoubleSigning(t *testing.T) {
+	keys, validators := generateValidators(t, 100, 100)
+	engine, bc := newTestChain(t, validators, 100, 10)
+	reporter, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	// The proposer of block 1 signs two different headers at height 1
+	offender := nextProposer(t, engine, bc, keys)
+	block := addBlock(t, engine, bc, keys, nil)
+	other := &proto.Header{Height: 1, PrevBlockHash: block.Header.PrevBlockHash, Timestamp: block.Header.Timestamp + 1}
+	otherHash, err := types.HashHeader(other)
+	assert.Nil(t, err)
+	otherSignature, err := offender.Sign(otherHash)
+	assert.Nil(t, err)
+
+	evidence := &proto.DoubleSignEvidence{
+		PublicKey:  offender.PublicKey().Bytes(),
+		HeaderA:    block.Header,
+		SignatureA: block.Signature,
+		HeaderB:    other,
+		SignatureB: otherSignature.Bytes(),
+	}
+	assert.NoError(t, types.VerifyEvidence(evidence))
+
+	tx, err := types.NewEvidenceTransaction(reporter.PublicKey().Bytes(), evidence, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, types.SignTransaction(reporter, tx))
+	addBlock(t, engine, bc, keys, []*proto.Transaction{tx})
+
+	// The offender lost 10% of its stake and is jailed, so it does not propose blocks anymore
+	validatorsState, err := engine.Validators(bc)
+	assert.Nil(t, err)
+	for _, validator := range validatorsState {
+		if string(validator.PublicKey) == string(offender.PublicKey().Bytes()) {
+			assert.True(t, validator.Jailed)
+			assert.Equal(t, uint64(90), validator.Delegations[0].Amount)
+		}
+	}
+	// The jailed offender has no finality voting weight
+	weights, err := engine.Weights(bc)
+	assert.Nil(t, err)
+	assert.Len(t, weights, len(validators)-1)
+	assert.Zero(t, weights[string(offender.PublicKey().Bytes())])
+
+	for i := 0; i < 5; i++ {
+		assert.NotEqual(t, offender, nextProposer(t, engine, bc, keys))
+		addBlock(t, engine, bc, keys, nil)
+	}
+
+	// The same evidence cannot be used twice
+	tx, err = types.NewEvidenceTransaction(reporter.PublicKey().Bytes(), evidence, 2)
+	assert.Nil(t, err)
+	assert.Nil(t, types.SignTransaction(reporter, tx))
+	block, err = bc.BuildBlock(nextProposer(t, engine, bc, keys), []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(block))
+}
+
+// generateValidators generates validator keys with the given stakes, returning them indexed by public key together with the genesis validators
+func generateValidators(t *testing.T, stakes ...uint64) (map[string]*crypto.PrivateKey, []GenesisValidator) {
+	keys := make(map[string]*crypto.PrivateKey)
+	validators := make([]GenesisValidator, 0, len(stakes))
+	for _, stake := range stakes {
+		privateKey, err := crypto.GeneratePrivateKey()
+		assert.Nil(t, err)
+		keys[string(privateKey.PublicKey().Bytes())] = privateKey
+		validators = append(validators, GenesisValidator{PublicKey: privateKey.PublicKey().Bytes(), Stake: stake})
+	}
+	return keys, validators
+}
+
+// newTestChain creates a Proof-of-Stake engine with a reward of 100 and a blockchain using it
+func newTestChain(t *testing.T, validators []GenesisValidator, commission uint64, unbondingPeriod uint64) (*Engine, *core.Blockchain) {
+	engine, err := New(Config{
+		Rewards:         consensus.RewardSchedule{InitialReward: 100},
+		Commission:      commission,
+		UnbondingPeriod: unbondingPeriod,
+		SlashPercent:    10,
+		Validators:      validators,
+	})
+	assert.Nil(t, err)
+	return engine, core.NewBlockchain(core.NewMemorystore(), core.WithEngine(engine))
+}
+
+// nextProposer returns the key of the proposer of the next block
+func nextProposer(t *testing.T, engine *Engine, bc *core.Blockchain, keys map[string]*crypto.PrivateKey) *crypto.PrivateKey {
+	header, err := bc.GetHeaderByHeight(bc.Height())
+	assert.Nil(t, err)
+	hash, err := types.HashHeader(header)
+	assert.Nil(t, err)
+	proposer, err := engine.Proposer(bc, hash)
+	assert.Nil(t, err)
+	return keys[string(proposer)]
+}
+
+// addBlock adds a block proposed by the selected proposer with the given transactions
+func addBlock(t *testing.T, engine *Engine, bc *core.Blockchain, keys map[string]*crypto.PrivateKey, txs []*proto.Transaction) *proto.Block {
+	block, err := bc.BuildBlock(nextProposer(t, engine, bc, keys), txs)
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	return block
+}
+
+func balance(bc *core.Blockchain, key *crypto.PrivateKey) uint64 {
+	return bc.State().Balance(key.PublicKey().Address())
+}
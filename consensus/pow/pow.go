@@ -0,0 +1,143 @@
+package pow
+
+import (
+	"fmt"
+	"math/bits"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// maxDifficulty is the number of bits of a header hash
+const maxDifficulty = 256
+
+// Config holds the Proof-of-Work parameters
+type Config struct {
+	// InitialDifficulty is the difficulty of the first blocks, in leading zero bits of the header hash
+	InitialDifficulty uint32
+	// MinDifficulty is the lowest difficulty the retargeting can reach
+	MinDifficulty uint32
+	// TargetBlockTime is the expected time between blocks the difficulty is adjusted to
+	TargetBlockTime time.Duration
+	// Rewards is the block reward schedule
+	Rewards consensus.RewardSchedule
+}
+
+// DefaultConfig is the Proof-of-Work configuration used when none is provided
+var DefaultConfig = Config{
+	InitialDifficulty: 16,
+	MinDifficulty:     8,
+	TargetBlockTime:   10 * time.Second,
+	Rewards:           consensus.DefaultRewardSchedule,
+}
+
+// Engine is the Proof-of-Work consensus engine. A block is sealed by finding a header nonce
+// for which the header hash has at least as many leading zero bits as the header difficulty.
+// The difficulty is adjusted on every block according to the time since the parent block.
+type Engine struct {
+	config Config
+}
+
+// New creates a new Proof-of-Work engine with the given configuration
+func New(config Config) *Engine {
+	return &Engine{
+		config: config,
+	}
+}
+
+// CalcDifficulty returns the difficulty of a block with the given timestamp on top of the parent.
+// The difficulty goes up by one if the block came in less than half the target block time,
+// and down by one if it took more than twice the target block time.
+func (e *Engine) CalcDifficulty(parent *proto.Header, timestamp int64) uint32 {
+	// The genesis block is not mined so the blocks on top of it start at the initial difficulty
+	if parent == nil || parent.Height == 0 {
+		return e.config.InitialDifficulty
+	}
+
+	difficulty := parent.Difficulty
+	elapsed := time.Duration(timestamp - parent.Timestamp)
+	switch {
+	case elapsed < e.config.TargetBlockTime/2 && difficulty < maxDifficulty:
+		difficulty++
+	case elapsed > e.config.TargetBlockTime*2 && difficulty > e.config.MinDifficulty:
+		difficulty--
+	}
+	return difficulty
+}
+
+// Prepare sets the difficulty of the header
+func (e *Engine) Prepare(chain consensus.ChainReader, parent *proto.Header, header *proto.Header) error {
+	header.Difficulty = e.CalcDifficulty(parent, header.Timestamp)
+	return nil
+}
+
+// VerifyHeader checks the header difficulty and that the header hash meets it
+func (e *Engine) VerifyHeader(chain consensus.ChainReader, parent *proto.Header, header *proto.Header) error {
+	if expected := e.CalcDifficulty(parent, header.Timestamp); header.Difficulty != expected {
+		return fmt.Errorf("invalid difficulty %d, expected %d", header.Difficulty, expected)
+	}
+	hash, err := types.HashHeader(header)
+	if err != nil {
+		return err
+	}
+	if !MeetsDifficulty(hash, header.Difficulty) {
+		return fmt.Errorf("header hash does not meet the difficulty %d", header.Difficulty)
+	}
+	return nil
+}
+
+// VerifyBlock accepts any block signer
+func (e *Engine) VerifyBlock(chain consensus.ChainReader, b *proto.Block) error {
+	return nil
+}
+
+// Seal mines the block by searching a nonce meeting the difficulty and signs it with the signer key
+func (e *Engine) Seal(chain consensus.ChainReader, b *proto.Block, signer *crypto.PrivateKey) error {
+	for nonce := uint64(0); ; nonce++ {
+		b.Header.Nonce = nonce
+		hash, err := types.HashHeader(b.Header)
+		if err != nil {
+			return err
+		}
+		if MeetsDifficulty(hash, b.Header.Difficulty) {
+			break
+		}
+		if nonce == ^uint64(0) {
+			return fmt.Errorf("no nonce meets the difficulty %d", b.Header.Difficulty)
+		}
+	}
+
+	_, err := types.SignBlock(signer, b)
+	return err
+}
+
+// ApplyTransaction rejects special transactions, the Proof-of-Work engine has none
+func (e *Engine) ApplyTransaction(chain consensus.ChainReader, state consensus.State, header *proto.Header, tx *proto.Transaction) error {
+	return consensus.UnsupportedTransaction(tx)
+}
+
+// Finalize does nothing, the Proof-of-Work engine has no consensus state
+func (e *Engine) Finalize(chain consensus.ChainReader, state consensus.State, b *proto.Block) error {
+	return nil
+}
+
+// Reward returns the block reward at the given height
+func (e *Engine) Reward(height uint64) uint64 {
+	return e.config.Rewards.Reward(height)
+}
+
+// MeetsDifficulty returns true if the hash has at least difficulty leading zero bits
+func MeetsDifficulty(hash []byte, difficulty uint32) bool {
+	var zeros uint32
+	for _, b := range hash {
+		if b != 0 {
+			zeros += uint32(bits.LeadingZeros8(b))
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= difficulty
+}
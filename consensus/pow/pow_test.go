@@ -0,0 +1,73 @@
+package pow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeetsDifficulty(t *testing.T) {
+	hash := []byte{0x00, 0x0f, 0xff}
+	assert.True(t, MeetsDifficulty(hash, 0))
+	assert.True(t, MeetsDifficulty(hash, 12))
+	assert.False(t, MeetsDifficulty(hash, 13))
+	assert.True(t, MeetsDifficulty([]byte{0x00, 0x00}, 16))
+}
+
+func TestCalcDifficulty(t *testing.T) {
+	engine := New(Config{InitialDifficulty: 10, MinDifficulty: 9, TargetBlockTime: 10 * time.Second})
+	parent := &proto.Header{Height: 5, Timestamp: 0, Difficulty: 10}
+
+	assert.Equal(t, uint32(10), engine.CalcDifficulty(nil, 0))
+	assert.Equal(t, uint32(11), engine.CalcDifficulty(parent, int64(time.Second)))
+	assert.Equal(t, uint32(10), engine.CalcDifficulty(parent, int64(10*time.Second)))
+	assert.Equal(t, uint32(9), engine.CalcDifficulty(parent, int64(time.Minute)))
+
+	// The difficulty does not go below the minimum
+	parent.Difficulty = 9
+	assert.Equal(t, uint32(9), engine.CalcDifficulty(parent, int64(time.Minute)))
+}
+
+func TestProofOfWork(t *testing.T) {
+	engine := New(Config{InitialDifficulty: 8, MinDifficulty: 4, TargetBlockTime: time.Second, Rewards: consensus.DefaultRewardSchedule})
+	bc := core.NewBlockchain(core.NewMemorystore(), core.WithEngine(engine))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	for i := 0; i < 3; i++ {
+		block, err := bc.BuildBlock(privateKey, nil)
+		assert.Nil(t, err)
+		hash, err := types.HashBlock(block)
+		assert.Nil(t, err)
+		assert.True(t, MeetsDifficulty(hash, block.Header.Difficulty))
+		assert.NoError(t, bc.AddBlock(block))
+	}
+
+	// A block whose nonce does not meet the difficulty is rejected
+	block, err := bc.BuildBlock(privateKey, nil)
+	assert.Nil(t, err)
+	for {
+		block.Header.Nonce++
+		hash, err := types.HashHeader(block.Header)
+		assert.Nil(t, err)
+		if !MeetsDifficulty(hash, block.Header.Difficulty) {
+			break
+		}
+	}
+	_, err = types.SignBlock(privateKey, block)
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(block))
+
+	// A block with the wrong difficulty is rejected even if its hash meets it
+	block, err = bc.BuildBlock(privateKey, nil)
+	assert.Nil(t, err)
+	block.Header.Difficulty--
+	assert.Nil(t, engine.Seal(bc, block, privateKey))
+	assert.Error(t, bc.AddBlock(block))
+}
@@ -0,0 +1,27 @@
+package consensus
+
+// RewardSchedule defines the block reward (subsidy) paid to block producers through the coinbase transaction.
+// The reward starts at InitialReward and is halved every HalvingInterval blocks.
+type RewardSchedule struct {
+	InitialReward   uint64
+	HalvingInterval uint64
+}
+
+// DefaultRewardSchedule is the reward schedule used when none is configured
+var DefaultRewardSchedule = RewardSchedule{
+	InitialReward:   50,
+	HalvingInterval: 210_000,
+}
+
+// Reward returns the block reward for the block at the given height
+func (s RewardSchedule) Reward(height uint64) uint64 {
+	if s.HalvingInterval == 0 {
+		return s.InitialReward
+	}
+	halvings := height / s.HalvingInterval
+	// Shifting by 64 or more bits would leave the reward unchanged instead of zero
+	if halvings >= 64 {
+		return 0
+	}
+	return s.InitialReward >> halvings
+}
@@ -0,0 +1,20 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewardSchedule(t *testing.T) {
+	schedule := RewardSchedule{InitialReward: 100, HalvingInterval: 10}
+
+	assert.Equal(t, uint64(100), schedule.Reward(0))
+	assert.Equal(t, uint64(100), schedule.Reward(9))
+	assert.Equal(t, uint64(50), schedule.Reward(10))
+	assert.Equal(t, uint64(25), schedule.Reward(25))
+	assert.Equal(t, uint64(0), schedule.Reward(10*64))
+
+	noHalving := RewardSchedule{InitialReward: 7}
+	assert.Equal(t, uint64(7), noHalving.Reward(1_000_000))
+}
@@ -3,12 +3,15 @@ package core
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/consensus/instant"
 	"github.com/joaoh82/marvinblockchain/crypto"
 	"github.com/joaoh82/marvinblockchain/proto"
 	"github.com/joaoh82/marvinblockchain/types"
@@ -18,44 +21,136 @@ import (
 // chainMnemonic is the mnemonic for the blockchain private key composed of 12 words
 const chainMnemonic = "velvet echo quill jungle nimbus crescent whisk anchor harbor tangle mosaic horizon"
 
+// genesisTimestamp is the fixed timestamp of the genesis block (2024-08-26 00:00:00 UTC), so every node creates the same genesis block
+const genesisTimestamp = 1724630400000000000
+
+// ErrInvalidBlock is wrapped by the errors of blocks breaking the validation rules, as opposed to blocks
+// that are already known, not the next block or only valid on another fork
+var ErrInvalidBlock = errors.New("invalid block")
+
 type Blockchain struct {
 	headers *HeaderList
 	store   Storage
+	state   *AccountState
 	lock    sync.RWMutex
+	engine  consensus.Engine
+	params  types.ConsensusParams
+
+	// genesisAlloc are the accounts funded by the genesis block
+	genesisAlloc []GenesisAccount
+
+	// blockHandlers are notified of every block added
+	blockHandlers []func(b *proto.Block)
+	// processHandlers are notified of every block processed by AddBlock, added or rejected
+	processHandlers []func(b *proto.Block, elapsed time.Duration, err error)
+
+	// txIndex locates the transactions of the blockchain by their hex encoded hash
+	txIndex map[string]TxLocation
+
+	// finalized is the height of the last block finalized by the validators
+	finalized int
+
+	clock          Clock
+	medianTimeSpan int
+	maxFutureDrift time.Duration
 }
 
-// NewBlockchain creates a new blockchain
-func NewBlockchain(store Storage) *Blockchain {
+// Option configures optional parameters of the blockchain
+type Option func(*Blockchain)
+
+// WithEngine sets the consensus engine of the blockchain
+func WithEngine(engine consensus.Engine) Option {
+	return func(bc *Blockchain) {
+		bc.engine = engine
+	}
+}
+
+// WithConsensusParams sets the block and transaction limits of the blockchain
+func WithConsensusParams(params types.ConsensusParams) Option {
+	return func(bc *Blockchain) {
+		bc.params = params
+	}
+}
+
+// NewBlockchain creates a new blockchain. Unless another consensus engine is configured,
+// the blockchain uses the instant seal engine with the default reward schedule. The instant seal engine
+// accepts blocks sealed by any key, so it is only meant for the dev node and the tests: nodes accepting
+// blocks from the network configure their engine with WithEngine.
+func NewBlockchain(store Storage, opts ...Option) *Blockchain {
 	bc := &Blockchain{
 		headers: NewHeaderList(),
 		store:   store,
+		state:   NewAccountState(),
+		engine:  instant.New(consensus.DefaultRewardSchedule),
+		params:  types.DefaultConsensusParams,
+		txIndex: make(map[string]TxLocation),
+
+		clock:          systemClock{},
+		medianTimeSpan: DefaultMedianTimeSpan,
+		maxFutureDrift: DefaultMaxFutureDrift,
+	}
+	for _, opt := range opts {
+		opt(bc)
 	}
 
 	// Genesis block - Genesis block is the first block in the blockchain and has the height 0
-	genesisBlock, err := createGenesisBlock()
+	genesisBlock, err := bc.createGenesisBlock()
 	if err != nil {
 		panic(err)
 	}
-	bc.addBlockWithoutValidation(genesisBlock)
+	if err := bc.addBlockWithoutValidation(genesisBlock); err != nil {
+		panic(err)
+	}
 
 	return bc
 }
 
 // AddBlock adds a block to the blockchain
 func (bc *Blockchain) AddBlock(b *proto.Block) error {
-	// Validate the block before adding it to the blockchain
-	if err := bc.ValidateBlock(b); err != nil {
+	start := time.Now()
+	err := bc.validateAndAddBlock(b)
+
+	bc.lock.RLock()
+	handlers := bc.processHandlers
+	bc.lock.RUnlock()
+	for _, handler := range handlers {
+		handler(b, time.Since(start), err)
+	}
+	return err
+}
+
+// validateAndAddBlock validates the block, then adds it to the blockchain
+func (bc *Blockchain) validateAndAddBlock(b *proto.Block) error {
+	state, err := bc.validateBlock(b)
+	if err != nil {
 		return err
 	}
-	return bc.addBlockWithoutValidation(b)
+	return bc.addBlock(b, state)
 }
 
-// addBlock adds a block to the blockchain without validation
+// addBlockWithoutValidation adds a block to the blockchain without validation, only applying its state changes
 func (bc *Blockchain) addBlockWithoutValidation(b *proto.Block) error {
-	bc.lock.Lock()
-	defer bc.lock.Unlock()
+	state, err := bc.processBlock(b)
+	if err != nil {
+		return err
+	}
+	return bc.addBlock(b, state)
+}
 
+// addBlock adds the block header to the header list, commits the block state changes, stores the block
+// and notifies the block handlers
+func (bc *Blockchain) addBlock(b *proto.Block, state *stateOverlay) error {
+	locations, err := txLocations(b)
+	if err != nil {
+		return err
+	}
+
+	bc.lock.Lock()
 	bc.headers.Add(b.Header)
+	state.commit()
+	for hash, location := range locations {
+		bc.txIndex[hash] = location
+	}
 
 	// Log the block added to the blockchain
 	log.Info().Fields(map[string]interface{}{
@@ -64,46 +159,145 @@ func (bc *Blockchain) addBlockWithoutValidation(b *proto.Block) error {
 	}).Msg("block added to blockchain")
 
 	// Store the block in the storage
-	return bc.store.Put(b)
+	err = bc.store.Put(b)
+	handlers := bc.blockHandlers
+	bc.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, handler := range handlers {
+		handler(b)
+	}
+	return nil
+}
+
+// OnBlockAdded registers a handler called with every block added to the blockchain
+func (bc *Blockchain) OnBlockAdded(handler func(b *proto.Block)) {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	bc.blockHandlers = append(bc.blockHandlers, handler)
+}
+
+// OnBlockProcessed registers a handler called with every block passed to AddBlock, how long validating
+// and adding it took, and the error if the block was rejected
+func (bc *Blockchain) OnBlockProcessed(handler func(b *proto.Block, elapsed time.Duration, err error)) {
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	bc.processHandlers = append(bc.processHandlers, handler)
+}
+
+// HasBlockHash checks if the blockchain has the block with the given hash
+func (bc *Blockchain) HasBlockHash(hash []byte) bool {
+	_, err := bc.store.Get(hex.EncodeToString(hash))
+	return err == nil
 }
 
 // HasBlock checks if the blockchain has a block at the given height
 func (bc *Blockchain) HasBlock(height int) bool {
-	return height <= bc.Height()
+	return height >= 0 && height <= bc.Height()
 }
 
 // ValidateBlock checks if the block is valid to be added to the blockchain
 func (bc *Blockchain) ValidateBlock(b *proto.Block) error {
+	_, err := bc.validateBlock(b)
+	return err
+}
+
+// validateBlock validates the block and returns its state changes on top of the current state
+func (bc *Blockchain) validateBlock(b *proto.Block) (*stateOverlay, error) {
+	// Refuse blocks that would reorganize the blockchain past a finalized block
+	if err := bc.validateNotFinalized(b); err != nil {
+		return nil, reject(RejectFinalized, err)
+	}
+
 	// Check if the blockchain already has the block
 	if bc.HasBlock(int(b.Header.GetHeight())) {
 		blockHash, _ := types.HashBlock(b)
-		return fmt.Errorf("blockchain already has block at height (%d) with hash (%s)", b.Header.Height, hex.EncodeToString(blockHash))
+		return nil, reject(RejectKnown, fmt.Errorf("blockchain already has block at height (%d) with hash (%s)", b.Header.Height, hex.EncodeToString(blockHash)))
 	}
 
 	// Check if the block height is the next height in the blockchain
 	if b.Header.GetHeight() != uint64(bc.Height()+1) {
-		return fmt.Errorf("block height %d is not the next height in the blockchain. Current Height: %d", b.Header.GetHeight(), bc.Height())
+		return nil, reject(RejectHeight, fmt.Errorf("block height %d is not the next height in the blockchain. Current Height: %d", b.Header.GetHeight(), bc.Height()))
+	}
+
+	// Check if the block is within the size and transaction limits
+	if err := bc.params.ValidateBlock(b); err != nil {
+		return nil, reject(RejectLimits, fmt.Errorf("%w: %w", ErrInvalidBlock, err))
+	}
+
+	// Check if the block timestamp is within the allowed range
+	if err := bc.validateTimestamp(b.Header.GetTimestamp()); err != nil {
+		return nil, reject(RejectTimestamp, err)
 	}
 
 	// Check if the block is valid
 	if ok, err := types.VerifyBlock(b); err != nil || !ok {
-		return fmt.Errorf("block verification failed: %v", err)
+		return nil, reject(RejectSignature, fmt.Errorf("%w: block verification failed: %v", ErrInvalidBlock, err))
+	}
+
+	// Check that the header commits to the transactions of the block
+	txHash, err := types.CalculateTxHash(b.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(txHash, b.Header.TxHash) {
+		return nil, reject(RejectTxHash, fmt.Errorf("%w: transactions hash does not match the header", ErrInvalidBlock))
+	}
+
+	// Check the coinbase transaction and the reward claimed by the block producer
+	if err := bc.validateCoinbase(b); err != nil {
+		return nil, reject(RejectCoinbase, fmt.Errorf("%w: %w", ErrInvalidBlock, err))
 	}
 
 	// Retrieve the last header in the blockchain, calculate the hash and compare it with the previous block hash
 	lastHeader, err := bc.GetHeaderByHeight(bc.Height())
 	if err != nil {
-		return err
+		return nil, err
 	}
 	lastHeaderHash, err := types.HashHeader(lastHeader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// Check if the previous block hash in the new block is the same as the hash of the last header in the blockchain
 	if !bytes.Equal(lastHeaderHash, b.Header.PrevBlockHash) {
-		return fmt.Errorf("invalid previous block hash")
+		return nil, reject(RejectPrevHash, fmt.Errorf("invalid previous block hash"))
+	}
+
+	// Check the consensus rules of the header and the block
+	if err := bc.engine.VerifyHeader(bc, lastHeader, b.Header); err != nil {
+		return nil, reject(RejectConsensus, fmt.Errorf("%w: header consensus verification failed: %v", ErrInvalidBlock, err))
+	}
+	if err := bc.engine.VerifyBlock(bc, b); err != nil {
+		return nil, reject(RejectConsensus, fmt.Errorf("%w: block consensus verification failed: %v", ErrInvalidBlock, err))
 	}
 
+	// Apply the transactions of the block to check the balances and nonces of the accounts
+	overlay, err := bc.processBlock(b)
+	if err != nil {
+		return nil, reject(RejectTransactions, fmt.Errorf("%w: %w", ErrInvalidBlock, err))
+	}
+	return overlay, nil
+}
+
+// VerifyHeader checks that the header extends the parent header and follows the consensus rules of the engine
+func (bc *Blockchain) VerifyHeader(parent *proto.Header, header *proto.Header) error {
+	if header.GetHeight() != parent.GetHeight()+1 {
+		return fmt.Errorf("header height %d does not follow parent height %d", header.GetHeight(), parent.GetHeight())
+	}
+	parentHash, err := types.HashHeader(parent)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(parentHash, header.PrevBlockHash) {
+		return fmt.Errorf("invalid previous block hash")
+	}
+	if err := bc.engine.VerifyHeader(bc, parent, header); err != nil {
+		return fmt.Errorf("%w: header consensus verification failed: %v", ErrInvalidBlock, err)
+	}
 	return nil
 }
 
@@ -153,8 +347,23 @@ func (bc *Blockchain) Height() int {
 	return bc.headers.Height()
 }
 
-// createGenesisBlock creates the genesis block of the blockchain
-func createGenesisBlock() (*proto.Block, error) {
+// State returns the account state at the last block of the blockchain
+func (bc *Blockchain) State() consensus.StateReader {
+	return bc.state
+}
+
+// Params returns the block and transaction limits of the blockchain
+func (bc *Blockchain) Params() types.ConsensusParams {
+	return bc.params
+}
+
+// Engine returns the consensus engine of the blockchain
+func (bc *Blockchain) Engine() consensus.Engine {
+	return bc.engine
+}
+
+// createGenesisBlock creates the genesis block of the blockchain, with the consensus fields prepared by the engine
+func (bc *Blockchain) createGenesisBlock() (*proto.Block, error) {
 	private_key, err := crypto.NewPrivateKeyfromMnemonic(chainMnemonic)
 	if err != nil {
 		return nil, err
@@ -164,11 +373,22 @@ func createGenesisBlock() (*proto.Block, error) {
 		PrevBlockHash: make([]byte, 32), // Genesis block has no previous block, so the hash is 32 bytes of zeros
 		Version:       1,
 		Height:        0, // Genesis block height is 0
-		Timestamp:     time.Now().UnixNano(),
+		Timestamp:     genesisTimestamp,
+	}
+	// The only transactions of the genesis block fund the genesis accounts
+	txs, err := bc.genesisTransactions()
+	if err != nil {
+		return nil, err
+	}
+	if header.TxHash, err = types.CalculateTxHash(txs); err != nil {
+		return nil, err
+	}
+	if err := bc.engine.Prepare(bc, nil, header); err != nil {
+		return nil, err
 	}
-	// Genesis block has no transactions and only needs the header
 	block := &proto.Block{
-		Header: header,
+		Header:       header,
+		Transactions: txs,
 	}
 
 	// Sign the block
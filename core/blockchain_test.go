@@ -1,8 +1,11 @@
 package core
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/joaoh82/marvinblockchain/consensus"
 	"github.com/joaoh82/marvinblockchain/crypto"
 	"github.com/joaoh82/marvinblockchain/proto"
 	"github.com/joaoh82/marvinblockchain/types"
@@ -15,6 +18,30 @@ func TestNewBlockchain(t *testing.T) {
 	assert.True(t, bc.HasBlock(0))
 }
 
+func TestGenesisAlloc(t *testing.T) {
+	privKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	address := privKey.PublicKey().Address()
+
+	bc := NewBlockchain(NewMemorystore(), WithGenesisAlloc([]GenesisAccount{{Address: address, Balance: 100}}))
+	assert.Equal(t, uint64(100), bc.State().Balance(address))
+	genesis, err := bc.GetBlockByHeight(0)
+	assert.Nil(t, err)
+	assert.Len(t, genesis.Transactions, 1)
+
+	// The allocation is part of the genesis hash
+	other := NewBlockchain(NewMemorystore(), WithGenesisAlloc([]GenesisAccount{{Address: address, Balance: 200}}))
+	empty := NewBlockchain(NewMemorystore())
+	hash, err := types.HashHeader(bc.headers.Get(0))
+	assert.Nil(t, err)
+	otherHash, err := types.HashHeader(other.headers.Get(0))
+	assert.Nil(t, err)
+	emptyHash, err := types.HashHeader(empty.headers.Get(0))
+	assert.Nil(t, err)
+	assert.NotEqual(t, hash, otherHash)
+	assert.NotEqual(t, hash, emptyHash)
+}
+
 func TestHasBlock(t *testing.T) {
 	bc := NewBlockchain(NewMemorystore())
 
@@ -29,6 +56,9 @@ func TestHasBlock(t *testing.T) {
 	assert.True(t, bc.HasBlock(50))
 	assert.False(t, bc.HasBlock(101))
 	assert.True(t, bc.HasBlock(100))
+	assert.False(t, bc.HasBlock(-1))
+	_, err := bc.GetHeaderByHeight(-1)
+	assert.Error(t, err)
 }
 
 func TestAddBlock(t *testing.T) {
@@ -48,6 +78,67 @@ func TestAddBlock(t *testing.T) {
 	existingBlock := GenerateRandomBlock(t, 50, []byte("hash"))
 	err := bc.AddBlock(existingBlock)
 	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrInvalidBlock))
+	assert.Equal(t, RejectKnown, RejectReason(err))
+
+	// Add a block with a tampered signature
+	prevHash, err := types.HashHeader(bc.headers.Last())
+	assert.NoError(t, err)
+	tampered := GenerateRandomBlock(t, uint64(numBlocks+1), prevHash)
+	tampered.Header.Timestamp++
+	err = bc.AddBlock(tampered)
+	assert.ErrorIs(t, err, ErrInvalidBlock)
+	assert.Equal(t, RejectSignature, RejectReason(err))
+}
+
+func TestOnBlockProcessed(t *testing.T) {
+	bc := NewBlockchain(NewMemorystore())
+	var processed []error
+	bc.OnBlockProcessed(func(b *proto.Block, elapsed time.Duration, err error) {
+		assert.Greater(t, elapsed, time.Duration(0))
+		processed = append(processed, err)
+	})
+
+	prevHash, err := types.HashHeader(bc.headers.Last())
+	assert.NoError(t, err)
+	block := GenerateRandomBlock(t, 1, prevHash)
+	assert.NoError(t, bc.AddBlock(block))
+	assert.Error(t, bc.AddBlock(block))
+
+	assert.Len(t, processed, 2)
+	assert.NoError(t, processed[0])
+	assert.Equal(t, RejectKnown, RejectReason(processed[1]))
+	assert.Equal(t, RejectOther, RejectReason(errors.New("storage failure")))
+}
+
+func TestGetBlock(t *testing.T) {
+	bc := NewBlockchain(NewMemorystore())
+	var added []*proto.Block
+	bc.OnBlockAdded(func(b *proto.Block) {
+		added = append(added, b)
+	})
+
+	prevHash, err := types.HashHeader(bc.headers.Last())
+	assert.NoError(t, err)
+	block := GenerateRandomBlock(t, 1, prevHash)
+	assert.NoError(t, bc.AddBlock(block))
+	assert.Equal(t, []*proto.Block{block}, added)
+
+	hash, err := types.HashBlock(block)
+	assert.NoError(t, err)
+	assert.True(t, bc.HasBlockHash(hash))
+	stored, err := bc.GetBlockByHash(hash)
+	assert.NoError(t, err)
+	assert.Equal(t, block, stored)
+	stored, err = bc.GetBlockByHeight(1)
+	assert.NoError(t, err)
+	assert.Equal(t, block, stored)
+
+	assert.False(t, bc.HasBlockHash([]byte("unknown")))
+	_, err = bc.GetBlockByHash([]byte("unknown"))
+	assert.Error(t, err)
+	_, err = bc.GetBlockByHeight(2)
+	assert.Error(t, err)
 }
 
 // GenerateRandomBlock generates a random block with signature for testing purposes
@@ -70,18 +161,22 @@ func GenerateRandomBlock(t *testing.T, height uint64, prevBlockHash []byte) *pro
 			TxHash:        []byte("tx"),
 			Version:       1,
 			Height:        height,
-			Timestamp:     1724695016265493000,
+			Timestamp:     1724695016265493000 + int64(height),
 			Nonce:         1,
 			Difficulty:    1,
 		},
 	}
 
+	coinbase, err := types.NewCoinbaseTransaction(publicKey.Bytes(), consensus.DefaultRewardSchedule.Reward(height), height)
+	assert.Nil(t, err)
+	types.AddTransaction(b, coinbase)
+
 	tx := &proto.Transaction{
 		From:  privateKey.PublicKey().Bytes(),
 		To:    toPrivKey.PublicKey().Bytes(),
 		Value: 1,
 		Data:  []byte("data"),
-		Nonce: 1,
+		Nonce: int64(height),
 	}
 	types.SignTransaction(&privateKey, tx)
 	types.AddTransaction(b, tx)
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// BuildBlock creates a new block on top of the last block in the blockchain with the given transactions, sealed by the consensus engine.
+// The first transaction of the block is the coinbase transaction paying the block reward and the fees
+// of the transactions to the producer. Transactions that do not fit within the consensus limits are left out.
+func (bc *Blockchain) BuildBlock(producer *crypto.PrivateKey, txs []*proto.Transaction) (*proto.Block, error) {
+	lastHeader, err := bc.GetHeaderByHeight(bc.Height())
+	if err != nil {
+		return nil, err
+	}
+	prevHash, err := types.HashHeader(lastHeader)
+	if err != nil {
+		return nil, err
+	}
+	height := lastHeader.Height + 1
+
+	header := &proto.Header{
+		PrevBlockHash: prevHash,
+		Version:       1,
+		Height:        height,
+		Timestamp:     bc.nextTimestamp(),
+	}
+	if err := bc.engine.Prepare(bc, lastHeader, header); err != nil {
+		return nil, err
+	}
+	reward := bc.engine.Reward(height)
+	coinbase, err := types.NewCoinbaseTransaction(producer.PublicKey().Bytes(), reward, height)
+	if err != nil {
+		return nil, err
+	}
+	overhead := sealedBlockSize(header, coinbase)
+	if overhead > bc.params.MaxBlockSize {
+		return nil, fmt.Errorf("block header and coinbase size %d exceeds the maximum block size %d", overhead, bc.params.MaxBlockSize)
+	}
+	txs = bc.selectTransactions(txs, overhead)
+
+	fees, err := TotalFees(txs)
+	if err != nil {
+		return nil, err
+	}
+	if reward > math.MaxUint64-fees {
+		return nil, fmt.Errorf("block reward overflow")
+	}
+	if coinbase, err = types.NewCoinbaseTransaction(producer.PublicKey().Bytes(), reward+fees, height); err != nil {
+		return nil, err
+	}
+
+	block := &proto.Block{
+		Header:       header,
+		Transactions: append([]*proto.Transaction{coinbase}, txs...),
+	}
+	txHash, err := types.CalculateTxHash(block.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	block.Header.TxHash = txHash
+
+	if err := bc.engine.Seal(bc, block, producer); err != nil {
+		return nil, err
+	}
+	if err := bc.params.ValidateBlock(block); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// sealedBlockSize returns the serialized size of the sealed block holding the header and the coinbase transaction only.
+// The values only known once the transactions are selected or the block is sealed are measured at their largest
+// encoding: the coinbase value including the fees, the transactions hash, the proof-of-work nonce, the public key,
+// the signature and the hash of the block.
+func sealedBlockSize(header *proto.Header, coinbase *proto.Transaction) int {
+	sealed := pb.Clone(header).(*proto.Header)
+	sealed.TxHash = make([]byte, sha256.Size)
+	sealed.Nonce = math.MaxUint64
+	paid := pb.Clone(coinbase).(*proto.Transaction)
+	paid.Value = math.MaxUint64
+
+	return pb.Size(&proto.Block{
+		Header:       sealed,
+		Transactions: []*proto.Transaction{paid},
+		PublicKey:    make([]byte, crypto.PublicKeySize),
+		Signature:    make([]byte, crypto.SignatureSize),
+		Hash:         make([]byte, sha256.Size),
+	})
+}
+
+// selectTransactions returns the transactions, in order, that fit within the consensus limits
+// in a block whose header and coinbase transaction take size bytes
+func (bc *Blockchain) selectTransactions(txs []*proto.Transaction, size int) []*proto.Transaction {
+	selected := make([]*proto.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		// One transaction slot is taken by the coinbase transaction
+		if len(selected)+1 >= bc.params.MaxBlockTransactions {
+			break
+		}
+		if bc.params.ValidateTransaction(tx) != nil {
+			continue
+		}
+		txSize := types.BlockTransactionSize(tx)
+		if size+txSize > bc.params.MaxBlockSize {
+			continue
+		}
+		size += txSize
+		selected = append(selected, tx)
+	}
+	return selected
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func TestBuildBlock(t *testing.T) {
+	bc := NewBlockchain(NewMemorystore())
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	for i := 1; i <= 3; i++ {
+		tx := generateSignedTransaction(t, privateKey, int64(i), 2)
+		block, err := bc.BuildBlock(privateKey, []*proto.Transaction{tx})
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(i), block.Header.Height)
+		assert.Len(t, block.Transactions, 2)
+
+		coinbase := block.Transactions[0]
+		assert.True(t, types.IsCoinbase(coinbase))
+		assert.Equal(t, privateKey.PublicKey().Bytes(), coinbase.To)
+		assert.Equal(t, consensus.DefaultRewardSchedule.Reward(uint64(i))+2, coinbase.Value)
+
+		assert.NoError(t, bc.AddBlock(block))
+	}
+	assert.Equal(t, 3, bc.Height())
+}
+
+func TestBuildBlockLimits(t *testing.T) {
+	params := types.ConsensusParams{MaxBlockSize: 4096, MaxBlockTransactions: 3, MaxTxDataSize: 16}
+	bc := NewBlockchain(NewMemorystore(), WithConsensusParams(params))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	oversized := generateSignedTransaction(t, privateKey, 1, 1)
+	oversized.Data = make([]byte, 17)
+	txs := []*proto.Transaction{
+		oversized,
+		generateSignedTransaction(t, privateKey, 1, 1),
+		generateSignedTransaction(t, privateKey, 2, 1),
+		generateSignedTransaction(t, privateKey, 3, 1),
+	}
+
+	// The transaction with too much data is left out and only 2 transactions fit next to the coinbase
+	block, err := bc.BuildBlock(privateKey, txs)
+	assert.Nil(t, err)
+	assert.Len(t, block.Transactions, 3)
+	assert.Equal(t, txs[1], block.Transactions[1])
+	assert.Equal(t, txs[2], block.Transactions[2])
+	assert.NoError(t, bc.ValidateBlock(block))
+
+	// Validation rejects blocks over the limits
+	block.Transactions = append(block.Transactions, txs[3])
+	resignBlock(t, privateKey, block)
+	assert.ErrorIs(t, bc.ValidateBlock(block), types.ErrTooManyTransactions)
+}
+
+func TestBuildBlockFillsMaxBlockSize(t *testing.T) {
+	params := types.ConsensusParams{MaxBlockSize: 1024, MaxBlockTransactions: 100, MaxTxDataSize: 64}
+	bc := NewBlockchain(NewMemorystore(), WithConsensusParams(params))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	txs := make([]*proto.Transaction, 0, 10)
+	for i := 1; i <= 10; i++ {
+		txs = append(txs, generateSignedTransaction(t, privateKey, int64(i), 1))
+	}
+
+	// The measured header and coinbase leave room for some transactions, and the sealed block stays within the limit
+	block, err := bc.BuildBlock(privateKey, txs)
+	assert.Nil(t, err)
+	assert.Greater(t, len(block.Transactions), 1)
+	assert.Less(t, len(block.Transactions), 11)
+	assert.LessOrEqual(t, pb.Size(block), params.MaxBlockSize)
+	assert.NoError(t, bc.AddBlock(block))
+
+	// A block that cannot hold its header and coinbase fails before it is sealed
+	params.MaxBlockSize = 128
+	bc = NewBlockchain(NewMemorystore(), WithConsensusParams(params))
+	_, err = bc.BuildBlock(privateKey, txs)
+	assert.ErrorContains(t, err, "exceeds the maximum block size")
+}
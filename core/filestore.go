@@ -0,0 +1,340 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// recordHeaderSize is the size of the length prefix of every block record of a file store
+const recordHeaderSize = 4
+
+// finalizedSuffix is the suffix of the file next to the block file recording the finalized block
+const finalizedSuffix = ".finalized"
+
+// FileStore stores the blocks in an append-only file, each block as a length prefixed record.
+// The hash index is kept in memory and rebuilt when the file is opened.
+type FileStore struct {
+	lock    sync.RWMutex
+	path    string
+	file    *os.File
+	size    int64
+	offsets map[string]int64
+	// writeErr is the error of the last write, nil once a write succeeds again
+	writeErr error
+}
+
+// NewFileStore opens the block file at the path, creating it if needed. A record left incomplete
+// by a crash at the end of the file is truncated.
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStore{
+		path:    path,
+		file:    file,
+		offsets: make(map[string]int64),
+	}
+	if err := s.loadIndex(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadIndex reads every record of the file to index the blocks by hash
+func (s *FileStore) loadIndex() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	s.size = info.Size()
+
+	var offset int64
+	err = s.iterate(s.size, func(b *proto.Block, recordOffset int64, next int64) error {
+		hash, err := types.HashBlock(b)
+		if err != nil {
+			return err
+		}
+		s.offsets[hex.EncodeToString(hash)] = recordOffset
+		offset = next
+		return nil
+	})
+	if err != nil && !errors.Is(err, errIncompleteRecord) {
+		return err
+	}
+
+	if offset < info.Size() {
+		log.Warn().Int64("size", info.Size()).Int64("offset", offset).Msg("truncating incomplete block record")
+		if err := s.file.Truncate(offset); err != nil {
+			return err
+		}
+	}
+	s.size = offset
+	return nil
+}
+
+// Put appends the block to the file, a block already stored is not written again
+func (s *FileStore) Put(b *proto.Block) error {
+	hash, err := types.HashBlock(b)
+	if err != nil {
+		return err
+	}
+	data, err := types.SerializeBlock(b)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := hex.EncodeToString(hash)
+	if _, ok := s.offsets[key]; ok {
+		return nil
+	}
+
+	record := make([]byte, recordHeaderSize+len(data))
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[recordHeaderSize:], data)
+	if _, err := s.file.WriteAt(record, s.size); err != nil {
+		s.writeErr = err
+		return err
+	}
+	s.writeErr = nil
+	s.offsets[key] = s.size
+	s.size += int64(len(record))
+	return nil
+}
+
+// Get reads the block with the hex encoded hash from the file
+func (s *FileStore) Get(hash string) (*proto.Block, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	offset, ok := s.offsets[hash]
+	if !ok {
+		return nil, fmt.Errorf("block with hash (%s) not found", hash)
+	}
+	b, _, err := s.readRecord(offset, s.size)
+	return b, err
+}
+
+// Len returns the number of blocks stored
+func (s *FileStore) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.offsets)
+}
+
+// Size returns the size of the block file in bytes
+func (s *FileStore) Size() int64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.size
+}
+
+// PutFinalized records the height and the header hash of the finalized block, replacing the previous one
+func (s *FileStore) PutFinalized(height int, hash []byte) error {
+	data := make([]byte, 8+len(hash))
+	binary.BigEndian.PutUint64(data, uint64(height))
+	copy(data[8:], hash)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tmp := s.path + finalizedSuffix + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path+finalizedSuffix)
+}
+
+// Finalized returns the height and the header hash of the finalized block recorded, a zero height if there is none
+func (s *FileStore) Finalized() (int, []byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	data, err := os.ReadFile(s.path + finalizedSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(data) < 8 {
+		return 0, nil, fmt.Errorf("invalid finalized block file (%s)", s.path+finalizedSuffix)
+	}
+	return int(binary.BigEndian.Uint64(data)), data[8:], nil
+}
+
+// Iterate calls the function with every block in the order they were stored, stopping at the first error.
+// The blocks stored while iterating are not visited.
+func (s *FileStore) Iterate(fn func(b *proto.Block) error) error {
+	s.lock.RLock()
+	size := s.size
+	s.lock.RUnlock()
+
+	return s.iterate(size, func(b *proto.Block, offset int64, next int64) error {
+		return fn(b)
+	})
+}
+
+// Check returns the error of the last write if it failed, or the error accessing the block file
+func (s *FileStore) Check() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.writeErr != nil {
+		return s.writeErr
+	}
+	_, err := s.file.Stat()
+	return err
+}
+
+// Sync flushes the file to the disk
+func (s *FileStore) Sync() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.file.Sync()
+}
+
+// Close flushes the file to the disk and closes it
+func (s *FileStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// errIncompleteRecord is returned when the file ends in the middle of a record
+var errIncompleteRecord = errors.New("incomplete block record")
+
+// iterate reads the records from the start of the file up to the size
+func (s *FileStore) iterate(size int64, fn func(b *proto.Block, offset int64, next int64) error) error {
+	var offset int64
+	for {
+		b, next, err := s.readRecord(offset, size)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(b, offset, next); err != nil {
+			return err
+		}
+		offset = next
+	}
+}
+
+// readRecord reads the block record at the offset and returns the offset of the next record,
+// the records written past the size are considered incomplete
+func (s *FileStore) readRecord(offset int64, size int64) (*proto.Block, int64, error) {
+	if offset >= size {
+		return nil, 0, io.EOF
+	}
+	header := make([]byte, recordHeaderSize)
+	if offset+recordHeaderSize > size {
+		return nil, 0, errIncompleteRecord
+	}
+	if _, err := s.file.ReadAt(header, offset); err != nil {
+		return nil, 0, err
+	}
+	next := offset + recordHeaderSize + int64(binary.BigEndian.Uint32(header))
+	if next > size {
+		return nil, 0, errIncompleteRecord
+	}
+
+	data := make([]byte, next-offset-recordHeaderSize)
+	if _, err := s.file.ReadAt(data, offset+recordHeaderSize); err != nil {
+		return nil, 0, err
+	}
+	b := &proto.Block{}
+	if err := pb.Unmarshal(data, b); err != nil {
+		return nil, 0, fmt.Errorf("corrupted block record at offset %d", offset)
+	}
+	return b, next, nil
+}
+
+// errStopIteration stops an iteration over the stored blocks early
+var errStopIteration = errors.New("stop iteration")
+
+// LoadBlockchain creates a blockchain on the file store, adding back the blocks stored by a previous run.
+// The blocks are validated again, which rebuilds the account state, then the finalized height is restored.
+// The blockchain options must create the same genesis block as the stored one.
+func LoadBlockchain(store *FileStore, opts ...Option) (*Blockchain, error) {
+	var stored *proto.Block
+	err := store.Iterate(func(b *proto.Block) error {
+		stored = b
+		return errStopIteration
+	})
+	if err != nil && !errors.Is(err, errStopIteration) {
+		return nil, err
+	}
+
+	// Check the stored genesis block before the blockchain stores its own
+	if stored != nil {
+		genesis, err := NewBlockchain(NewMemorystore(), opts...).GetHeaderByHeight(0)
+		if err != nil {
+			return nil, err
+		}
+		genesisHash, err := types.HashHeader(genesis)
+		if err != nil {
+			return nil, err
+		}
+		storedHash, err := types.HashBlock(stored)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(genesisHash, storedHash) {
+			return nil, fmt.Errorf("stored genesis block (%s) does not match the configured genesis block (%s)",
+				hex.EncodeToString(storedHash), hex.EncodeToString(genesisHash))
+		}
+	}
+
+	bc := NewBlockchain(store, opts...)
+	err = store.Iterate(func(b *proto.Block) error {
+		if b.Header.GetHeight() == 0 {
+			return nil
+		}
+		if err := bc.AddBlock(b); err != nil {
+			return fmt.Errorf("failed to load block at height %d: %v", b.Header.GetHeight(), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The finalized block must be a block of the loaded chain
+	height, hash, err := store.Finalized()
+	if err != nil {
+		return nil, err
+	}
+	if height > 0 {
+		if err := bc.SetFinalized(height, hash); err != nil {
+			return nil, fmt.Errorf("failed to restore the finalized block at height %d: %w", height, err)
+		}
+	}
+	return bc, nil
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/consensus/pow"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.dat")
+	store, err := NewFileStore(path)
+	assert.Nil(t, err)
+
+	block := GenerateRandomBlock(t, 1, make([]byte, 32))
+	hash, err := types.HashBlock(block)
+	assert.Nil(t, err)
+	assert.NoError(t, store.Put(block))
+	// Storing the same block again does not duplicate it
+	assert.NoError(t, store.Put(block))
+	assert.Equal(t, 1, store.Len())
+
+	stored, err := store.Get(hex.EncodeToString(hash))
+	assert.Nil(t, err)
+	assert.Equal(t, block.Header.Height, stored.Header.Height)
+	_, err = store.Get("unknown")
+	assert.Error(t, err)
+	assert.NoError(t, store.Check())
+	assert.NoError(t, store.Close())
+	assert.Error(t, store.Check())
+
+	// The blocks are found again after reopening the file
+	store, err = NewFileStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	assert.Equal(t, 1, store.Len())
+	_, err = store.Get(hex.EncodeToString(hash))
+	assert.Nil(t, err)
+}
+
+func TestFileStoreTruncatesIncompleteRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.dat")
+	store, err := NewFileStore(path)
+	assert.Nil(t, err)
+	assert.NoError(t, store.Put(GenerateRandomBlock(t, 1, make([]byte, 32))))
+	assert.NoError(t, store.Close())
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	// Simulate a crash in the middle of writing a second block
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.Nil(t, err)
+	_, err = file.Write([]byte{0, 0, 1, 0, 1, 2, 3})
+	assert.Nil(t, err)
+	assert.NoError(t, file.Close())
+
+	store, err = NewFileStore(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, store.Len())
+	assert.NoError(t, store.Put(GenerateRandomBlock(t, 2, make([]byte, 32))))
+	assert.NoError(t, store.Close())
+
+	truncated, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Greater(t, truncated.Size(), info.Size())
+	store, err = NewFileStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	assert.Equal(t, 2, store.Len())
+}
+
+func TestLoadBlockchain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocks.dat")
+	store, err := NewFileStore(path)
+	assert.Nil(t, err)
+	bc, err := LoadBlockchain(store)
+	assert.Nil(t, err)
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		block, err := bc.BuildBlock(producer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, bc.AddBlock(block))
+	}
+	balance := bc.State().Balance(producer.PublicKey().Address())
+	finalized, err := types.HashHeader(bc.headers.Get(2))
+	assert.Nil(t, err)
+	assert.NoError(t, bc.SetFinalized(2, finalized))
+	assert.NoError(t, store.Close())
+
+	// The blocks, the account state and the finalized height are restored from the files
+	store, err = NewFileStore(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	loaded, err := LoadBlockchain(store)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, loaded.Height())
+	assert.Equal(t, 4, store.Len())
+	assert.Equal(t, balance, loaded.State().Balance(producer.PublicKey().Address()))
+	assert.Equal(t, 2, loaded.FinalizedHeight())
+
+	// A finalized block which is not in the stored chain is refused
+	assert.NoError(t, store.PutFinalized(2, []byte("other hash")))
+	_, err = LoadBlockchain(store)
+	assert.ErrorIs(t, err, ErrFinalizedBlock)
+	assert.NoError(t, store.PutFinalized(2, finalized))
+
+	// A blockchain with another genesis block is refused
+	_, err = LoadBlockchain(store, WithEngine(pow.New(pow.DefaultConfig)))
+	assert.Error(t, err)
+	assert.Equal(t, 4, store.Len())
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// ErrFinalizedBlock is returned when a block would replace a block at or below the finalized height
+var ErrFinalizedBlock = errors.New("block conflicts with a finalized block")
+
+// finalizedStore is a block store recording the finalized block, so it is restored when the blockchain is loaded
+type finalizedStore interface {
+	PutFinalized(height int, hash []byte) error
+}
+
+// FinalizedHeight returns the height of the last finalized block. The genesis block is always final.
+func (bc *Blockchain) FinalizedHeight() int {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+
+	return bc.finalized
+}
+
+// SetFinalized marks the block at the given height with the given header hash as finalized, recording it in the
+// block store if it can. The finalized height never goes backwards and the blocks up to it can not be replaced anymore.
+func (bc *Blockchain) SetFinalized(height int, hash []byte) error {
+	header, err := bc.GetHeaderByHeight(height)
+	if err != nil {
+		return err
+	}
+	headerHash, err := types.HashHeader(header)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(headerHash, hash) {
+		return fmt.Errorf("%w: block hash at height (%d) does not match the finalized hash", ErrFinalizedBlock, height)
+	}
+
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+
+	if height < bc.finalized {
+		return fmt.Errorf("height (%d) is below the finalized height (%d)", height, bc.finalized)
+	}
+	if store, ok := bc.store.(finalizedStore); ok && height > bc.finalized {
+		if err := store.PutFinalized(height, hash); err != nil {
+			return fmt.Errorf("failed to store the finalized block: %w", err)
+		}
+	}
+	bc.finalized = height
+
+	log.Info().Fields(map[string]interface{}{
+		"height": height,
+	}).Msg("block finalized")
+
+	return nil
+}
+
+// validateNotFinalized refuses a block competing with a block at or below the finalized height. As the finalized
+// blocks can never be replaced, a competing branch forking below the finalized height is refused from its first
+// block, while the finalized blocks themselves are only known blocks.
+func (bc *Blockchain) validateNotFinalized(b *proto.Block) error {
+	height := b.Header.GetHeight()
+	finalized := bc.FinalizedHeight()
+	if height > uint64(finalized) {
+		return nil
+	}
+
+	header, err := bc.GetHeaderByHeight(int(height))
+	if err != nil {
+		return err
+	}
+	hash, err := types.HashHeader(header)
+	if err != nil {
+		return err
+	}
+	blockHash, err := types.HashBlock(b)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(hash, blockHash) {
+		return fmt.Errorf("%w: block at height (%d) competes with a block at or below the finalized height (%d)", ErrFinalizedBlock, height, finalized)
+	}
+	return nil
+}
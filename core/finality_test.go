@@ -0,0 +1,109 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetFinalized(t *testing.T) {
+	bc := NewBlockchain(NewMemorystore())
+	assert.Equal(t, 0, bc.FinalizedHeight())
+
+	for i := 0; i < 5; i++ {
+		prevHash, err := types.HashHeader(bc.headers.Last())
+		assert.NoError(t, err)
+		assert.NoError(t, bc.AddBlock(GenerateRandomBlock(t, uint64(i+1), prevHash)))
+	}
+
+	// The hash must match the block at the height
+	err := bc.SetFinalized(3, []byte("hash"))
+	assert.True(t, errors.Is(err, ErrFinalizedBlock))
+	// The height must be in the blockchain
+	assert.Error(t, bc.SetFinalized(6, []byte("hash")))
+
+	hash, err := types.HashHeader(bc.headers.Get(3))
+	assert.NoError(t, err)
+	assert.NoError(t, bc.SetFinalized(3, hash))
+	assert.Equal(t, 3, bc.FinalizedHeight())
+
+	// The finalized height never goes backwards
+	hash, err = types.HashHeader(bc.headers.Get(2))
+	assert.NoError(t, err)
+	assert.Error(t, bc.SetFinalized(2, hash))
+	assert.Equal(t, 3, bc.FinalizedHeight())
+}
+
+func TestAddBlockRefusesFinalizedHeight(t *testing.T) {
+	bc := NewBlockchain(NewMemorystore())
+
+	// The genesis block is final
+	err := bc.AddBlock(GenerateRandomBlock(t, 0, make([]byte, 32)))
+	assert.True(t, errors.Is(err, ErrFinalizedBlock))
+
+	for i := 0; i < 3; i++ {
+		prevHash, err := types.HashHeader(bc.headers.Last())
+		assert.NoError(t, err)
+		assert.NoError(t, bc.AddBlock(GenerateRandomBlock(t, uint64(i+1), prevHash)))
+	}
+	hash, err := types.HashHeader(bc.headers.Get(2))
+	assert.NoError(t, err)
+	assert.NoError(t, bc.SetFinalized(2, hash))
+
+	// A competing block at a finalized height is refused
+	prevHash, err := types.HashHeader(bc.headers.Get(1))
+	assert.NoError(t, err)
+	err = bc.AddBlock(GenerateRandomBlock(t, 2, prevHash))
+	assert.True(t, errors.Is(err, ErrFinalizedBlock))
+}
+
+func TestAddBlockRefusesCompetingBranch(t *testing.T) {
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	bc := NewBlockchain(NewMemorystore())
+	for i := 0; i < 5; i++ {
+		b, err := bc.BuildBlock(producer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, bc.AddBlock(b))
+	}
+	hash, err := types.HashHeader(bc.headers.Get(3))
+	assert.NoError(t, err)
+	assert.NoError(t, bc.SetFinalized(3, hash))
+
+	// A longer branch forking from the genesis block, below the finalized height
+	other, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	competing := NewBlockchain(NewMemorystore())
+	branch := make([]*proto.Block, 0, 7)
+	for i := 0; i < 7; i++ {
+		b, err := competing.BuildBlock(other, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, competing.AddBlock(b))
+		branch = append(branch, b)
+	}
+
+	// Its blocks up to the finalized height are refused as conflicting with the finalized blocks
+	for _, b := range branch[:3] {
+		err := bc.AddBlock(b)
+		assert.ErrorIs(t, err, ErrFinalizedBlock)
+		assert.Equal(t, RejectFinalized, RejectReason(err))
+	}
+	for _, b := range branch[3:] {
+		assert.Error(t, bc.AddBlock(b))
+	}
+	assert.Equal(t, 5, bc.Height())
+	finalized, err := types.HashHeader(bc.headers.Get(3))
+	assert.NoError(t, err)
+	assert.Equal(t, hash, finalized)
+
+	// A finalized block received again is only a known block
+	known, err := bc.GetBlockByHeight(2)
+	assert.Nil(t, err)
+	err = bc.AddBlock(known)
+	assert.NotErrorIs(t, err, ErrFinalizedBlock)
+	assert.Equal(t, RejectKnown, RejectReason(err))
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// GenesisAccount is an account funded with a balance when the genesis block is added
+type GenesisAccount struct {
+	Address crypto.Address
+	Balance uint64
+}
+
+// WithGenesisAlloc sets the accounts funded by the genesis block
+func WithGenesisAlloc(accounts []GenesisAccount) Option {
+	return func(bc *Blockchain) {
+		bc.genesisAlloc = accounts
+	}
+}
+
+// genesisTransactions returns the coinbase transactions crediting the genesis accounts. The allocation is committed
+// in the transactions hash of the genesis header, so chains with different allocations have different genesis hashes.
+func (bc *Blockchain) genesisTransactions() ([]*proto.Transaction, error) {
+	txs := make([]*proto.Transaction, 0, len(bc.genesisAlloc))
+	for _, account := range bc.genesisAlloc {
+		tx, err := types.NewCoinbaseTransaction(account.Address.Bytes(), account.Balance, 0)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
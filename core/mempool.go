@@ -1,25 +1,77 @@
 package core
 
 import (
+	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 
+	"github.com/joaoh82/marvinblockchain/consensus"
 	"github.com/joaoh82/marvinblockchain/proto"
 	"github.com/joaoh82/marvinblockchain/types"
 )
 
-// Mempool is a pool of transactions that are not yet included in a block
+const (
+	// DefaultMempoolMaxTransactions is the default maximum number of transactions in the mempool
+	DefaultMempoolMaxTransactions = 50_000
+	// DefaultMempoolMaxBytes is the default maximum size of the transactions of the mempool, as serialized in a block
+	DefaultMempoolMaxBytes = 32 * 1024 * 1024
+	// maxNonceGap is how far ahead of the next nonce of its sender a transaction can wait in the mempool
+	maxNonceGap = 64
+)
+
+var (
+	// ErrInvalidTransaction is wrapped by the errors of transactions that cannot be applied to the state,
+	// as opposed to transactions refused because the mempool already has them or is full
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	// ErrMempoolFull is returned when the mempool is full of transactions paying higher fees
+	ErrMempoolFull = errors.New("mempool is full")
+)
+
+// Mempool is a pool of transactions that are not yet included in a block. When it has the account state,
+// the transactions are checked against the nonce and the balance of their sender before they are accepted.
+// Once full, the transactions paying the lowest fees are evicted to make room for the ones paying more.
 type Mempool struct {
-	lock         sync.RWMutex
-	transactions map[string]*proto.Transaction
+	lock            sync.RWMutex
+	transactions    map[string]*proto.Transaction
+	size            int
+	state           consensus.StateReader
+	maxTransactions int
+	maxBytes        int
+	handlers        []func(tx *proto.Transaction)
+}
+
+// MempoolOption configures a mempool
+type MempoolOption func(m *Mempool)
+
+// WithMempoolState checks the transactions added to the mempool against the account state
+func WithMempoolState(state consensus.StateReader) MempoolOption {
+	return func(m *Mempool) {
+		m.state = state
+	}
+}
+
+// WithMempoolLimits sets the maximum number of transactions and the maximum size of the mempool
+func WithMempoolLimits(maxTransactions int, maxBytes int) MempoolOption {
+	return func(m *Mempool) {
+		m.maxTransactions = maxTransactions
+		m.maxBytes = maxBytes
+	}
 }
 
 // NewMempool creates a new mempool
-func NewMempool() *Mempool {
-	return &Mempool{
-		transactions: make(map[string]*proto.Transaction),
+func NewMempool(opts ...MempoolOption) *Mempool {
+	m := &Mempool{
+		transactions:    make(map[string]*proto.Transaction),
+		maxTransactions: DefaultMempoolMaxTransactions,
+		maxBytes:        DefaultMempoolMaxBytes,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 // Flush removes all transactions from the mempool
@@ -28,6 +80,7 @@ func (m *Mempool) Flush() {
 	defer m.lock.Unlock()
 
 	m.transactions = make(map[string]*proto.Transaction)
+	m.size = 0
 }
 
 // Len returns the number of transactions in the mempool
@@ -38,6 +91,14 @@ func (m *Mempool) Len() int {
 	return len(m.transactions)
 }
 
+// Bytes returns the number of bytes the transactions of the mempool add to a serialized block
+func (m *Mempool) Bytes() int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.size
+}
+
 // Has returns true if the mempool has the transaction
 func (m *Mempool) Has(tx *proto.Transaction) bool {
 	m.lock.RLock()
@@ -53,21 +114,190 @@ func (m *Mempool) Has(tx *proto.Transaction) bool {
 	return ok
 }
 
-// Add adds a transaction to the mempool
+// Get returns the transaction with the given hash, or nil if it is not in the mempool
+func (m *Mempool) Get(hash []byte) *proto.Transaction {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return m.transactions[hex.EncodeToString(hash)]
+}
+
+// Add adds a transaction to the mempool, evicting the transactions paying the lowest fees if it is full
 func (m *Mempool) Add(tx *proto.Transaction) error {
-	if m.Has(tx) {
-		return fmt.Errorf("transaction already exists in the mempool")
+	// Coinbase transactions are created by the block producer and are never relayed
+	if types.IsCoinbase(tx) {
+		return fmt.Errorf("%w: coinbase transactions cannot be added to the mempool", ErrInvalidTransaction)
 	}
 
-	m.lock.Lock()
-	defer m.lock.Unlock()
-
 	hash, err := types.HashTransaction(tx)
 	if err != nil {
-		return nil
+		return err
 	}
 	hashStr := hex.EncodeToString(hash)
+	size := types.BlockTransactionSize(tx)
 
+	m.lock.Lock()
+	if _, ok := m.transactions[hashStr]; ok {
+		m.lock.Unlock()
+		return fmt.Errorf("transaction already exists in the mempool")
+	}
+	if err := m.checkState(tx); err != nil {
+		m.lock.Unlock()
+		return err
+	}
+	if size > m.maxBytes {
+		m.lock.Unlock()
+		return fmt.Errorf("%w: transaction size %d exceeds the mempool size of %d", ErrInvalidTransaction, size, m.maxBytes)
+	}
+	if !m.makeRoom(tx, size) {
+		m.lock.Unlock()
+		return ErrMempoolFull
+	}
 	m.transactions[hashStr] = tx
+	m.size += size
+	handlers := m.handlers
+	m.lock.Unlock()
+
+	for _, handler := range handlers {
+		handler(tx)
+	}
 	return nil
 }
+
+// checkState checks that the nonce of the transaction is not used yet nor too far ahead, and that the sender
+// can pay for the transaction on top of its transactions already in the mempool, if the mempool has the account state.
+// The lock must be held.
+func (m *Mempool) checkState(tx *proto.Transaction) error {
+	if m.state == nil {
+		return nil
+	}
+	from, err := types.AccountAddress(tx.From)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTransaction, err)
+	}
+
+	nonce := m.state.Nonce(from)
+	if tx.Nonce <= nonce {
+		return fmt.Errorf("nonce %d already used by account (%s), next nonce is %d", tx.Nonce, from, nonce+1)
+	}
+	if tx.Nonce > nonce+maxNonceGap {
+		return fmt.Errorf("%w: nonce %d too far ahead of the next nonce %d of account (%s)", ErrInvalidTransaction, tx.Nonce, nonce+1, from)
+	}
+	cost, ok := transactionCost(tx)
+	if !ok {
+		return fmt.Errorf("%w: transaction cost overflows", ErrInvalidTransaction)
+	}
+	for _, pending := range m.transactions {
+		if !bytes.Equal(pending.From, tx.From) {
+			continue
+		}
+		pendingCost, ok := transactionCost(pending)
+		if !ok || cost+pendingCost < cost {
+			return fmt.Errorf("%w: pending cost of account (%s) overflows", ErrInvalidTransaction, from)
+		}
+		cost += pendingCost
+	}
+	if balance := m.state.Balance(from); balance < cost {
+		return fmt.Errorf("%w: account (%s) balance %d cannot pay %d with its pending transactions", ErrInvalidTransaction, from, balance, cost)
+	}
+	return nil
+}
+
+// transactionCost returns the value and the fee the sender of the transaction pays, false if it overflows
+func transactionCost(tx *proto.Transaction) (uint64, bool) {
+	cost := tx.Value + tx.Fee
+	return cost, cost >= tx.Value
+}
+
+// makeRoom evicts the transactions paying the lowest fees until the transaction fits in the mempool.
+// Nothing is evicted and false is returned if the transaction does not pay more than the ones to evict.
+// The lock must be held.
+func (m *Mempool) makeRoom(tx *proto.Transaction, size int) bool {
+	count, bytes := len(m.transactions)+1, m.size+size
+	if count <= m.maxTransactions && bytes <= m.maxBytes {
+		return true
+	}
+
+	// The transactions paying the lowest fees first, the ones with the highest nonce first among equal fees
+	// so the transactions of a sender are evicted from the last one
+	hashes := make([]string, 0, len(m.transactions))
+	for hash := range m.transactions {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		a, b := m.transactions[hashes[i]], m.transactions[hashes[j]]
+		if a.Fee != b.Fee {
+			return a.Fee < b.Fee
+		}
+		if a.Nonce != b.Nonce {
+			return a.Nonce > b.Nonce
+		}
+		return hashes[i] < hashes[j]
+	})
+
+	evicted := 0
+	for ; evicted < len(hashes) && (count > m.maxTransactions || bytes > m.maxBytes); evicted++ {
+		victim := m.transactions[hashes[evicted]]
+		if victim.Fee >= tx.Fee {
+			return false
+		}
+		count--
+		bytes -= types.BlockTransactionSize(victim)
+	}
+	for _, hash := range hashes[:evicted] {
+		m.size -= types.BlockTransactionSize(m.transactions[hash])
+		delete(m.transactions, hash)
+	}
+	return true
+}
+
+// OnAdd registers a handler called with every transaction added to the mempool
+func (m *Mempool) OnAdd(handler func(tx *proto.Transaction)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.handlers = append(m.handlers, handler)
+}
+
+// Pending returns the transactions in the mempool ordered by nonce,
+// so the transactions of each sender can be included in a block in order
+func (m *Mempool) Pending() []*proto.Transaction {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	hashes := make([]string, 0, len(m.transactions))
+	for hash := range m.transactions {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		a, b := m.transactions[hashes[i]], m.transactions[hashes[j]]
+		if a.Nonce != b.Nonce {
+			return a.Nonce < b.Nonce
+		}
+		return hashes[i] < hashes[j]
+	})
+
+	txs := make([]*proto.Transaction, len(hashes))
+	for i, hash := range hashes {
+		txs[i] = m.transactions[hash]
+	}
+	return txs
+}
+
+// Remove removes the transactions from the mempool
+func (m *Mempool) Remove(txs []*proto.Transaction) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, tx := range txs {
+		hash, err := types.HashTransaction(tx)
+		if err != nil {
+			continue
+		}
+		key := hex.EncodeToString(hash)
+		if removed, ok := m.transactions[key]; ok {
+			m.size -= types.BlockTransactionSize(removed)
+			delete(m.transactions, key)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/joaoh82/marvinblockchain/crypto"
 	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,3 +38,114 @@ func TestAddTransaction(t *testing.T) {
 	mempool.Flush()
 	assert.Equal(t, 0, mempool.Len())
 }
+
+func TestAddCoinbaseTransaction(t *testing.T) {
+	mempool := NewMempool()
+
+	privKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	coinbase, err := types.NewCoinbaseTransaction(privKey.PublicKey().Bytes(), 50, 1)
+	assert.Nil(t, err)
+
+	assert.Error(t, mempool.Add(coinbase))
+	assert.Equal(t, 0, mempool.Len())
+}
+
+func TestMempoolPendingAndRemove(t *testing.T) {
+	mempool := NewMempool()
+	var added []*proto.Transaction
+	mempool.OnAdd(func(tx *proto.Transaction) {
+		added = append(added, tx)
+	})
+
+	privKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	txs := []*proto.Transaction{
+		generateSignedTransaction(t, privKey, 3, 0),
+		generateSignedTransaction(t, privKey, 1, 0),
+		generateSignedTransaction(t, privKey, 2, 0),
+	}
+	for _, tx := range txs {
+		assert.NoError(t, mempool.Add(tx))
+	}
+	assert.Len(t, added, 3)
+	assert.Error(t, mempool.Add(txs[0]))
+
+	// Pending transactions are ordered by nonce
+	pending := mempool.Pending()
+	assert.Len(t, pending, 3)
+	for i, tx := range pending {
+		assert.Equal(t, int64(i+1), tx.Nonce)
+	}
+
+	mempool.Remove(pending[:2])
+	assert.Equal(t, 1, mempool.Len())
+	assert.True(t, mempool.Has(txs[0]))
+}
+
+func TestMempoolChecksState(t *testing.T) {
+	privKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	address := privKey.PublicKey().Address()
+	bc := NewBlockchain(NewMemorystore(), WithGenesisAlloc([]GenesisAccount{{Address: address, Balance: 100}}))
+	mempool := NewMempool(WithMempoolState(bc.State()))
+	to := privKey.PublicKey().Bytes()
+
+	assert.NoError(t, mempool.Add(signedTransfer(t, privKey, to, 1, 80, 10)))
+	assert.NoError(t, mempool.Add(signedTransfer(t, privKey, to, maxNonceGap, 1, 0)))
+
+	// A transaction the sender cannot pay for, or too far ahead of its next nonce, is invalid
+	assert.ErrorIs(t, mempool.Add(signedTransfer(t, privKey, to, 2, 91, 10)), ErrInvalidTransaction)
+	assert.ErrorIs(t, mempool.Add(signedTransfer(t, privKey, to, maxNonceGap+1, 1, 0)), ErrInvalidTransaction)
+	// The sender cannot pay for it on top of its pending transactions, even though it can on its own
+	assert.ErrorIs(t, mempool.Add(signedTransfer(t, privKey, to, 2, 10, 0)), ErrInvalidTransaction)
+	assert.NoError(t, mempool.Add(signedTransfer(t, privKey, to, 2, 9, 0)))
+	assert.Equal(t, 3, mempool.Len())
+
+	// A used nonce is refused without being invalid, the transaction may have raced with the block
+	block, err := bc.BuildBlock(privKey, mempool.Pending()[:1])
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	err = mempool.Add(signedTransfer(t, privKey, to, 1, 1, 0))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidTransaction)
+	assert.Equal(t, 3, mempool.Len())
+}
+
+func TestMempoolEvictsLowestFees(t *testing.T) {
+	privKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	mempool := NewMempool(WithMempoolLimits(3, DefaultMempoolMaxBytes))
+
+	txs := []*proto.Transaction{
+		generateSignedTransaction(t, privKey, 1, 5),
+		generateSignedTransaction(t, privKey, 2, 1),
+		generateSignedTransaction(t, privKey, 3, 1),
+	}
+	for _, tx := range txs {
+		assert.NoError(t, mempool.Add(tx))
+	}
+
+	// A transaction paying no more than the lowest fee is refused once the mempool is full
+	assert.ErrorIs(t, mempool.Add(generateSignedTransaction(t, privKey, 4, 1)), ErrMempoolFull)
+
+	// A transaction paying more evicts the lowest fee, the last nonce first
+	assert.NoError(t, mempool.Add(generateSignedTransaction(t, privKey, 4, 2)))
+	assert.Equal(t, 3, mempool.Len())
+	assert.False(t, mempool.Has(txs[2]))
+	assert.True(t, mempool.Has(txs[1]))
+
+	// The size limit evicts as many transactions as needed
+	size := mempool.Bytes()
+	mempool = NewMempool(WithMempoolLimits(10, size))
+	for _, tx := range txs {
+		assert.NoError(t, mempool.Add(tx))
+	}
+	assert.NoError(t, mempool.Add(generateSignedTransaction(t, privKey, 4, 9)))
+	assert.LessOrEqual(t, mempool.Bytes(), size)
+	assert.True(t, mempool.Has(txs[0]))
+	assert.False(t, mempool.Has(txs[2]))
+
+	mempool.Flush()
+	assert.Equal(t, 0, mempool.Bytes())
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// processBlock applies the transactions of the block on top of the current account state.
+// The returned overlay holds the state changes, which are only written when the block is added to the blockchain.
+func (bc *Blockchain) processBlock(b *proto.Block) (*stateOverlay, error) {
+	state := newStateOverlay(bc.state)
+
+	for i, tx := range b.Transactions {
+		if err := bc.applyTransaction(state, b.Header, tx); err != nil {
+			return nil, fmt.Errorf("failed to apply transaction %d: %v", i, err)
+		}
+	}
+	if err := bc.engine.Finalize(bc, state, b); err != nil {
+		return nil, fmt.Errorf("failed to finalize block: %v", err)
+	}
+
+	return state, nil
+}
+
+// applyTransaction applies the state changes of a transaction.
+// Every transaction but the coinbase must have the next nonce of the sender, which pays the fee.
+func (bc *Blockchain) applyTransaction(state *stateOverlay, header *proto.Header, tx *proto.Transaction) error {
+	if types.IsCoinbase(tx) {
+		to, err := types.AccountAddress(tx.To)
+		if err != nil {
+			return err
+		}
+		return state.AddBalance(to, tx.Value)
+	}
+
+	from, err := types.AccountAddress(tx.From)
+	if err != nil {
+		return err
+	}
+	if expected := state.Nonce(from) + 1; tx.Nonce != expected {
+		return fmt.Errorf("invalid nonce %d for account (%s), expected %d", tx.Nonce, from, expected)
+	}
+	state.setNonce(from, tx.Nonce)
+	if err := state.SubBalance(from, tx.Fee); err != nil {
+		return err
+	}
+
+	if tx.GetType() != proto.TxType_TX_TYPE_TRANSFER {
+		return bc.engine.ApplyTransaction(bc, state, header, tx)
+	}
+
+	to, err := types.AccountAddress(tx.To)
+	if err != nil {
+		return err
+	}
+	if err := state.SubBalance(from, tx.Value); err != nil {
+		return err
+	}
+	return state.AddBalance(to, tx.Value)
+}
@@ -0,0 +1,48 @@
+package core
+
+import "errors"
+
+// Reasons a block is rejected by the validation
+const (
+	RejectFinalized    = "finalized"
+	RejectKnown        = "known"
+	RejectHeight       = "height"
+	RejectLimits       = "limits"
+	RejectTimestamp    = "timestamp"
+	RejectSignature    = "signature"
+	RejectTxHash       = "tx_hash"
+	RejectCoinbase     = "coinbase"
+	RejectPrevHash     = "prev_hash"
+	RejectConsensus    = "consensus"
+	RejectTransactions = "transactions"
+	// RejectOther is the reason of the errors not caused by a validation rule, like a storage error
+	RejectOther = "other"
+)
+
+// rejectionError is a validation error with the reason the block is rejected
+type rejectionError struct {
+	reason string
+	err    error
+}
+
+func (e *rejectionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rejectionError) Unwrap() error {
+	return e.err
+}
+
+// reject returns the error with the reason the block is rejected
+func reject(reason string, err error) error {
+	return &rejectionError{reason: reason, err: err}
+}
+
+// RejectReason returns the reason of a block validation error
+func RejectReason(err error) string {
+	var rejection *rejectionError
+	if errors.As(err, &rejection) {
+		return rejection.reason
+	}
+	return RejectOther
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// TotalFees returns the sum of the fees of the given transactions
+func TotalFees(txs []*proto.Transaction) (uint64, error) {
+	var total uint64
+	for _, tx := range txs {
+		if total > math.MaxUint64-tx.GetFee() {
+			return 0, fmt.Errorf("transaction fees overflow")
+		}
+		total += tx.GetFee()
+	}
+	return total, nil
+}
+
+// validateCoinbase checks that the first transaction of the block is its only coinbase transaction
+// and that it does not claim more than the block reward plus the fees of the block transactions
+func (bc *Blockchain) validateCoinbase(b *proto.Block) error {
+	if len(b.Transactions) == 0 || !types.IsCoinbase(b.Transactions[0]) {
+		return fmt.Errorf("first transaction of the block is not a coinbase transaction")
+	}
+	coinbase := b.Transactions[0]
+	if len(coinbase.From) != 0 || len(coinbase.Signature) != 0 {
+		return fmt.Errorf("coinbase transaction must not have a sender or signature")
+	}
+
+	for i, tx := range b.Transactions[1:] {
+		if types.IsCoinbase(tx) {
+			return fmt.Errorf("block has more than one coinbase transaction (index %d)", i+1)
+		}
+	}
+
+	fees, err := TotalFees(b.Transactions[1:])
+	if err != nil {
+		return err
+	}
+	reward := bc.engine.Reward(b.Header.GetHeight())
+	if reward > math.MaxUint64-fees {
+		return fmt.Errorf("block reward overflow")
+	}
+	if coinbase.Value > reward+fees {
+		return fmt.Errorf("coinbase value %d exceeds allowed block reward %d plus fees %d", coinbase.Value, reward, fees)
+	}
+
+	return nil
+}
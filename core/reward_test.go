@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/consensus/instant"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCoinbase(t *testing.T) {
+	bc := NewBlockchain(NewMemorystore(), WithEngine(instant.New(consensus.RewardSchedule{InitialReward: 10})))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	// The reward plus the fees of the transactions can be claimed
+	tx := generateSignedTransaction(t, privateKey, 1, 5)
+	block, err := bc.BuildBlock(privateKey, []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(15), block.Transactions[0].Value)
+	assert.NoError(t, bc.ValidateBlock(block))
+
+	// Claiming more than allowed is rejected
+	block.Transactions[0].Value = 16
+	resignBlock(t, privateKey, block)
+	assert.Error(t, bc.ValidateBlock(block))
+
+	// A block without a coinbase transaction is rejected
+	block.Transactions = block.Transactions[1:]
+	resignBlock(t, privateKey, block)
+	assert.Error(t, bc.ValidateBlock(block))
+
+	// A block with more than one coinbase transaction is rejected
+	block, err = bc.BuildBlock(privateKey, nil)
+	assert.Nil(t, err)
+	extra, err := types.NewCoinbaseTransaction(privateKey.PublicKey().Bytes(), 0, 1)
+	assert.Nil(t, err)
+	block.Transactions = append(block.Transactions, extra)
+	resignBlock(t, privateKey, block)
+	assert.Error(t, bc.ValidateBlock(block))
+}
+
+func TestTotalFeesOverflow(t *testing.T) {
+	txs := []*proto.Transaction{{Fee: ^uint64(0)}, {Fee: 1}}
+	_, err := TotalFees(txs)
+	assert.Error(t, err)
+}
+
+// generateSignedTransaction generates a signed transaction with the given nonce and fee for testing purposes
+func generateSignedTransaction(t *testing.T, privateKey *crypto.PrivateKey, nonce int64, fee uint64) *proto.Transaction {
+	toPrivKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	tx := &proto.Transaction{
+		From:  privateKey.PublicKey().Bytes(),
+		To:    toPrivKey.PublicKey().Bytes(),
+		Value: 1,
+		Fee:   fee,
+		Nonce: nonce,
+	}
+	assert.Nil(t, types.SignTransaction(privateKey, tx))
+	return tx
+}
+
+// resignBlock recalculates the transactions hash of a modified block and signs it again
+func resignBlock(t *testing.T, privateKey *crypto.PrivateKey, b *proto.Block) {
+	txHash, err := types.CalculateTxHash(b.Transactions)
+	assert.Nil(t, err)
+	b.Header.TxHash = txHash
+	_, err = types.SignBlock(privateKey, b)
+	assert.Nil(t, err)
+}
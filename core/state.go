@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+)
+
+// Account holds the balance of an account and the nonce of the last transaction it sent
+type Account struct {
+	Balance uint64
+	Nonce   int64
+}
+
+// AccountState is the state of the accounts, and the data of the consensus engine, after the last block of the blockchain
+type AccountState struct {
+	lock     sync.RWMutex
+	accounts map[string]Account
+	data     map[string][]byte
+}
+
+// NewAccountState creates a new empty account state
+func NewAccountState() *AccountState {
+	return &AccountState{
+		accounts: make(map[string]Account),
+		data:     make(map[string][]byte),
+	}
+}
+
+// Account returns the account with the given address
+func (s *AccountState) Account(address crypto.Address) Account {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.accounts[string(address.Bytes())]
+}
+
+// Balance returns the balance of the account
+func (s *AccountState) Balance(address crypto.Address) uint64 {
+	return s.Account(address).Balance
+}
+
+// Nonce returns the nonce of the last transaction sent by the account
+func (s *AccountState) Nonce(address crypto.Address) int64 {
+	return s.Account(address).Nonce
+}
+
+// Get returns the consensus engine data stored under the key
+func (s *AccountState) Get(key string) []byte {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.data[key]
+}
+
+// stateOverlay records the state changes of a block on top of the account state.
+// The changes are only written to the account state when the overlay is committed.
+type stateOverlay struct {
+	base     *AccountState
+	accounts map[string]Account
+	data     map[string][]byte
+}
+
+func newStateOverlay(base *AccountState) *stateOverlay {
+	return &stateOverlay{
+		base:     base,
+		accounts: make(map[string]Account),
+		data:     make(map[string][]byte),
+	}
+}
+
+func (o *stateOverlay) account(address crypto.Address) Account {
+	if account, ok := o.accounts[string(address.Bytes())]; ok {
+		return account
+	}
+	return o.base.Account(address)
+}
+
+// Balance returns the balance of the account
+func (o *stateOverlay) Balance(address crypto.Address) uint64 {
+	return o.account(address).Balance
+}
+
+// Nonce returns the nonce of the last transaction sent by the account
+func (o *stateOverlay) Nonce(address crypto.Address) int64 {
+	return o.account(address).Nonce
+}
+
+// Get returns the consensus engine data stored under the key
+func (o *stateOverlay) Get(key string) []byte {
+	if value, ok := o.data[key]; ok {
+		return value
+	}
+	return o.base.Get(key)
+}
+
+// AddBalance credits the account
+func (o *stateOverlay) AddBalance(address crypto.Address, amount uint64) error {
+	account := o.account(address)
+	if account.Balance > math.MaxUint64-amount {
+		return fmt.Errorf("balance overflow for account (%s)", address)
+	}
+	account.Balance += amount
+	o.accounts[string(address.Bytes())] = account
+	return nil
+}
+
+// SubBalance debits the account, failing if the balance is not enough
+func (o *stateOverlay) SubBalance(address crypto.Address, amount uint64) error {
+	account := o.account(address)
+	if account.Balance < amount {
+		return fmt.Errorf("insufficient balance for account (%s): %d < %d", address, account.Balance, amount)
+	}
+	account.Balance -= amount
+	o.accounts[string(address.Bytes())] = account
+	return nil
+}
+
+// Set stores consensus engine data under the key, a nil value deletes the key
+func (o *stateOverlay) Set(key string, value []byte) {
+	o.data[key] = value
+}
+
+// setNonce sets the nonce of the last transaction sent by the account
+func (o *stateOverlay) setNonce(address crypto.Address, nonce int64) {
+	account := o.account(address)
+	account.Nonce = nonce
+	o.accounts[string(address.Bytes())] = account
+}
+
+// commit writes the changes to the account state
+func (o *stateOverlay) commit() {
+	o.base.lock.Lock()
+	defer o.base.lock.Unlock()
+
+	for address, account := range o.accounts {
+		o.base.accounts[address] = account
+	}
+	for key, value := range o.data {
+		if value == nil {
+			delete(o.base.data, key)
+			continue
+		}
+		o.base.data[key] = value
+	}
+}
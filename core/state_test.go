@@ -0,0 +1,94 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/consensus/instant"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateOverlay(t *testing.T) {
+	state := NewAccountState()
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	address := privateKey.PublicKey().Address()
+
+	overlay := newStateOverlay(state)
+	assert.Nil(t, overlay.AddBalance(address, 10))
+	assert.Nil(t, overlay.SubBalance(address, 4))
+	assert.Error(t, overlay.SubBalance(address, 7))
+	assert.Error(t, overlay.AddBalance(address, ^uint64(0)))
+	overlay.setNonce(address, 3)
+	overlay.Set("key", []byte("value"))
+
+	// The changes are not visible in the state until the overlay is committed
+	assert.Equal(t, uint64(6), overlay.Balance(address))
+	assert.Equal(t, uint64(0), state.Balance(address))
+	assert.Nil(t, state.Get("key"))
+
+	overlay.commit()
+	assert.Equal(t, Account{Balance: 6, Nonce: 3}, state.Account(address))
+	assert.Equal(t, []byte("value"), state.Get("key"))
+
+	overlay = newStateOverlay(state)
+	overlay.Set("key", nil)
+	overlay.commit()
+	assert.Nil(t, state.Get("key"))
+}
+
+func TestAccountBalances(t *testing.T) {
+	bc := NewBlockchain(NewMemorystore(), WithEngine(instantEngine(10)))
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	receiver, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	// The producer receives the reward plus the fee and pays the value plus the fee
+	tx := signedTransfer(t, producer, receiver.PublicKey().Bytes(), 1, 4, 2)
+	block, err := bc.BuildBlock(producer, []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+
+	assert.Equal(t, uint64(6), bc.State().Balance(producer.PublicKey().Address()))
+	assert.Equal(t, int64(1), bc.State().Nonce(producer.PublicKey().Address()))
+	assert.Equal(t, uint64(4), bc.State().Balance(receiver.PublicKey().Address()))
+
+	// Replaying a nonce is rejected
+	tx = signedTransfer(t, receiver, producer.PublicKey().Bytes(), 1, 1, 0)
+	block, err = bc.BuildBlock(producer, []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	tx = signedTransfer(t, receiver, producer.PublicKey().Bytes(), 1, 1, 0)
+	block, err = bc.BuildBlock(producer, []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(block))
+
+	// Spending more than the balance is rejected and leaves the state untouched
+	tx = signedTransfer(t, receiver, producer.PublicKey().Bytes(), 2, 100, 0)
+	block, err = bc.BuildBlock(producer, []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.Error(t, bc.AddBlock(block))
+	assert.Equal(t, uint64(3), bc.State().Balance(receiver.PublicKey().Address()))
+}
+
+// instantEngine creates an instant seal engine with a constant block reward
+func instantEngine(reward uint64) consensus.Engine {
+	return instant.New(consensus.RewardSchedule{InitialReward: reward})
+}
+
+// signedTransfer creates a transfer signed by the sender for testing purposes
+func signedTransfer(t *testing.T, from *crypto.PrivateKey, to []byte, nonce int64, value uint64, fee uint64) *proto.Transaction {
+	tx := &proto.Transaction{
+		From:  from.PublicKey().Bytes(),
+		To:    to,
+		Value: value,
+		Fee:   fee,
+		Nonce: nonce,
+	}
+	assert.Nil(t, types.SignTransaction(from, tx))
+	return tx
+}
@@ -1,9 +1,12 @@
 package core
 
 import (
+	"encoding/hex"
 	"fmt"
+	"sync"
 
 	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
 )
 
 type Storage interface {
@@ -11,19 +14,38 @@ type Storage interface {
 	Get(string) (*proto.Block, error)
 }
 
+// MemoryStore stores the blocks in memory, indexed by the hex encoded block hash
 type MemoryStore struct {
+	lock   sync.RWMutex
+	blocks map[string]*proto.Block
 }
 
 func NewMemorystore() *MemoryStore {
-	return &MemoryStore{}
+	return &MemoryStore{
+		blocks: make(map[string]*proto.Block),
+	}
 }
 
 func (s *MemoryStore) Put(b *proto.Block) error {
+	hash, err := types.HashBlock(b)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.blocks[hex.EncodeToString(hash)] = b
 	return nil
 }
 
 func (s *MemoryStore) Get(hash string) (*proto.Block, error) {
-	fmt.Println("Getting block from memory store")
-	fmt.Println("Hash:", hash)
-	return nil, nil
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	block, ok := s.blocks[hash]
+	if !ok {
+		return nil, fmt.Errorf("block with hash (%s) not found", hash)
+	}
+	return block, nil
 }
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultMedianTimeSpan is the number of last headers used to calculate the median time past
+	DefaultMedianTimeSpan = 11
+	// DefaultMaxFutureDrift is how far ahead of the local clock a block timestamp is allowed to be
+	DefaultMaxFutureDrift = 2 * time.Minute
+)
+
+// Clock provides the current time to the blockchain. It can be replaced to make validation deterministic in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock backed by the system time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock sets the clock used to validate and create block timestamps
+func WithClock(clock Clock) Option {
+	return func(bc *Blockchain) {
+		bc.clock = clock
+	}
+}
+
+// WithMedianTimeSpan sets the number of last headers used to calculate the median time past
+func WithMedianTimeSpan(span int) Option {
+	return func(bc *Blockchain) {
+		bc.medianTimeSpan = span
+	}
+}
+
+// WithMaxFutureDrift sets how far ahead of the clock a block timestamp is allowed to be
+func WithMaxFutureDrift(drift time.Duration) Option {
+	return func(bc *Blockchain) {
+		bc.maxFutureDrift = drift
+	}
+}
+
+// MedianTimePast returns the median timestamp (in nanoseconds) of the last headers in the blockchain.
+// A new block must have a timestamp greater than the median time past.
+func (bc *Blockchain) MedianTimePast() int64 {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+
+	span := bc.medianTimeSpan
+	if span > bc.headers.Len() {
+		span = bc.headers.Len()
+	}
+	if span < 1 {
+		span = 1
+	}
+
+	timestamps := make([]int64, 0, span)
+	for height := bc.headers.Height(); height > bc.headers.Height()-span; height-- {
+		timestamps = append(timestamps, bc.headers.Get(height).Timestamp)
+	}
+	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+	return timestamps[len(timestamps)/2]
+}
+
+// validateTimestamp checks that the block timestamp is after the median time past
+// and not too far in the future according to the blockchain clock
+func (bc *Blockchain) validateTimestamp(timestamp int64) error {
+	medianTimePast := bc.MedianTimePast()
+	if timestamp <= medianTimePast {
+		return fmt.Errorf("%w: block timestamp %d is not after the median time past %d", ErrInvalidBlock, timestamp, medianTimePast)
+	}
+
+	maxTimestamp := bc.clock.Now().Add(bc.maxFutureDrift).UnixNano()
+	if timestamp > maxTimestamp {
+		return fmt.Errorf("block timestamp %d is too far in the future (max %d)", timestamp, maxTimestamp)
+	}
+
+	return nil
+}
+
+// nextTimestamp returns the timestamp for a new block, which is the current time
+// unless the clock is behind the median time past
+func (bc *Blockchain) nextTimestamp() int64 {
+	timestamp := bc.clock.Now().UnixNano()
+	if medianTimePast := bc.MedianTimePast(); timestamp <= medianTimePast {
+		timestamp = medianTimePast + 1
+	}
+	return timestamp
+}
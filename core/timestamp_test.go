@@ -0,0 +1,79 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// fixedClock is a Clock that always returns the same time
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestMedianTimePast(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(0, genesisTimestamp)}
+	bc := NewBlockchain(NewMemorystore(), WithClock(clock), WithMedianTimeSpan(3))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	// With only the genesis block the median time past is the genesis timestamp
+	assert.Equal(t, int64(genesisTimestamp), bc.MedianTimePast())
+
+	for i := 1; i <= 5; i++ {
+		clock.now = clock.now.Add(time.Second)
+		block, err := bc.BuildBlock(privateKey, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, bc.AddBlock(block))
+	}
+
+	// The median of the last 3 blocks is the timestamp of block 4
+	header, err := bc.GetHeaderByHeight(4)
+	assert.Nil(t, err)
+	assert.Equal(t, header.Timestamp, bc.MedianTimePast())
+}
+
+func TestValidateBlockTimestamp(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(0, genesisTimestamp).Add(time.Hour)}
+	bc := NewBlockchain(NewMemorystore(), WithClock(clock), WithMaxFutureDrift(time.Minute))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	block, err := bc.BuildBlock(privateKey, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, clock.now.UnixNano(), block.Header.Timestamp)
+	assert.NoError(t, bc.ValidateBlock(block))
+
+	// A block that is not after the median time past is rejected
+	block.Header.Timestamp = genesisTimestamp
+	resignBlock(t, privateKey, block)
+	assert.Error(t, bc.ValidateBlock(block))
+
+	// A block too far in the future is rejected
+	block.Header.Timestamp = clock.now.Add(2 * time.Minute).UnixNano()
+	resignBlock(t, privateKey, block)
+	assert.Error(t, bc.ValidateBlock(block))
+
+	// A block within the allowed drift is accepted
+	block.Header.Timestamp = clock.now.Add(30 * time.Second).UnixNano()
+	resignBlock(t, privateKey, block)
+	assert.NoError(t, bc.ValidateBlock(block))
+}
+
+func TestBuildBlockClockBehindMedianTimePast(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(0, genesisTimestamp).Add(-30 * time.Second)}
+	bc := NewBlockchain(NewMemorystore(), WithClock(clock))
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	block, err := bc.BuildBlock(privateKey, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(genesisTimestamp+1), block.Header.Timestamp)
+	assert.NoError(t, bc.AddBlock(block))
+}
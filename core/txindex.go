@@ -0,0 +1,49 @@
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// TxLocation is the position of a transaction in the blockchain
+type TxLocation struct {
+	BlockHash []byte
+	Height    uint64
+	Index     int
+}
+
+// GetTransaction returns the transaction with the given hash and its position in the blockchain
+func (bc *Blockchain) GetTransaction(hash []byte) (*proto.Transaction, TxLocation, error) {
+	bc.lock.RLock()
+	location, ok := bc.txIndex[hex.EncodeToString(hash)]
+	bc.lock.RUnlock()
+	if !ok {
+		return nil, TxLocation{}, fmt.Errorf("transaction with hash (%s) not found", hex.EncodeToString(hash))
+	}
+
+	b, err := bc.GetBlockByHash(location.BlockHash)
+	if err != nil {
+		return nil, TxLocation{}, err
+	}
+	return b.Transactions[location.Index], location, nil
+}
+
+// txLocations returns the locations of the transactions of the block by their hex encoded hash
+func txLocations(b *proto.Block) (map[string]TxLocation, error) {
+	blockHash, err := types.HashBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	locations := make(map[string]TxLocation, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		hash, err := types.HashTransaction(tx)
+		if err != nil {
+			return nil, err
+		}
+		locations[hex.EncodeToString(hash)] = TxLocation{BlockHash: blockHash, Height: b.Header.GetHeight(), Index: i}
+	}
+	return locations, nil
+}
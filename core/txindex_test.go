@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTransaction(t *testing.T) {
+	bc := NewBlockchain(NewMemorystore())
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	tx := generateSignedTransaction(t, privateKey, 1, 2)
+	block, err := bc.BuildBlock(privateKey, []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+
+	hash, err := types.HashTransaction(tx)
+	assert.Nil(t, err)
+	found, location, err := bc.GetTransaction(hash)
+	assert.Nil(t, err)
+	assert.Equal(t, tx.Nonce, found.Nonce)
+	assert.Equal(t, uint64(1), location.Height)
+	assert.Equal(t, 1, location.Index)
+	blockHash, err := types.HashBlock(block)
+	assert.Nil(t, err)
+	assert.Equal(t, blockHash, location.BlockHash)
+
+	_, _, err = bc.GetTransaction(make([]byte, 32))
+	assert.Error(t, err)
+}
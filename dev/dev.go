@@ -0,0 +1,279 @@
+package dev
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/rpc"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+const (
+	// DefaultMnemonic is the well known mnemonic the dev accounts are derived from. Never use it outside development.
+	DefaultMnemonic = "test test test test test test test test test test test junk"
+	// DefaultAccounts is the number of pre-funded dev accounts
+	DefaultAccounts = 10
+	// DefaultBalance is the genesis balance of every dev account
+	DefaultBalance = 1_000_000_000
+	// DefaultChainID is the chain ID of the dev chain, from which the eth_chainId of the RPC is derived
+	DefaultChainID = "marvin-dev"
+)
+
+// Config holds the dev node parameters
+type Config struct {
+	// Mnemonic is the mnemonic the pre-funded accounts are derived from
+	Mnemonic string
+	// Accounts is the number of pre-funded accounts
+	Accounts int
+	// Balance is the genesis balance of every account
+	Balance uint64
+	// BlockTime seals a block on a fixed interval when set, otherwise a block is sealed whenever a transaction enters the mempool
+	BlockTime time.Duration
+	// RPCAddr is the address of the JSON-RPC server, empty to disable it
+	RPCAddr string
+	// ChainID identifies the dev chain in the RPC
+	ChainID string
+}
+
+// DefaultConfig is the dev node configuration used when none is provided
+var DefaultConfig = Config{
+	Mnemonic: DefaultMnemonic,
+	Accounts: DefaultAccounts,
+	Balance:  DefaultBalance,
+	RPCAddr:  rpc.DefaultConfig.ListenAddr,
+	ChainID:  DefaultChainID,
+}
+
+// DeriveAccounts derives n private keys from the mnemonic. The first key is the key of the mnemonic itself
+// and the key at index i > 0 is the key of the mnemonic suffixed with "/i".
+//
+// This is a deterministic scheme for dev accounts only, not a BIP-32 or SLIP-10 derivation path: wallets
+// restoring the mnemonic will not find the same keys, and the keys must never hold real funds.
+func DeriveAccounts(mnemonic string, n int) ([]*crypto.PrivateKey, error) {
+	accounts := make([]*crypto.PrivateKey, 0, n)
+	for i := 0; i < n; i++ {
+		phrase := mnemonic
+		if i > 0 {
+			phrase = fmt.Sprintf("%s/%d", mnemonic, i)
+		}
+		privateKey, err := crypto.NewPrivateKeyfromMnemonic(phrase)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &privateKey)
+	}
+	return accounts, nil
+}
+
+// Node is a single process development node using the instant seal engine.
+// The accounts derived from the mnemonic are funded in the genesis block and the first one seals the blocks.
+type Node struct {
+	config   Config
+	bc       *core.Blockchain
+	mempool  *core.Mempool
+	accounts []*crypto.PrivateKey
+	rpc      *rpc.Server
+
+	// sealLock serializes the sealing of blocks
+	sealLock sync.Mutex
+	trigger  chan struct{}
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewNode creates a dev node with the given configuration. The options configure the blockchain.
+func NewNode(config Config, opts ...core.Option) (*Node, error) {
+	if config.Accounts < 1 {
+		return nil, fmt.Errorf("dev node requires at least one account")
+	}
+	accounts, err := DeriveAccounts(config.Mnemonic, config.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	alloc := make([]core.GenesisAccount, 0, len(accounts))
+	for _, account := range accounts {
+		alloc = append(alloc, core.GenesisAccount{Address: account.PublicKey().Address(), Balance: config.Balance})
+	}
+	opts = append([]core.Option{core.WithGenesisAlloc(alloc)}, opts...)
+
+	bc := core.NewBlockchain(core.NewMemorystore(), opts...)
+	n := &Node{
+		config:   config,
+		bc:       bc,
+		mempool:  core.NewMempool(core.WithMempoolState(bc.State())),
+		accounts: accounts,
+		trigger:  make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+	n.mempool.OnAdd(func(tx *proto.Transaction) {
+		select {
+		case n.trigger <- struct{}{}:
+		default:
+		}
+	})
+	if config.RPCAddr != "" {
+		rpcConfig := rpc.DefaultConfig
+		rpcConfig.ListenAddr = config.RPCAddr
+		n.rpc = rpc.NewServer(rpcConfig)
+		rpc.NewAPI(bc, n.mempool).Register(n.rpc)
+		rpc.NewEthAPI(bc, n.mempool, rpc.EthChainID(config.ChainID)).Register(n.rpc)
+		rpc.NewSubscriptions(bc, n.mempool).Register(n.rpc)
+		rpc.NewREST(bc, n.mempool).Register(n.rpc)
+	}
+
+	return n, nil
+}
+
+// Blockchain returns the blockchain of the node
+func (n *Node) Blockchain() *core.Blockchain {
+	return n.bc
+}
+
+// Mempool returns the mempool of the node
+func (n *Node) Mempool() *core.Mempool {
+	return n.mempool
+}
+
+// Accounts returns the pre-funded account keys
+func (n *Node) Accounts() []*crypto.PrivateKey {
+	return n.accounts
+}
+
+// RPC returns the JSON-RPC server of the node, nil when disabled
+func (n *Node) RPC() *rpc.Server {
+	return n.rpc
+}
+
+// Start serves the RPC requests and starts sealing blocks, on every new transaction or on the configured block time
+func (n *Node) Start() error {
+	if n.rpc != nil {
+		if err := n.rpc.Start(); err != nil {
+			return err
+		}
+	}
+	n.wg.Add(1)
+	go n.loop()
+	return nil
+}
+
+// Stop stops serving the RPC requests and sealing blocks, then waits for the block being sealed
+func (n *Node) Stop() {
+	if n.rpc != nil {
+		if err := n.rpc.Stop(); err != nil {
+			log.Error().Err(err).Msg("failed to stop rpc server")
+		}
+	}
+	close(n.quit)
+	n.wg.Wait()
+}
+
+// SendTransaction verifies the transaction signature and adds it to the mempool
+func (n *Node) SendTransaction(tx *proto.Transaction) error {
+	if ok, err := types.VerifyTransaction(tx); err != nil || !ok {
+		return fmt.Errorf("transaction verification failed: %v", err)
+	}
+	from, err := types.AccountAddress(tx.From)
+	if err != nil {
+		return err
+	}
+	if nonce := n.bc.State().Nonce(from); tx.Nonce <= nonce {
+		return fmt.Errorf("nonce %d already used by account (%s), next nonce is %d", tx.Nonce, from, nonce+1)
+	}
+	return n.mempool.Add(tx)
+}
+
+// Seal seals a block with the pending transactions of the mempool that can be applied.
+// Transactions that can never be applied are discarded, transactions with a future nonce stay in the mempool.
+func (n *Node) Seal() (*proto.Block, error) {
+	n.sealLock.Lock()
+	defer n.sealLock.Unlock()
+
+	pending := n.mempool.Pending()
+	block, err := n.bc.BuildBlock(n.accounts[0], pending)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.bc.ValidateBlock(block); err != nil {
+		// Some transactions can not be applied, only keep the ones that can
+		block, err = n.buildValidBlock(pending)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := n.bc.AddBlock(block); err != nil {
+		return nil, err
+	}
+	n.mempool.Remove(n.staleTransactions(pending))
+
+	return block, nil
+}
+
+// buildValidBlock builds a block adding the pending transactions one by one, skipping the ones that make the block invalid
+func (n *Node) buildValidBlock(pending []*proto.Transaction) (*proto.Block, error) {
+	accepted := make([]*proto.Transaction, 0, len(pending))
+	for _, tx := range pending {
+		block, err := n.bc.BuildBlock(n.accounts[0], append(accepted, tx))
+		if err != nil {
+			return nil, err
+		}
+		if err := n.bc.ValidateBlock(block); err != nil {
+			log.Debug().Err(err).Int64("nonce", tx.Nonce).Msg("transaction skipped")
+			continue
+		}
+		accepted = append(accepted, tx)
+	}
+	return n.bc.BuildBlock(n.accounts[0], accepted)
+}
+
+// staleTransactions returns the transactions that were included in the last block or can never be applied,
+// which are the ones whose nonce is not ahead of the account nonce. The transactions skipped for another reason
+// stay in the mempool, they may apply in a later block.
+func (n *Node) staleTransactions(txs []*proto.Transaction) []*proto.Transaction {
+	stale := make([]*proto.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		from, err := types.AccountAddress(tx.From)
+		if err != nil || tx.Nonce <= n.bc.State().Nonce(from) {
+			stale = append(stale, tx)
+		}
+	}
+	return stale
+}
+
+// loop seals blocks until the node is stopped
+func (n *Node) loop() {
+	defer n.wg.Done()
+
+	var tick <-chan time.Time
+	if n.config.BlockTime > 0 {
+		ticker := time.NewTicker(n.config.BlockTime)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-n.quit:
+			return
+		case <-tick:
+			n.seal()
+		case <-n.trigger:
+			// With a block time the transactions wait for the next block
+			if n.config.BlockTime == 0 && n.mempool.Len() > 0 {
+				n.seal()
+			}
+		}
+	}
+}
+
+func (n *Node) seal() {
+	if _, err := n.Seal(); err != nil {
+		log.Error().Err(err).Msg("failed to seal block")
+	}
+}
@@ -0,0 +1,178 @@
+package dev
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/rpc"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveAccounts(t *testing.T) {
+	accounts, err := DeriveAccounts(DefaultMnemonic, 3)
+	assert.Nil(t, err)
+	assert.Len(t, accounts, 3)
+
+	first, err := crypto.NewPrivateKeyfromMnemonic(DefaultMnemonic)
+	assert.Nil(t, err)
+	assert.Equal(t, first.Bytes(), accounts[0].Bytes())
+	assert.NotEqual(t, accounts[0].Bytes(), accounts[1].Bytes())
+	assert.NotEqual(t, accounts[1].Bytes(), accounts[2].Bytes())
+
+	// The accounts are the same every time
+	again, err := DeriveAccounts(DefaultMnemonic, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, accounts[2].Bytes(), again[2].Bytes())
+}
+
+func TestNewNodeFundsAccounts(t *testing.T) {
+	node, err := NewNode(DefaultConfig)
+	assert.Nil(t, err)
+	assert.Len(t, node.Accounts(), DefaultAccounts)
+	for _, account := range node.Accounts() {
+		assert.Equal(t, uint64(DefaultBalance), node.Blockchain().State().Balance(account.PublicKey().Address()))
+	}
+
+	_, err = NewNode(Config{Mnemonic: DefaultMnemonic})
+	assert.Error(t, err)
+}
+
+func TestNodeSealsOnTransaction(t *testing.T) {
+	config := DefaultConfig
+	config.RPCAddr = ""
+	node, err := NewNode(config)
+	assert.Nil(t, err)
+	assert.NoError(t, node.Start())
+	defer node.Stop()
+
+	from, to := node.Accounts()[1], node.Accounts()[2]
+	assert.NoError(t, node.SendTransaction(transfer(t, from, to, 100, 1)))
+	assert.Eventually(t, func() bool { return node.Blockchain().Height() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 0, node.Mempool().Len())
+
+	state := node.Blockchain().State()
+	assert.Equal(t, uint64(DefaultBalance-100), state.Balance(from.PublicKey().Address()))
+	assert.Equal(t, uint64(DefaultBalance+100), state.Balance(to.PublicKey().Address()))
+
+	// A used nonce and an invalid signature are rejected
+	assert.Error(t, node.SendTransaction(transfer(t, from, to, 100, 1)))
+	tx := transfer(t, from, to, 100, 2)
+	tx.Value = 200
+	assert.Error(t, node.SendTransaction(tx))
+}
+
+func TestNodeSealSkipsInvalidTransactions(t *testing.T) {
+	node, err := NewNode(DefaultConfig)
+	assert.Nil(t, err)
+
+	from, to := node.Accounts()[1], node.Accounts()[2]
+	valid := transfer(t, from, to, 100, 1)
+	future := transfer(t, from, to, 100, 3)
+	funded := transfer(t, to, from, DefaultBalance/2, 1)
+	for _, tx := range []*proto.Transaction{valid, future, funded} {
+		assert.NoError(t, node.SendTransaction(tx))
+	}
+	// The sender cannot pay for it on top of its pending transaction, nor on its own
+	assert.Error(t, node.SendTransaction(transfer(t, to, from, DefaultBalance/2+200, 2)))
+	assert.Error(t, node.SendTransaction(transfer(t, to, from, DefaultBalance+1, 2)))
+
+	block, err := node.Seal()
+	assert.Nil(t, err)
+	assert.Len(t, block.Transactions, 3)
+	assert.Equal(t, 1, node.Blockchain().Height())
+
+	// The transaction with a future nonce waits for the missing nonce
+	assert.Equal(t, 1, node.Mempool().Len())
+	assert.True(t, node.Mempool().Has(future))
+
+	assert.NoError(t, node.SendTransaction(transfer(t, from, to, 100, 2)))
+	block, err = node.Seal()
+	assert.Nil(t, err)
+	assert.Len(t, block.Transactions, 3)
+	assert.Equal(t, 0, node.Mempool().Len())
+	assert.Equal(t, int64(3), node.Blockchain().State().Nonce(from.PublicKey().Address()))
+}
+
+func TestNodeStaleTransactions(t *testing.T) {
+	node, err := NewNode(DefaultConfig)
+	assert.Nil(t, err)
+
+	from, to := node.Accounts()[1], node.Accounts()[2]
+	assert.NoError(t, node.SendTransaction(transfer(t, from, to, 100, 1)))
+	_, err = node.Seal()
+	assert.Nil(t, err)
+
+	// Only the transactions whose nonce is used are stale, the next nonce may still be applied
+	used := transfer(t, from, to, 200, 1)
+	next := transfer(t, from, to, 100, 2)
+	future := transfer(t, from, to, 100, 3)
+	assert.Equal(t, []*proto.Transaction{used}, node.staleTransactions([]*proto.Transaction{used, next, future}))
+}
+
+func TestNodeSealsOnBlockTime(t *testing.T) {
+	config := DefaultConfig
+	config.BlockTime = 10 * time.Millisecond
+	config.RPCAddr = ""
+	node, err := NewNode(config)
+	assert.Nil(t, err)
+	assert.NoError(t, node.Start())
+	defer node.Stop()
+
+	// Empty blocks are sealed on the block time
+	assert.Eventually(t, func() bool { return node.Blockchain().Height() >= 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestNodeServesRPC(t *testing.T) {
+	config := DefaultConfig
+	config.RPCAddr = "127.0.0.1:0"
+	node, err := NewNode(config)
+	assert.Nil(t, err)
+	assert.NoError(t, node.Start())
+	defer node.Stop()
+
+	// A transaction sent through the RPC is sealed in a block
+	data, err := types.SerializeTransaction(transfer(t, node.Accounts()[1], node.Accounts()[2], 100, 1))
+	assert.Nil(t, err)
+	response := call(t, node.RPC().Addr(), "marvin_sendRawTransaction", hex.EncodeToString(data))
+	assert.Nil(t, response.Error)
+	assert.Eventually(t, func() bool { return node.Blockchain().Height() == 1 }, time.Second, 5*time.Millisecond)
+
+	response = call(t, node.RPC().Addr(), "eth_blockNumber")
+	assert.Nil(t, response.Error)
+	assert.JSONEq(t, `"0x1"`, string(response.Result))
+	response = call(t, node.RPC().Addr(), "eth_chainId")
+	assert.Nil(t, response.Error)
+	assert.JSONEq(t, fmt.Sprintf(`"0x%x"`, rpc.EthChainID(DefaultChainID)), string(response.Result))
+}
+
+// transfer creates a transfer signed by the sender
+func transfer(t *testing.T, from *crypto.PrivateKey, to *crypto.PrivateKey, value uint64, nonce int64) *proto.Transaction {
+	tx := &proto.Transaction{
+		To:    to.PublicKey().Bytes(),
+		Value: value,
+		Nonce: nonce,
+	}
+	assert.Nil(t, types.SignTransaction(from, tx))
+	return tx
+}
+
+// call sends the JSON-RPC request to the server and decodes its response
+func call(t *testing.T, addr string, method string, params ...interface{}) rpc.Response {
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	assert.Nil(t, err)
+	resp, err := http.Post("http://"+addr, "application/json", bytes.NewReader(body))
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	var response rpc.Response
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	return response
+}
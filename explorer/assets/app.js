@@ -0,0 +1,185 @@
+"use strict";
+
+// The REST API is served by the node at the root of the same address
+const api = "/";
+const pageSize = 20;
+const refreshInterval = 5000;
+
+const content = document.getElementById("content");
+
+async function get(path) {
+  const response = await fetch(api + path);
+  const body = await response.json();
+  if (!response.ok) {
+    throw new Error(body.error || response.statusText);
+  }
+  return body;
+}
+
+function escape(value) {
+  return String(value)
+    .replace(/&/g, "&amp;")
+    .replace(/</g, "&lt;")
+    .replace(/>/g, "&gt;")
+    .replace(/"/g, "&quot;");
+}
+
+function link(route, text) {
+  return `<a class="hash" href="#/${route}">${escape(text)}</a>`;
+}
+
+function accountLink(address) {
+  return address ? link(`account/${address}`, address) : "-";
+}
+
+function rows(fields) {
+  return fields.map(([name, value]) => `<tr><th>${escape(name)}</th><td class="hash">${value}</td></tr>`).join("");
+}
+
+function showError(err) {
+  content.innerHTML = `<div class="error">${escape(err.message)}</div>`;
+}
+
+async function refreshStats() {
+  try {
+    const [page, mempool] = await Promise.all([get("blocks?limit=1"), get("mempool")]);
+    document.getElementById("height").textContent = page.height;
+    document.getElementById("mempool").textContent = mempool.size;
+  } catch (err) {
+    document.getElementById("height").textContent = "-";
+    document.getElementById("mempool").textContent = "-";
+  }
+}
+
+async function showBlocks(from) {
+  const query = from === undefined ? `limit=${pageSize}` : `from=${from}&limit=${pageSize}`;
+  const page = await get(`blocks?${query}`);
+  const blocks = page.blocks.map((b) => `<tr>
+    <td>${link(`block/${b.height}`, b.height)}</td>
+    <td class="hash">${link(`block/${b.hash}`, b.hash)}</td>
+    <td>${escape(b.time)}</td>
+    <td>${b.txCount}</td>
+    <td class="hash">${accountLink(b.producer)}</td>
+  </tr>`).join("");
+  const next = page.next === null ? "" : `<a href="#/blocks/${page.next}">Older blocks</a>`;
+  content.innerHTML = `<h2>Recent blocks</h2>
+    <table>
+      <tr><th>Height</th><th>Hash</th><th>Time</th><th>Transactions</th><th>Producer</th></tr>
+      ${blocks}
+    </table>
+    <div class="pager">${next}</div>`;
+}
+
+async function showBlock(id) {
+  const b = await get(/^\d+$/.test(id) ? `blocks/${id}` : `blocks/hash/${id}`);
+  const txs = b.transactions.map((tx) => `<tr>
+    <td class="hash">${link(`tx/${tx.hash}`, tx.hash)}</td>
+    <td>${escape(tx.type)}</td>
+    <td class="hash">${accountLink(tx.fromAddress)}</td>
+    <td class="hash">${accountLink(tx.toAddress)}</td>
+    <td>${tx.value}</td>
+  </tr>`).join("");
+  content.innerHTML = `<h2>Block ${escape(b.height)}</h2>
+    <table>${rows([
+      ["Hash", escape(b.hash)],
+      ["Previous block", b.height > 0 ? link(`block/${b.prevBlockHash}`, b.prevBlockHash) : "-"],
+      ["Time", escape(b.time)],
+      ["Producer", accountLink(b.producer)],
+      ["Transactions root", escape(b.txHash)],
+      ["Difficulty", escape(b.difficulty)],
+      ["Nonce", escape(b.nonce)],
+      ["Extra data", escape(b.extraData || "-")],
+    ])}</table>
+    <h2>Transactions (${b.txCount})</h2>
+    <table>
+      <tr><th>Hash</th><th>Type</th><th>From</th><th>To</th><th>Value</th></tr>
+      ${txs}
+    </table>`;
+}
+
+async function showTransaction(hash) {
+  const tx = await get(`tx/${hash}`);
+  const block = tx.blockHash === null ? "Pending" : link(`block/${tx.blockHash}`, `${tx.blockHeight} (${tx.blockHash})`);
+  content.innerHTML = `<h2>Transaction</h2>
+    <table>${rows([
+      ["Hash", escape(tx.hash)],
+      ["Block", block],
+      ["Type", escape(tx.type)],
+      ["From", accountLink(tx.fromAddress)],
+      ["To", accountLink(tx.toAddress)],
+      ["Value", escape(tx.value)],
+      ["Fee", escape(tx.fee)],
+      ["Nonce", escape(tx.nonce)],
+      ["Data", escape(tx.data || "-")],
+    ])}</table>`;
+}
+
+async function showAccount(address) {
+  const account = await get(`accounts/${address}`);
+  content.innerHTML = `<h2>Account</h2>
+    <table>${rows([
+      ["Address", escape(account.address)],
+      ["Balance", escape(account.balance)],
+      ["Nonce", escape(account.nonce)],
+    ])}</table>`;
+}
+
+async function search(query) {
+  const result = await get(`search?q=${encodeURIComponent(query)}`);
+  switch (result.type) {
+    case "block":
+      location.hash = `#/block/${result.block.hash}`;
+      break;
+    case "transaction":
+      location.hash = `#/tx/${result.transaction.hash}`;
+      break;
+    case "account":
+      location.hash = `#/account/${result.account.address}`;
+      break;
+  }
+}
+
+async function route() {
+  const [view, id] = location.hash.replace(/^#\/?/, "").split("/");
+  try {
+    switch (view) {
+      case "block":
+        await showBlock(id);
+        break;
+      case "tx":
+        await showTransaction(id);
+        break;
+      case "account":
+        await showAccount(id);
+        break;
+      case "blocks":
+        await showBlocks(id);
+        break;
+      default:
+        await showBlocks();
+    }
+  } catch (err) {
+    showError(err);
+  }
+}
+
+document.getElementById("search").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const query = document.getElementById("query").value.trim();
+  if (query) {
+    search(query).catch(showError);
+  }
+});
+
+window.addEventListener("hashchange", route);
+
+setInterval(() => {
+  refreshStats();
+  // Only the first page of recent blocks follows the new blocks
+  if (location.hash === "" || location.hash === "#/") {
+    route();
+  }
+}, refreshInterval);
+
+refreshStats();
+route();
@@ -0,0 +1,24 @@
+package explorer
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// Path is the path the explorer is served under
+const Path = "/explorer/"
+
+// assets are the HTML, script and style files of the explorer, which reads the blockchain through the REST API
+//
+//go:embed assets
+var assets embed.FS
+
+// Handler returns the handler serving the explorer under Path
+func Handler() http.Handler {
+	files, err := fs.Sub(assets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix(Path, http.FileServer(http.FS(files)))
+}
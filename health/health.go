@@ -0,0 +1,188 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/network"
+	"github.com/joaoh82/marvinblockchain/proto"
+)
+
+// Paths the health and the readiness are served under
+const (
+	HealthPath = "/healthz"
+	ReadyPath  = "/readyz"
+)
+
+// Statuses of a report
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Chain is the blockchain whose synchronization is checked
+type Chain interface {
+	Height() int
+	GetHeaderByHeight(height int) (*proto.Header, error)
+}
+
+// Network is the network server whose peers are checked
+type Network interface {
+	Peers() []*network.Peer
+}
+
+// Syncer is the synchronization of the chain from the peers
+type Syncer interface {
+	Progress() network.SyncProgress
+}
+
+// Store is the block store whose health is checked
+type Store interface {
+	Check() error
+}
+
+// Config holds the thresholds of the readiness
+type Config struct {
+	// MinPeers is the minimum number of connected peers of a ready node
+	MinPeers int
+	// MaxHeightLag is the maximum number of blocks a ready node is behind its best peer
+	MaxHeightLag uint64
+	// MaxBlockAge is the maximum time since the timestamp of the last block of a ready node, 0 to not check it
+	MaxBlockAge time.Duration
+}
+
+// DefaultConfig is the health configuration used when none is provided. The age of the last block is not checked,
+// as chains sealing blocks on demand can go a long time without a block.
+var DefaultConfig = Config{
+	MinPeers:     1,
+	MaxHeightLag: 5,
+}
+
+// Report is the state of the node checked by a probe, with the reasons it failed
+type Report struct {
+	Status              string   `json:"status"`
+	Storage             string   `json:"storage"`
+	Height              uint64   `json:"height"`
+	BestPeerHeight      uint64   `json:"bestPeerHeight"`
+	Peers               int      `json:"peers"`
+	Syncing             bool     `json:"syncing"`
+	LastBlockAgeSeconds float64  `json:"lastBlockAgeSeconds"`
+	Failures            []string `json:"failures,omitempty"`
+}
+
+// Checker reports the health and the readiness of a node. A node is healthy while its block store works,
+// and ready once it is healthy, connected to enough peers, close to the height of its best peer
+// and has a recent enough last block.
+type Checker struct {
+	config Config
+	chain  Chain
+	server Network
+	syncer Syncer
+	store  Store
+	now    func() time.Time
+}
+
+// NewChecker creates the checker of the node components
+func NewChecker(config Config, chain Chain, server Network, syncer Syncer, store Store) *Checker {
+	return &Checker{
+		config: config,
+		chain:  chain,
+		server: server,
+		syncer: syncer,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// Health reports whether the node works, only failing on a storage error
+func (c *Checker) Health() *Report {
+	report := c.report()
+	if report.Storage != StatusOK {
+		report.fail("storage: %s", report.Storage)
+	}
+	return report
+}
+
+// Readiness reports whether the node is synchronized with the network and can serve its requests
+func (c *Checker) Readiness() *Report {
+	report := c.Health()
+	if report.Peers < c.config.MinPeers {
+		report.fail("%d peers connected, %d required", report.Peers, c.config.MinPeers)
+	}
+	if report.BestPeerHeight > report.Height+c.config.MaxHeightLag {
+		report.fail("height %d is %d blocks behind the best peer height %d, %d allowed",
+			report.Height, report.BestPeerHeight-report.Height, report.BestPeerHeight, c.config.MaxHeightLag)
+	}
+	if c.config.MaxBlockAge > 0 {
+		if age := time.Duration(report.LastBlockAgeSeconds * float64(time.Second)); age > c.config.MaxBlockAge {
+			report.fail("last block is %s old, %s allowed", age.Round(time.Second), c.config.MaxBlockAge)
+		}
+	}
+	return report
+}
+
+// HealthHandler returns the handler of the health probe, answering 503 when the node is unhealthy
+func (c *Checker) HealthHandler() http.Handler {
+	return probeHandler(c.Health)
+}
+
+// ReadyHandler returns the handler of the readiness probe, answering 503 when the node is not ready
+func (c *Checker) ReadyHandler() http.Handler {
+	return probeHandler(c.Readiness)
+}
+
+// report reads the state of the node
+func (c *Checker) report() *Report {
+	report := &Report{
+		Status:  StatusOK,
+		Storage: StatusOK,
+		Height:  uint64(c.chain.Height()),
+	}
+	if err := c.store.Check(); err != nil {
+		report.Storage = err.Error()
+	}
+
+	peers := c.server.Peers()
+	report.Peers = len(peers)
+	// only heights validated locally count, the heights claimed by the peers are not trusted
+	for _, peer := range peers {
+		if height := peer.VerifiedHeight(); height > report.BestPeerHeight {
+			report.BestPeerHeight = height
+		}
+	}
+	report.Syncing = c.syncer.Progress().Syncing
+
+	if header, err := c.chain.GetHeaderByHeight(int(report.Height)); err == nil {
+		age := c.now().Sub(time.Unix(0, header.Timestamp))
+		report.LastBlockAgeSeconds = age.Seconds()
+	}
+	return report
+}
+
+// fail records the reason the check failed
+func (r *Report) fail(format string, args ...interface{}) {
+	r.Status = StatusUnavailable
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
+
+// probeHandler serves the report as JSON, with the 503 status when the check failed
+func probeHandler(check func() *Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := check()
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Debug().Err(err).Msg("failed to write health report")
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/network"
+	"github.com/stretchr/testify/assert"
+)
+
+// testStore is a block store failing with its error
+type testStore struct {
+	err error
+}
+
+func (s *testStore) Check() error {
+	return s.err
+}
+
+// testSyncer is a synchronization that is not running
+type testSyncer struct{}
+
+func (testSyncer) Progress() network.SyncProgress {
+	return network.SyncProgress{}
+}
+
+// newTestServer starts a network server for the chain
+func newTestServer(t *testing.T, chain *core.Blockchain) *network.Server {
+	server, err := network.NewServer(network.DefaultConfig, network.NewTCPTransport("127.0.0.1:0"), chain)
+	assert.Nil(t, err)
+	assert.NoError(t, server.Start())
+	t.Cleanup(server.Stop)
+	return server
+}
+
+// probe serves the request of the probe and decodes its report
+func probe(t *testing.T, handler http.Handler) (int, Report) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	var report Report
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	return recorder.Code, report
+}
+
+func TestChecker(t *testing.T) {
+	chain := core.NewBlockchain(core.NewMemorystore())
+	store := &testStore{}
+	server := newTestServer(t, chain)
+	config := Config{MinPeers: 1, MaxHeightLag: 2, MaxBlockAge: time.Minute}
+	checker := NewChecker(config, chain, server, testSyncer{}, store)
+	genesis, err := chain.GetHeaderByHeight(0)
+	assert.Nil(t, err)
+	checker.now = func() time.Time { return time.Unix(0, genesis.Timestamp).Add(30 * time.Second) }
+
+	// Healthy, but not ready without peers
+	code, report := probe(t, checker.HealthHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	code, report = probe(t, checker.ReadyHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, 0, report.Peers)
+	assert.Len(t, report.Failures, 1)
+	assert.Equal(t, float64(30), report.LastBlockAgeSeconds)
+
+	// A peer 3 blocks ahead is more than the allowed lag
+	peerChain := core.NewBlockchain(core.NewMemorystore())
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		b, err := peerChain.BuildBlock(producer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, peerChain.AddBlock(b))
+	}
+	peer := newTestServer(t, peerChain)
+	_, err = server.Connect(peer.Addr())
+	assert.Nil(t, err)
+
+	// The height claimed in the handshake is not trusted until the headers of the peer are validated
+	report = *checker.Readiness()
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, 1, report.Peers)
+	assert.Equal(t, uint64(0), report.BestPeerHeight)
+	assert.Equal(t, uint64(3), server.Peers()[0].Height())
+
+	server.Peers()[0].SetHeight(3)
+	report = *checker.Readiness()
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, 1, report.Peers)
+	assert.Equal(t, uint64(3), report.BestPeerHeight)
+	assert.Len(t, report.Failures, 1)
+
+	checker.config.MaxHeightLag = 3
+	code, report = probe(t, checker.ReadyHandler())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, report.Failures)
+
+	// An old last block and a storage error
+	checker.now = func() time.Time { return time.Unix(0, genesis.Timestamp).Add(time.Hour) }
+	assert.Equal(t, StatusUnavailable, checker.Readiness().Status)
+	store.err = errors.New("disk full")
+	code, report = probe(t, checker.HealthHandler())
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "disk full", report.Storage)
+}
@@ -1,96 +1,142 @@
 package main
 
 import (
-	"encoding/hex"
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/joaoh82/marvinblockchain/crypto"
-	"github.com/joaoh82/marvinblockchain/proto"
-	"github.com/joaoh82/marvinblockchain/types"
-	"github.com/tyler-smith/go-bip39"
-)
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
 
-func main() {
-	fmt.Println("Marvin Blockchain")
+	"github.com/joaoh82/marvinblockchain/node"
+)
 
-	BlockSerialization()
+// rootCmd runs the full node daemon
+var rootCmd = &cobra.Command{
+	Use:   "marvin",
+	Short: "marvin runs a Marvin Blockchain full node",
+	Long: `marvin runs a Marvin Blockchain full node.
+The configuration is read from the config file, then overridden by the MARVIN_ prefixed
+environment variables (like MARVIN_DATA_DIR) and finally by the command line flags.
+The node stops on SIGINT or SIGTERM after flushing its state to the data directory.`,
+	Example:      "Usage: marvin --config marvin.yaml --data-dir ./data --bootnodes 10.0.0.1:3000,10.0.0.2:3000",
+	Version:      "0.0.1",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		level, err := zerolog.ParseLevel(config.LogLevel)
+		if err != nil {
+			return fmt.Errorf("invalid log level (%s)", config.LogLevel)
+		}
+		zerolog.SetGlobalLevel(level)
+
+		n, err := node.New(config)
+		if err != nil {
+			return err
+		}
+		if err := n.Start(); err != nil {
+			return err
+		}
+
+		// Run until interrupted
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		<-ctx.Done()
+
+		log.Info().Msg("shutting down")
+		return n.Stop()
+	},
 }
 
-func BlockSerialization() {
-	fmt.Println("Block Serialization")
-
-	mnemonicTo := "all wild paddle pride wheat menu task funny sign profit blouse hockey"
-	// Generate a new private key from the mnemonic
-	privateKeyTo, _ := crypto.NewPrivateKeyfromMnemonic(mnemonicTo)
-	// fmt.Println("private key TO:", privateKeyTo)
-	publicKeyTo := privateKeyTo.PublicKey()
-	// fmt.Println("public key TO:", publicKeyTo)
-	addressTo := publicKeyTo.Address()
-	fmt.Println("address TO:", addressTo)
-
-	mnemonicFrom := "hello wild paddle pride wheat menu task funny sign profit blouse hockey"
-	// Generate a new private key from the mnemonic
-	privateKeyFrom, _ := crypto.NewPrivateKeyfromMnemonic(mnemonicFrom)
-	// fmt.Println("private key FROM:", privateKeyFrom)
-	publicKeyFrom := privateKeyFrom.PublicKey()
-	// fmt.Println("public key FROM:", publicKeyFrom)
-	addressFrom := publicKeyFrom.Address()
-	fmt.Println("address FROM:", addressFrom)
-
-	header := &proto.Header{
-		PrevBlockHash: make([]byte, 32),
-		TxHash:        make([]byte, 32),
-		Version:       1,
-		Height:        1,
-		Timestamp:     1627483623,
-		Nonce:         12345,
-		Difficulty:    10,
+// loadConfig reads the config file, then applies the environment variables and the flags set on the command line
+func loadConfig(cmd *cobra.Command) (node.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	config, err := node.LoadConfig(path)
+	if err != nil {
+		return node.Config{}, err
 	}
-
-	block := &proto.Block{
-		Header:       header,
-		Transactions: []*proto.Transaction{},
-		PublicKey:    publicKeyFrom.Bytes(),
-		Signature:    []byte{},
-		Hash:         []byte{},
+	if err := config.ApplyEnv(os.LookupEnv); err != nil {
+		return node.Config{}, err
 	}
 
-	tx := &proto.Transaction{
-		From:      publicKeyFrom.Bytes(),
-		To:        publicKeyTo.Bytes(),
-		Value:     1000,
-		Data:      []byte("Transaction data"),
-		Signature: make([]byte, 64),
-		Nonce:     123,
-		Hash:      make([]byte, 32),
-	}
-	types.SignTransaction(&privateKeyFrom, tx)
-	types.AddTransaction(block, tx)
-
-	types.SignBlock(&privateKeyFrom, block)
-
-	bBlock, _ := types.SerializeBlock(block)
-	fmt.Println("GO: Block WITH TRANSACTIONS hex:", hex.EncodeToString(bBlock))
+	flags := cmd.Flags()
+	if flags.Changed("data-dir") {
+		config.DataDir, _ = flags.GetString("data-dir")
+	}
+	if flags.Changed("chain-id") {
+		config.ChainID, _ = flags.GetString("chain-id")
+	}
+	if flags.Changed("consensus") {
+		config.Consensus, _ = flags.GetString("consensus")
+	}
+	if flags.Changed("authorities") {
+		authorities, _ := flags.GetString("authorities")
+		config.Authorities = node.SplitList(authorities)
+	}
+	if flags.Changed("validator-key-file") {
+		config.ValidatorKeyFile, _ = flags.GetString("validator-key-file")
+	}
+	if flags.Changed("listen") {
+		config.ListenAddr, _ = flags.GetString("listen")
+	}
+	if flags.Changed("bootnodes") {
+		bootnodes, _ := flags.GetString("bootnodes")
+		config.Bootnodes = node.SplitList(bootnodes)
+	}
+	if flags.Changed("max-peers") {
+		config.MaxPeers, _ = flags.GetInt("max-peers")
+	}
+	if flags.Changed("target-outbound") {
+		config.TargetOutbound, _ = flags.GetInt("target-outbound")
+	}
+	if flags.Changed("rpc-addr") {
+		config.RPCAddr, _ = flags.GetString("rpc-addr")
+	}
+	if flags.Changed("grpc-addr") {
+		config.GRPCAddr, _ = flags.GetString("grpc-addr")
+	}
+	if flags.Changed("health-min-peers") {
+		config.HealthMinPeers, _ = flags.GetInt("health-min-peers")
+	}
+	if flags.Changed("health-max-height-lag") {
+		config.HealthMaxHeightLag, _ = flags.GetInt("health-max-height-lag")
+	}
+	if flags.Changed("health-max-block-age") {
+		config.HealthMaxBlockAge, _ = flags.GetDuration("health-max-block-age")
+	}
+	if flags.Changed("log-level") {
+		config.LogLevel, _ = flags.GetString("log-level")
+	}
+	return config, nil
+}
 
+func init() {
+	rootCmd.Flags().String("config", "", "The YAML configuration file")
+	rootCmd.Flags().String("data-dir", node.DefaultConfig.DataDir, "The directory of the block store, the node key and the peer files")
+	rootCmd.Flags().String("chain-id", node.DefaultConfig.ChainID, "The chain the node belongs to")
+	rootCmd.Flags().String("consensus", node.DefaultConfig.Consensus, "The consensus engine validating the blocks (pow or poa)")
+	rootCmd.Flags().String("authorities", "", "The comma separated hex public keys of the genesis authorities of a poa chain")
+	rootCmd.Flags().String("validator-key-file", "", "The file of the hex encoded key signing the checkpoint votes of a poa authority")
+	rootCmd.Flags().String("listen", node.DefaultConfig.ListenAddr, "The address accepting the peer connections")
+	rootCmd.Flags().String("bootnodes", "", "The comma separated addresses of the nodes dialed first")
+	rootCmd.Flags().Int("max-peers", node.DefaultConfig.MaxPeers, "The maximum number of connected peers")
+	rootCmd.Flags().Int("target-outbound", node.DefaultConfig.TargetOutbound, "The number of outbound connections to maintain")
+	rootCmd.Flags().String("rpc-addr", node.DefaultConfig.RPCAddr, "The address of the JSON-RPC server, empty to disable it")
+	rootCmd.Flags().String("grpc-addr", node.DefaultConfig.GRPCAddr, "The address of the gRPC server, empty to disable it")
+	rootCmd.Flags().Int("health-min-peers", node.DefaultConfig.HealthMinPeers, "The minimum number of connected peers of a ready node")
+	rootCmd.Flags().Int("health-max-height-lag", node.DefaultConfig.HealthMaxHeightLag, "The maximum number of blocks a ready node is behind its best peer")
+	rootCmd.Flags().Duration("health-max-block-age", node.DefaultConfig.HealthMaxBlockAge, "The maximum age of the last block of a ready node, 0 to not check it")
+	rootCmd.Flags().String("log-level", node.DefaultConfig.LogLevel, "The minimum level of the logged messages (debug, info, warn or error)")
 }
 
-func GenerateKeyPairFullCycle() {
-	// Generate a random entropy (128 bits for 12 words or 160 bits for 15 words, 256 bits for 24 words)
-	entropy, err := bip39.NewEntropy(128) // 128 bits for 12 words, 160 bits for 15 words, 256 bits for 24 words
-	if err != nil {
-		fmt.Println("Error generating entropy:", err)
-		return
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
 	}
-	mnemonic, _ := crypto.GetMnemonicFromEntropy(entropy)
-
-	fmt.Println("entropy:", entropy)
-	fmt.Println("mnemonic:", mnemonic)
-
-	// Generate a new private key from the mnemonic
-	privateKey, _ := crypto.NewPrivateKeyfromMnemonic(mnemonic)
-	fmt.Println("private key:", privateKey)
-	publicKey := privateKey.PublicKey()
-	fmt.Println("public key:", publicKey)
-	address := publicKey.Address()
-	fmt.Println("address:", address)
 }
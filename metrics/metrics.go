@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/network"
+	"github.com/joaoh82/marvinblockchain/proto"
+)
+
+// namespace prefixes the names of the metrics
+const namespace = "marvin"
+
+// Path is the path the metrics are served under
+const Path = "/metrics"
+
+// Chain is the blockchain the metrics follow
+type Chain interface {
+	Height() int
+	FinalizedHeight() int
+	OnBlockProcessed(handler func(b *proto.Block, elapsed time.Duration, err error))
+}
+
+// Mempool is the mempool the metrics follow
+type Mempool interface {
+	Len() int
+	Bytes() int
+}
+
+// Network is the network server the metrics follow
+type Network interface {
+	Peers() []*network.Peer
+	Traffic() map[string]network.Traffic
+}
+
+// Store is the block store the metrics follow
+type Store interface {
+	Len() int
+	Size() int64
+}
+
+// Metrics exports the state of a node in the Prometheus format: the chain height, the block processing
+// latency, the rejected blocks by reason, the mempool size, the peers, the network traffic by message type
+// and the block store size
+type Metrics struct {
+	registry           *prometheus.Registry
+	blockProcessing    *prometheus.HistogramVec
+	validationFailures *prometheus.CounterVec
+}
+
+// New creates the metrics of the node components
+func New(chain Chain, mempool Mempool, server Network, store Store) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		blockProcessing: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "block_processing_seconds",
+			Help:      "Time taken to validate and add a block, by result (added or rejected).",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 14),
+		}, []string{"result"}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "block_validation_failures_total",
+			Help:      "Number of blocks rejected, by reason.",
+		}, []string{"reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.blockProcessing,
+		m.validationFailures,
+		gauge("chain_height", "Height of the last block of the blockchain.", func() float64 {
+			return float64(chain.Height())
+		}),
+		gauge("chain_finalized_height", "Height of the last finalized block.", func() float64 {
+			return float64(chain.FinalizedHeight())
+		}),
+		gauge("mempool_transactions", "Number of transactions in the mempool.", func() float64 {
+			return float64(mempool.Len())
+		}),
+		gauge("mempool_bytes", "Size of the transactions in the mempool in bytes.", func() float64 {
+			return float64(mempool.Bytes())
+		}),
+		gauge("peers", "Number of connected peers.", func() float64 {
+			return float64(len(server.Peers()))
+		}),
+		gauge("block_store_blocks", "Number of blocks in the block store.", func() float64 {
+			return float64(store.Len())
+		}),
+		gauge("block_store_bytes", "Size of the block store in bytes.", func() float64 {
+			return float64(store.Size())
+		}),
+		&trafficCollector{server: server},
+	)
+
+	chain.OnBlockProcessed(m.observeBlock)
+	return m
+}
+
+// Handler returns the handler serving the metrics to the Prometheus scrapes
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observeBlock records the processing time of the block and the reason it was rejected
+func (m *Metrics) observeBlock(b *proto.Block, elapsed time.Duration, err error) {
+	if err != nil {
+		m.blockProcessing.WithLabelValues("rejected").Observe(elapsed.Seconds())
+		m.validationFailures.WithLabelValues(core.RejectReason(err)).Inc()
+		return
+	}
+	m.blockProcessing.WithLabelValues("added").Observe(elapsed.Seconds())
+}
+
+// gauge creates a gauge reading its value from the function on every scrape
+func gauge(name string, help string, value func() float64) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, value)
+}
+
+var (
+	sentMessagesDesc = prometheus.NewDesc(namespace+"_network_sent_messages_total",
+		"Number of messages sent to the peers, by message type.", []string{"type"}, nil)
+	sentBytesDesc = prometheus.NewDesc(namespace+"_network_sent_bytes_total",
+		"Encoded size of the messages sent to the peers in bytes, by message type.", []string{"type"}, nil)
+	receivedMessagesDesc = prometheus.NewDesc(namespace+"_network_received_messages_total",
+		"Number of messages received from the peers, by message type.", []string{"type"}, nil)
+	receivedBytesDesc = prometheus.NewDesc(namespace+"_network_received_bytes_total",
+		"Encoded size of the messages received from the peers in bytes, by message type.", []string{"type"}, nil)
+)
+
+// trafficCollector exports the traffic counted by the network server
+type trafficCollector struct {
+	server Network
+}
+
+// Describe sends the descriptions of the traffic metrics
+func (c *trafficCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sentMessagesDesc
+	ch <- sentBytesDesc
+	ch <- receivedMessagesDesc
+	ch <- receivedBytesDesc
+}
+
+// Collect sends the traffic of every message type
+func (c *trafficCollector) Collect(ch chan<- prometheus.Metric) {
+	for msgType, traffic := range c.server.Traffic() {
+		ch <- prometheus.MustNewConstMetric(sentMessagesDesc, prometheus.CounterValue, float64(traffic.SentMessages), msgType)
+		ch <- prometheus.MustNewConstMetric(sentBytesDesc, prometheus.CounterValue, float64(traffic.SentBytes), msgType)
+		ch <- prometheus.MustNewConstMetric(receivedMessagesDesc, prometheus.CounterValue, float64(traffic.ReceivedMessages), msgType)
+		ch <- prometheus.MustNewConstMetric(receivedBytesDesc, prometheus.CounterValue, float64(traffic.ReceivedBytes), msgType)
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/network"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// testNetwork is a network server with no peers and fixed traffic
+type testNetwork struct{}
+
+func (testNetwork) Peers() []*network.Peer {
+	return nil
+}
+
+func (testNetwork) Traffic() map[string]network.Traffic {
+	return map[string]network.Traffic{"block": {SentMessages: 2, SentBytes: 300, ReceivedMessages: 1, ReceivedBytes: 150}}
+}
+
+func TestMetrics(t *testing.T) {
+	store, err := core.NewFileStore(t.TempDir() + "/blocks.dat")
+	assert.Nil(t, err)
+	defer store.Close()
+	bc, err := core.LoadBlockchain(store)
+	assert.Nil(t, err)
+	mempool := core.NewMempool()
+	m := New(bc, mempool, testNetwork{}, store)
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	block, err := bc.BuildBlock(producer, nil)
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	assert.Error(t, bc.AddBlock(block))
+
+	tx := &proto.Transaction{From: producer.PublicKey().Bytes(), To: make([]byte, 20), Value: 10, Fee: 1, Nonce: 1}
+	assert.NoError(t, types.SignTransaction(producer, tx))
+	assert.NoError(t, mempool.Add(tx))
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	assert.Contains(t, body, "marvin_chain_height 1\n")
+	assert.Contains(t, body, "marvin_mempool_transactions 1\n")
+	assert.Contains(t, body, "marvin_peers 0\n")
+	assert.Contains(t, body, "marvin_block_store_blocks 2\n")
+	assert.Contains(t, body, `marvin_block_processing_seconds_count{result="added"} 1`)
+	assert.Contains(t, body, `marvin_block_validation_failures_total{reason="known"} 1`)
+	assert.Contains(t, body, `marvin_network_sent_bytes_total{type="block"} 300`)
+	assert.Contains(t, body, `marvin_network_received_messages_total{type="block"} 1`)
+	assert.Contains(t, body, "marvin_mempool_bytes ")
+	assert.Contains(t, body, "marvin_block_store_bytes ")
+}
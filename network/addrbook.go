@@ -0,0 +1,334 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxAddressLength is the maximum length of an address accepted from a peer
+	maxAddressLength = 255
+	// maxAddresses is the number of addresses kept in the book, the oldest ones are evicted beyond it
+	maxAddresses = 2048
+	// maxConsecutiveFailures is the number of failed dials in a row after which an address is forgotten
+	maxConsecutiveFailures = 10
+	// maxRetryDelay is the longest delay before dialing again an address that failed
+	maxRetryDelay = time.Hour
+)
+
+// AddressEntry is an address of a node in the address book with the outcome of the past connections
+type AddressEntry struct {
+	Addr                string    `json:"addr"`
+	Bootnode            bool      `json:"bootnode,omitempty"`
+	Successes           int       `json:"successes"`
+	Failures            int       `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Added               time.Time `json:"added,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitempty"`
+	LastAttempt         time.Time `json:"last_attempt,omitempty"`
+}
+
+// Score returns the score of the address, a failure costs twice as much as a success earns
+func (e *AddressEntry) Score() int {
+	return e.Successes - 2*e.Failures
+}
+
+// lastSeen returns when the address was added or last connected to, whichever is the latest
+func (e *AddressEntry) lastSeen() time.Time {
+	if e.LastSuccess.After(e.Added) {
+		return e.LastSuccess
+	}
+	return e.Added
+}
+
+// retryAt returns when the address can be dialed again, backing off exponentially after consecutive failures
+func (e *AddressEntry) retryAt(base time.Duration) time.Time {
+	if e.ConsecutiveFailures == 0 || base <= 0 {
+		return e.LastAttempt
+	}
+	delay := base << uint(e.ConsecutiveFailures-1)
+	if delay > maxRetryDelay || delay < base {
+		delay = maxRetryDelay
+	}
+	return e.LastAttempt.Add(delay)
+}
+
+// AddressBook keeps the addresses of the known nodes scored by the outcome of the connections to them.
+// It holds up to maxAddresses addresses, evicting the least recently seen ones, bootnodes excepted.
+// When it has a path, the address book is loaded from and saved to a JSON file.
+type AddressBook struct {
+	lock    sync.Mutex
+	path    string
+	entries map[string]*AddressEntry
+	dirty   bool
+}
+
+// NewAddressBook creates an address book persisted at the path, loading the addresses saved there.
+// An empty path keeps the addresses in memory only.
+func NewAddressBook(path string) (*AddressBook, error) {
+	book := &AddressBook{
+		path:    path,
+		entries: make(map[string]*AddressEntry),
+	}
+	if path == "" {
+		return book, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return book, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*AddressEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, errors.New("failed to unmarshal address book")
+	}
+	for _, entry := range entries {
+		book.entries[entry.Addr] = entry
+	}
+	return book, nil
+}
+
+// Add adds the address to the book, returning false if it was already known or is not a dialable IP address and port
+func (b *AddressBook) Add(addr string) bool {
+	if err := validateAddress(addr); err != nil {
+		return false
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.add(addr)
+}
+
+// AddBootnode adds the address as a bootnode, which is never forgotten nor evicted. Bootnodes are configured
+// by the operator, so they are not validated like the addresses shared by the peers and can be host names.
+func (b *AddressBook) AddBootnode(addr string) {
+	if addr == "" || len(addr) > maxAddressLength {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.add(addr)
+	if entry, ok := b.entries[addr]; ok {
+		entry.Bootnode = true
+	}
+}
+
+// ValidateAddress checks that the address is an IP address and a port a remote node can dial,
+// the unspecified and loopback addresses are rejected
+func validateAddress(addr string) error {
+	if len(addr) > maxAddressLength {
+		return errors.New("address too long")
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid IP address (%s)", host)
+	}
+	if ip.IsUnspecified() || ip.IsLoopback() {
+		return fmt.Errorf("address (%s) is not dialable", addr)
+	}
+	if n, err := strconv.ParseUint(port, 10, 16); err != nil || n == 0 {
+		return fmt.Errorf("invalid port (%s)", port)
+	}
+	return nil
+}
+
+// add adds the address if it is not known, evicting the least recently seen address when the book is full.
+// The lock must be held.
+func (b *AddressBook) add(addr string) bool {
+	if _, ok := b.entries[addr]; ok {
+		return false
+	}
+	if len(b.entries) >= maxAddresses && !b.evict() {
+		return false
+	}
+	b.entries[addr] = &AddressEntry{Addr: addr, Added: time.Now()}
+	b.dirty = true
+	return true
+}
+
+// evict removes the least recently seen address which is not a bootnode, returning false if there is none.
+// The lock must be held.
+func (b *AddressBook) evict() bool {
+	var oldest *AddressEntry
+	for _, entry := range b.entries {
+		if entry.Bootnode {
+			continue
+		}
+		if oldest == nil || entry.lastSeen().Before(oldest.lastSeen()) {
+			oldest = entry
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	delete(b.entries, oldest.Addr)
+	return true
+}
+
+// Get returns a copy of the entry of the address
+func (b *AddressBook) Get(addr string) (AddressEntry, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	entry, ok := b.entries[addr]
+	if !ok {
+		return AddressEntry{}, false
+	}
+	return *entry, true
+}
+
+// Len returns the number of addresses in the book
+func (b *AddressBook) Len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return len(b.entries)
+}
+
+// MarkAttempt records a dial to the address
+func (b *AddressBook) MarkAttempt(addr string) {
+	b.update(addr, func(entry *AddressEntry) {
+		entry.LastAttempt = time.Now()
+	})
+}
+
+// MarkSuccess records a successful connection to the address
+func (b *AddressBook) MarkSuccess(addr string) {
+	b.update(addr, func(entry *AddressEntry) {
+		entry.Successes++
+		entry.ConsecutiveFailures = 0
+		entry.LastSuccess = time.Now()
+	})
+}
+
+// MarkFailure records a failed connection to the address, forgetting it after too many failures in a row
+func (b *AddressBook) MarkFailure(addr string) {
+	b.update(addr, func(entry *AddressEntry) {
+		entry.Failures++
+		entry.ConsecutiveFailures++
+	})
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if entry, ok := b.entries[addr]; ok && !entry.Bootnode && entry.ConsecutiveFailures >= maxConsecutiveFailures {
+		delete(b.entries, addr)
+	}
+}
+
+// Addresses returns up to n addresses, best scored first, leaving out the ones failing right now
+func (b *AddressBook) Addresses(n int) []string {
+	entries := b.sorted(func(entry *AddressEntry) bool {
+		return entry.ConsecutiveFailures == 0
+	})
+	return addresses(entries, n)
+}
+
+// Candidates returns up to n addresses to dial, best scored first. Excluded addresses and addresses
+// that failed recently are left out, the retry delay doubling with every failure in a row.
+func (b *AddressBook) Candidates(n int, exclude map[string]bool, retryDelay time.Duration) []string {
+	now := time.Now()
+	entries := b.sorted(func(entry *AddressEntry) bool {
+		return !exclude[entry.Addr] && !entry.retryAt(retryDelay).After(now)
+	})
+	return addresses(entries, n)
+}
+
+// Save writes the address book to its file, if it has a path and changed since the last save
+func (b *AddressBook) Save() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.path == "" || !b.dirty {
+		return nil
+	}
+
+	entries := make([]*AddressEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Addr < entries[j].Addr })
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return errors.New("failed to marshal address book")
+	}
+
+	if err := writeFileAtomic(b.path, data); err != nil {
+		return err
+	}
+	b.dirty = false
+	return nil
+}
+
+func (b *AddressBook) update(addr string, fn func(entry *AddressEntry)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	entry, ok := b.entries[addr]
+	if !ok {
+		return
+	}
+	fn(entry)
+	b.dirty = true
+}
+
+// sorted returns copies of the entries matching the filter, best scored first
+func (b *AddressBook) sorted(filter func(entry *AddressEntry) bool) []AddressEntry {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	entries := make([]AddressEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		if filter(entry) {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Score() != entries[j].Score() {
+			return entries[i].Score() > entries[j].Score()
+		}
+		return entries[i].Addr < entries[j].Addr
+	})
+	return entries
+}
+
+func addresses(entries []AddressEntry, n int) []string {
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	addrs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		addrs = append(addrs, entry.Addr)
+	}
+	return addrs
+}
+
+// writeFileAtomic writes to a temporary file first and renames it, so a crash never leaves a truncated file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
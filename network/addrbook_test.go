@@ -0,0 +1,119 @@
+package network
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressBookScoring(t *testing.T) {
+	book, err := NewAddressBook("")
+	assert.Nil(t, err)
+
+	assert.True(t, book.Add("10.0.0.1:3000"))
+	assert.True(t, book.Add("10.0.0.2:3000"))
+	assert.True(t, book.Add("10.0.0.3:3000"))
+	assert.False(t, book.Add("10.0.0.1:3000"))
+	assert.False(t, book.Add(""))
+	assert.Equal(t, 3, book.Len())
+
+	book.MarkSuccess("10.0.0.2:3000")
+	book.MarkSuccess("10.0.0.2:3000")
+	book.MarkFailure("10.0.0.3:3000")
+	entry, ok := book.Get("10.0.0.2:3000")
+	assert.True(t, ok)
+	assert.Equal(t, 2, entry.Score())
+
+	// Failing addresses are not shared
+	assert.Equal(t, []string{"10.0.0.2:3000", "10.0.0.1:3000"}, book.Addresses(10))
+	assert.Equal(t, []string{"10.0.0.2:3000"}, book.Addresses(1))
+}
+
+func TestAddressBookCandidates(t *testing.T) {
+	book, err := NewAddressBook("")
+	assert.Nil(t, err)
+	book.Add("10.0.0.1:3000")
+	book.Add("10.0.0.2:3000")
+
+	// An address that just failed waits for the retry delay
+	book.MarkAttempt("10.0.0.1:3000")
+	book.MarkFailure("10.0.0.1:3000")
+	assert.Equal(t, []string{"10.0.0.2:3000"}, book.Candidates(10, nil, time.Hour))
+	assert.Equal(t, []string{"10.0.0.2:3000", "10.0.0.1:3000"}, book.Candidates(10, nil, 0))
+	assert.Empty(t, book.Candidates(10, map[string]bool{"10.0.0.2:3000": true}, time.Hour))
+}
+
+func TestAddressBookForgetsFailingAddresses(t *testing.T) {
+	book, err := NewAddressBook("")
+	assert.Nil(t, err)
+	book.Add("10.0.0.1:3000")
+	book.AddBootnode("boot:1")
+
+	for i := 0; i < maxConsecutiveFailures; i++ {
+		book.MarkFailure("10.0.0.1:3000")
+		book.MarkFailure("boot:1")
+	}
+	_, ok := book.Get("10.0.0.1:3000")
+	assert.False(t, ok)
+
+	// Bootnodes are never forgotten
+	entry, ok := book.Get("boot:1")
+	assert.True(t, ok)
+	assert.True(t, entry.Bootnode)
+}
+
+func TestAddressBookRejectsInvalidAddresses(t *testing.T) {
+	book, err := NewAddressBook("")
+	assert.Nil(t, err)
+
+	// Only dialable IP addresses with a port are accepted from the peers
+	for _, addr := range []string{"10.0.0.1", "node:3000", "0.0.0.0:3000", "[::]:3000", "127.0.0.1:3000", "[::1]:3000", "10.0.0.1:0", "10.0.0.1:70000"} {
+		assert.False(t, book.Add(addr), addr)
+	}
+	assert.True(t, book.Add("[2001:db8::1]:3000"))
+
+	// Bootnodes are configured by the operator and can be host names
+	book.AddBootnode("localhost:3000")
+	assert.Equal(t, 2, book.Len())
+}
+
+func TestAddressBookEvictsOldestAddresses(t *testing.T) {
+	book, err := NewAddressBook("")
+	assert.Nil(t, err)
+	book.AddBootnode("boot:1")
+	for i := 1; book.Len() < maxAddresses; i++ {
+		assert.True(t, book.Add(fmt.Sprintf("10.0.%d.%d:3000", i/256, i%256)))
+	}
+	book.entries["boot:1"].Added = time.Now().Add(-2 * time.Hour)
+	book.entries["10.0.0.1:3000"].Added = time.Now().Add(-2 * time.Hour)
+	book.entries["10.0.0.2:3000"].Added = time.Now().Add(-time.Hour)
+	book.MarkSuccess("10.0.0.1:3000")
+
+	// The least recently seen address makes room for the new one, the bootnode and the connected address are kept
+	assert.True(t, book.Add("10.1.0.1:3000"))
+	assert.Equal(t, maxAddresses, book.Len())
+	_, ok := book.Get("10.0.0.2:3000")
+	assert.False(t, ok)
+	for _, addr := range []string{"boot:1", "10.0.0.1:3000", "10.1.0.1:3000"} {
+		_, ok := book.Get(addr)
+		assert.True(t, ok, addr)
+	}
+}
+
+func TestAddressBookPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers", "addrbook.json")
+	book, err := NewAddressBook(path)
+	assert.Nil(t, err)
+	book.Add("10.0.0.1:3000")
+	book.MarkSuccess("10.0.0.1:3000")
+	assert.NoError(t, book.Save())
+
+	loaded, err := NewAddressBook(path)
+	assert.Nil(t, err)
+	entry, ok := loaded.Get("10.0.0.1:3000")
+	assert.True(t, ok)
+	assert.Equal(t, 1, entry.Successes)
+}
@@ -0,0 +1,114 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// BanList keeps the node IDs and hosts banned until a given time.
+// When it has a path, the ban list is loaded from and saved to a JSON file so bans survive restarts.
+type BanList struct {
+	lock sync.Mutex
+	path string
+	bans map[string]time.Time
+}
+
+// NewBanList creates a ban list persisted at the path, loading the bans saved there that did not expire.
+// An empty path keeps the bans in memory only.
+func NewBanList(path string) (*BanList, error) {
+	list := &BanList{
+		path: path,
+		bans: make(map[string]time.Time),
+	}
+	if path == "" {
+		return list, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return list, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &list.bans); err != nil {
+		return nil, errors.New("failed to unmarshal ban list")
+	}
+	list.removeExpired(time.Now())
+	return list, nil
+}
+
+// Ban bans the key until the given time, keeping the later expiry if it is already banned
+func (l *BanList) Ban(key string, until time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if until.After(l.bans[key]) {
+		l.bans[key] = until
+	}
+}
+
+// Unban lifts the ban of the key
+func (l *BanList) Unban(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.bans, key)
+}
+
+// IsBanned returns true if the key is banned and the ban did not expire
+func (l *BanList) IsBanned(key string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	until, ok := l.bans[key]
+	if !ok {
+		return false
+	}
+	if !time.Now().Before(until) {
+		delete(l.bans, key)
+		return false
+	}
+	return true
+}
+
+// Bans returns the active bans with their expiry
+func (l *BanList) Bans() map[string]time.Time {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.removeExpired(time.Now())
+	bans := make(map[string]time.Time, len(l.bans))
+	for key, until := range l.bans {
+		bans[key] = until
+	}
+	return bans
+}
+
+// Save writes the active bans to the ban list file, if it has a path
+func (l *BanList) Save() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.path == "" {
+		return nil
+	}
+
+	l.removeExpired(time.Now())
+	data, err := json.MarshalIndent(l.bans, "", "  ")
+	if err != nil {
+		return errors.New("failed to marshal ban list")
+	}
+	return writeFileAtomic(l.path, data)
+}
+
+func (l *BanList) removeExpired(now time.Time) {
+	for key, until := range l.bans {
+		if !now.Before(until) {
+			delete(l.bans, key)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package network
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBanListExpiry(t *testing.T) {
+	list, err := NewBanList("")
+	assert.Nil(t, err)
+
+	list.Ban("a", time.Now().Add(time.Hour))
+	list.Ban("b", time.Now().Add(-time.Second))
+	assert.True(t, list.IsBanned("a"))
+	assert.False(t, list.IsBanned("b"))
+	assert.False(t, list.IsBanned("c"))
+
+	// A shorter ban does not shorten the current one
+	until := time.Now().Add(2 * time.Hour)
+	list.Ban("a", until)
+	list.Ban("a", time.Now().Add(time.Minute))
+	assert.Equal(t, until, list.Bans()["a"])
+
+	list.Unban("a")
+	assert.False(t, list.IsBanned("a"))
+	assert.Empty(t, list.Bans())
+}
+
+func TestBanListPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+	list, err := NewBanList(path)
+	assert.Nil(t, err)
+	list.Ban("a", time.Now().Add(time.Hour))
+	list.Ban("b", time.Now().Add(50*time.Millisecond))
+	assert.NoError(t, list.Save())
+
+	// The bans which expired in the meantime are not loaded
+	time.Sleep(100 * time.Millisecond)
+	loaded, err := NewBanList(path)
+	assert.Nil(t, err)
+	assert.True(t, loaded.IsBanned("a"))
+	assert.Len(t, loaded.Bans(), 1)
+}
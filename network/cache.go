@@ -0,0 +1,49 @@
+package network
+
+import "sync"
+
+// hashCache is a bounded set of hashes, forgetting the oldest hashes once full
+type hashCache struct {
+	lock     sync.Mutex
+	hashes   map[string]struct{}
+	order    []string
+	next     int
+	capacity int
+}
+
+func newHashCache(capacity int) *hashCache {
+	return &hashCache{
+		hashes:   make(map[string]struct{}, capacity),
+		order:    make([]string, 0, capacity),
+		capacity: capacity,
+	}
+}
+
+// Add adds the hash, returning false if it was already in the cache
+func (c *hashCache) Add(hash []byte) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := string(hash)
+	if _, ok := c.hashes[key]; ok {
+		return false
+	}
+	if len(c.order) < c.capacity {
+		c.order = append(c.order, key)
+	} else {
+		delete(c.hashes, c.order[c.next])
+		c.order[c.next] = key
+		c.next = (c.next + 1) % c.capacity
+	}
+	c.hashes[key] = struct{}{}
+	return true
+}
+
+// Has returns true if the hash is in the cache
+func (c *hashCache) Has(hash []byte) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, ok := c.hashes[string(hash)]
+	return ok
+}
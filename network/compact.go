@@ -0,0 +1,230 @@
+package network
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+const (
+	// shortIDSize is the size of the short transaction IDs of compact blocks
+	shortIDSize = 6
+	// maxPendingBlocks is the number of compact blocks waiting for their missing transactions at once
+	maxPendingBlocks = 16
+)
+
+// pendingBlock is a compact block waiting for the transactions missing from the mempool
+type pendingBlock struct {
+	peer    *Peer
+	block   *proto.Block
+	missing []uint32
+}
+
+// shortTxID returns the short ID of a transaction in a compact block. The ID is keyed by the block hash
+// and the salt, so two transactions colliding in one compact block do not collide in the others.
+func shortTxID(blockHash []byte, salt uint64, txHash []byte) []byte {
+	saltBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(saltBytes, salt)
+	hash := sha256.Sum256(bytes.Join([][]byte{blockHash, saltBytes, txHash}, nil))
+	return hash[:shortIDSize]
+}
+
+// newCompactBlock returns the compact block of the block, the coinbase transaction is prefilled as it is never in a mempool
+func newCompactBlock(b *proto.Block) (*proto.CompactBlock, error) {
+	blockHash, err := types.HashBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	compact := &proto.CompactBlock{
+		Header:    b.Header,
+		PublicKey: b.PublicKey,
+		Signature: b.Signature,
+		Salt:      binary.BigEndian.Uint64(salt),
+	}
+	for i, tx := range b.Transactions {
+		if types.IsCoinbase(tx) {
+			compact.Prefilled = append(compact.Prefilled, &proto.PrefilledTransaction{Index: uint32(i), Transaction: tx})
+			continue
+		}
+		txHash, err := types.HashTransaction(tx)
+		if err != nil {
+			return nil, err
+		}
+		compact.ShortIds = append(compact.ShortIds, shortTxID(blockHash, compact.Salt, txHash))
+	}
+	return compact, nil
+}
+
+// handleCompactBlock rebuilds a compact block with the transactions of the mempool,
+// and requests the transactions missing from the mempool from the peer
+func (g *Gossip) handleCompactBlock(peer *Peer, compact *proto.CompactBlock) {
+	if compact.Header == nil {
+		g.server.Penalize(peer, OffenceInvalidBlock)
+		return
+	}
+	hash, err := types.HashHeader(compact.Header)
+	if err != nil {
+		return
+	}
+	g.knownBy(peer).Add(hash)
+
+	// The height of the peer is recorded once the block is known valid, when the rebuilt block is added
+	if g.chain.HasBlockHash(hash) {
+		peer.SetHeight(compact.Header.GetHeight())
+		return
+	}
+
+	count := len(compact.ShortIds) + len(compact.Prefilled)
+	txs := make([]*proto.Transaction, count)
+	for _, prefilled := range compact.Prefilled {
+		if int(prefilled.Index) >= count || txs[prefilled.Index] != nil || prefilled.Transaction == nil {
+			g.server.Penalize(peer, OffenceInvalidBlock)
+			return
+		}
+		txs[prefilled.Index] = prefilled.Transaction
+	}
+
+	pool := g.mempoolShortIDs(hash, compact.Salt)
+	var missing []uint32
+	next := 0
+	for i := range txs {
+		if txs[i] != nil {
+			continue
+		}
+		if tx := pool[string(compact.ShortIds[next])]; tx != nil {
+			txs[i] = tx
+		} else {
+			missing = append(missing, uint32(i))
+		}
+		next++
+	}
+
+	b := &proto.Block{
+		Header:       compact.Header,
+		Transactions: txs,
+		PublicKey:    compact.PublicKey,
+		Signature:    compact.Signature,
+		Hash:         hash,
+	}
+	if len(missing) == 0 {
+		g.completeBlock(peer, b)
+		return
+	}
+
+	g.lock.Lock()
+	if len(g.pending) >= maxPendingBlocks {
+		g.lock.Unlock()
+		g.requestBlock(peer, hash)
+		return
+	}
+	g.pending[hex.EncodeToString(hash)] = &pendingBlock{peer: peer, block: b, missing: missing}
+	g.lock.Unlock()
+
+	peer.Send(&proto.Message{Payload: &proto.Message_BlockTransactionsRequest{BlockTransactionsRequest: &proto.BlockTransactionsRequest{
+		BlockHash: hash,
+		Indexes:   missing,
+	}}})
+}
+
+// handleBlockTransactionsRequest sends the requested transactions of a block
+func (g *Gossip) handleBlockTransactionsRequest(peer *Peer, request *proto.BlockTransactionsRequest) {
+	b, err := g.chain.GetBlockByHash(request.BlockHash)
+	if err != nil {
+		return
+	}
+	txs := make([]*proto.Transaction, 0, len(request.Indexes))
+	for _, index := range request.Indexes {
+		if int(index) >= len(b.Transactions) {
+			return
+		}
+		txs = append(txs, b.Transactions[index])
+	}
+
+	peer.Send(&proto.Message{Payload: &proto.Message_BlockTransactionsResponse{BlockTransactionsResponse: &proto.BlockTransactionsResponse{
+		BlockHash:    request.BlockHash,
+		Transactions: txs,
+	}}})
+}
+
+// handleBlockTransactionsResponse completes the pending compact block with the transactions sent by the peer
+func (g *Gossip) handleBlockTransactionsResponse(peer *Peer, response *proto.BlockTransactionsResponse) {
+	key := hex.EncodeToString(response.BlockHash)
+	g.lock.Lock()
+	pending, ok := g.pending[key]
+	if !ok || pending.peer != peer {
+		g.lock.Unlock()
+		return
+	}
+	delete(g.pending, key)
+	g.lock.Unlock()
+
+	if len(response.Transactions) != len(pending.missing) {
+		g.server.Penalize(peer, OffenceInvalidResponse)
+		return
+	}
+	for i, index := range pending.missing {
+		pending.block.Transactions[index] = response.Transactions[i]
+	}
+	g.completeBlock(peer, pending.block)
+}
+
+// completeBlock adds a rebuilt compact block. The full block is requested instead if the transactions
+// do not match the header, which happens when a short ID matches another transaction of the mempool.
+func (g *Gossip) completeBlock(peer *Peer, b *proto.Block) {
+	txHash, err := types.CalculateTxHash(b.Transactions)
+	if err != nil || !bytes.Equal(txHash, b.Header.TxHash) {
+		log.Debug().Str("peer", peer.ID()).Msg("compact block does not match its header, requesting the full block")
+		g.requestBlock(peer, b.Hash)
+		return
+	}
+	g.handleBlock(peer, b)
+}
+
+// requestBlock requests the full block with the hash from the peer
+func (g *Gossip) requestBlock(peer *Peer, hash []byte) {
+	peer.Send(&proto.Message{Payload: &proto.Message_GetData{GetData: &proto.GetData{
+		Type:   proto.InventoryType_INVENTORY_TYPE_BLOCK,
+		Hashes: [][]byte{hash},
+	}}})
+}
+
+// mempoolShortIDs returns the transactions of the mempool by their short ID in the block,
+// short IDs shared by several transactions are mapped to nil so they are requested from the peer
+func (g *Gossip) mempoolShortIDs(blockHash []byte, salt uint64) map[string]*proto.Transaction {
+	pending := g.mempool.Pending()
+	pool := make(map[string]*proto.Transaction, len(pending))
+	for _, tx := range pending {
+		txHash, err := types.HashTransaction(tx)
+		if err != nil {
+			continue
+		}
+		id := string(shortTxID(blockHash, salt, txHash))
+		if _, ok := pool[id]; ok {
+			pool[id] = nil
+			continue
+		}
+		pool[id] = tx
+	}
+	return pool
+}
+
+// removePending forgets the compact blocks waiting for transactions from the peer, the gossip lock must be held
+func (g *Gossip) removePending(peer *Peer) {
+	for key, pending := range g.pending {
+		if pending.peer == peer {
+			delete(g.pending, key)
+		}
+	}
+}
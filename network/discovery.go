@@ -0,0 +1,189 @@
+package network
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+)
+
+// maxAddressesPerMessage is the maximum number of addresses shared in a message
+const maxAddressesPerMessage = 100
+
+// DiscoveryConfig holds the peer discovery parameters
+type DiscoveryConfig struct {
+	// Bootnodes are the addresses of the nodes dialed first to join the network
+	Bootnodes []string
+	// TargetOutbound is the number of outbound connections the dialer maintains
+	TargetOutbound int
+	// DialInterval is the interval between the checks of the number of outbound connections,
+	// and the base delay before dialing again an address that failed
+	DialInterval time.Duration
+	// AddressBookPath is the file the address book is persisted to, empty to keep it in memory
+	AddressBookPath string
+}
+
+// DefaultDiscoveryConfig is the peer discovery configuration used when none is provided
+var DefaultDiscoveryConfig = DiscoveryConfig{
+	TargetOutbound: 8,
+	DialInterval:   5 * time.Second,
+}
+
+// Discovery finds the nodes of the network and keeps the server connected to them. It starts from the bootnodes,
+// asks every connected peer for the addresses it knows, and dials the best scored addresses of the address book
+// until the target number of outbound connections is reached.
+type Discovery struct {
+	server *Server
+	book   *AddressBook
+	config DiscoveryConfig
+
+	trigger chan struct{}
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewDiscovery creates the peer discovery of the server, loading the address book of the configuration
+func NewDiscovery(server *Server, config DiscoveryConfig) (*Discovery, error) {
+	book, err := NewAddressBook(config.AddressBookPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range config.Bootnodes {
+		book.AddBootnode(addr)
+	}
+
+	d := &Discovery{
+		server:  server,
+		book:    book,
+		config:  config,
+		trigger: make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+	}
+	server.OnMessage(d.handleMessage)
+	server.OnPeerConnected(d.handlePeerConnected)
+	server.OnPeerDisconnected(func(peer *Peer) {
+		if !peer.Inbound() {
+			d.Trigger()
+		}
+	})
+
+	return d, nil
+}
+
+// AddressBook returns the address book of the discovery
+func (d *Discovery) AddressBook() *AddressBook {
+	return d.book
+}
+
+// Start starts maintaining the outbound connections
+func (d *Discovery) Start() {
+	d.wg.Add(1)
+	go d.loop()
+}
+
+// Stop stops dialing and saves the address book
+func (d *Discovery) Stop() {
+	close(d.quit)
+	d.wg.Wait()
+	if err := d.book.Save(); err != nil {
+		log.Error().Err(err).Msg("failed to save address book")
+	}
+}
+
+// Trigger requests a dial round
+func (d *Discovery) Trigger() {
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// handlePeerConnected records the address of the new peer and asks it for the addresses it knows
+func (d *Discovery) handlePeerConnected(peer *Peer) {
+	d.book.Add(peer.DialAddr())
+	peer.Send(&proto.Message{Payload: &proto.Message_AddressesRequest{AddressesRequest: &proto.AddressesRequest{}}})
+}
+
+// handleMessage shares the known addresses with the peers and learns the addresses they share
+func (d *Discovery) handleMessage(peer *Peer, msg *proto.Message) {
+	switch payload := msg.Payload.(type) {
+	case *proto.Message_AddressesRequest:
+		addrs := d.book.Addresses(maxAddressesPerMessage)
+		peer.Send(&proto.Message{Payload: &proto.Message_AddressesResponse{AddressesResponse: &proto.AddressesResponse{
+			Addresses: addrs,
+		}}})
+	case *proto.Message_AddressesResponse:
+		addrs := payload.AddressesResponse.Addresses
+		if len(addrs) > maxAddressesPerMessage {
+			addrs = addrs[:maxAddressesPerMessage]
+		}
+		added := 0
+		for _, addr := range addrs {
+			if d.book.Add(addr) {
+				added++
+			}
+		}
+		if added > 0 {
+			log.Debug().Int("addresses", added).Str("peer", peer.ID()).Msg("addresses learned")
+			d.Trigger()
+		}
+	}
+}
+
+// loop dials new peers on every interval, or when triggered, until stopped
+func (d *Discovery) loop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.config.DialInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dialPeers()
+		if err := d.book.Save(); err != nil {
+			log.Error().Err(err).Msg("failed to save address book")
+		}
+
+		select {
+		case <-d.quit:
+			return
+		case <-ticker.C:
+		case <-d.trigger:
+		}
+	}
+}
+
+// dialPeers dials the best candidates of the address book in parallel until the target number of outbound connections is reached
+func (d *Discovery) dialPeers() {
+	peers := d.server.Peers()
+	exclude := map[string]bool{d.server.Addr(): true}
+	outbound := 0
+	for _, peer := range peers {
+		exclude[peer.DialAddr()] = true
+		if !peer.Inbound() {
+			outbound++
+		}
+	}
+	need := d.config.TargetOutbound - outbound
+	if need <= 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, addr := range d.book.Candidates(need, exclude, d.config.DialInterval) {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+
+			d.book.MarkAttempt(addr)
+			if _, err := d.server.Connect(addr); err != nil {
+				log.Debug().Err(err).Str("addr", addr).Msg("failed to dial peer")
+				d.book.MarkFailure(addr)
+				return
+			}
+			d.book.MarkSuccess(addr)
+		}(addr)
+	}
+	wg.Wait()
+}
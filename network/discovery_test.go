@@ -0,0 +1,78 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoveryFindsPeers(t *testing.T) {
+	network := NewLoopbackNetwork()
+	config := DiscoveryConfig{
+		Bootnodes:      []string{"boot"},
+		TargetOutbound: 4,
+		DialInterval:   20 * time.Millisecond,
+	}
+
+	newDiscoveryNode(t, network, "boot", DiscoveryConfig{TargetOutbound: 4, DialInterval: 20 * time.Millisecond})
+	nodes := make([]*Server, 3)
+	// The peers share the addresses they listen on, which must be dialable IP addresses
+	for i, addr := range []string{"10.0.0.1:3000", "10.0.0.2:3000", "10.0.0.3:3000"} {
+		nodes[i], _ = newDiscoveryNode(t, network, addr, config)
+	}
+
+	// Every node learns the others through the bootnode and connects to them
+	for _, node := range nodes {
+		node := node
+		assert.Eventually(t, func() bool { return len(node.Peers()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestDiscoveryScoresBootnodes(t *testing.T) {
+	network := NewLoopbackNetwork()
+	_, discovery := newDiscoveryNode(t, network, "a", DiscoveryConfig{
+		Bootnodes:      []string{"missing"},
+		TargetOutbound: 1,
+		DialInterval:   10 * time.Millisecond,
+	})
+
+	assert.Eventually(t, func() bool {
+		entry, ok := discovery.AddressBook().Get("missing")
+		return ok && entry.Failures > 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestPeerDialAddr(t *testing.T) {
+	network := NewLoopbackNetwork()
+	local, remote := newLoopbackPipe(network, "10.0.0.1:3000", "10.0.0.2:52000")
+	defer local.Close()
+	defer remote.Close()
+
+	// An inbound peer is dialed at the IP of its connection and the port it listens on, as it often listens on all interfaces
+	peer := newPeer(local, true, &proto.Handshake{ListenAddr: "0.0.0.0:4000"}, nil)
+	assert.Equal(t, "10.0.0.2:4000", peer.DialAddr())
+	peer = newPeer(local, true, &proto.Handshake{}, nil)
+	assert.Empty(t, peer.DialAddr())
+
+	// An outbound peer is dialed at the address it was dialed at
+	peer = newPeer(local, false, &proto.Handshake{ListenAddr: "0.0.0.0:4000"}, nil)
+	assert.Equal(t, "10.0.0.2:52000", peer.DialAddr())
+}
+
+// newDiscoveryNode starts a server with peer discovery on the loopback network
+func newDiscoveryNode(t *testing.T, network *LoopbackNetwork, addr string, config DiscoveryConfig) (*Server, *Discovery) {
+	server, err := NewServer(DefaultConfig, network.NewTransport(addr), core.NewBlockchain(core.NewMemorystore()))
+	assert.Nil(t, err)
+	discovery, err := NewDiscovery(server, config)
+	assert.Nil(t, err)
+	assert.NoError(t, server.Start())
+	discovery.Start()
+	t.Cleanup(func() {
+		discovery.Stop()
+		server.Stop()
+	})
+	return server, discovery
+}
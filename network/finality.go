@@ -0,0 +1,102 @@
+package network
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/consensus/finality"
+	"github.com/joaoh82/marvinblockchain/proto"
+)
+
+// Finality is the finality gadget tallying the checkpoint votes the vote relay propagates
+type Finality interface {
+	AddVote(vote *proto.CheckpointVote) (bool, error)
+	OnVote(handler func(vote *proto.CheckpointVote))
+}
+
+// VoteRelay propagates the checkpoint votes of the validators. Every vote tallied by the finality gadget,
+// signed by the node or received from a peer, is sent to the peers which do not know it yet.
+// Votes are small, so they are pushed in full instead of being announced in inventory messages.
+type VoteRelay struct {
+	server   *Server
+	finality Finality
+
+	lock  sync.Mutex
+	known map[string]*hashCache
+}
+
+// NewVoteRelay creates the vote relay on the server, propagating the votes tallied by the finality gadget
+func NewVoteRelay(server *Server, finality Finality) *VoteRelay {
+	r := &VoteRelay{
+		server:   server,
+		finality: finality,
+		known:    make(map[string]*hashCache),
+	}
+
+	server.OnPeerDisconnected(func(peer *Peer) {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		delete(r.known, peer.ID())
+	})
+	server.OnMessage(r.handleMessage)
+	finality.OnVote(r.relay)
+
+	return r
+}
+
+// relay sends the vote to the peers which do not know it
+func (r *VoteRelay) relay(vote *proto.CheckpointVote) {
+	id := voteID(vote)
+	msg := &proto.Message{Payload: &proto.Message_CheckpointVote{CheckpointVote: vote}}
+	for _, peer := range r.server.Peers() {
+		if !r.knownBy(peer).Add(id) {
+			continue
+		}
+		if err := peer.Send(msg); err != nil {
+			log.Debug().Err(err).Str("peer", peer.ID()).Msg("failed to relay checkpoint vote")
+		}
+	}
+}
+
+// handleMessage tallies the checkpoint votes received from a peer, which relays the new ones.
+// The peer is penalized if the vote can never be tallied.
+func (r *VoteRelay) handleMessage(peer *Peer, msg *proto.Message) {
+	payload, ok := msg.Payload.(*proto.Message_CheckpointVote)
+	if !ok {
+		return
+	}
+	vote := payload.CheckpointVote
+	r.knownBy(peer).Add(voteID(vote))
+
+	if _, err := r.finality.AddVote(vote); err != nil {
+		log.Debug().Err(err).Str("peer", peer.ID()).Msg("checkpoint vote rejected")
+		if errors.Is(err, finality.ErrInvalidVote) {
+			r.server.Penalize(peer, OffenceInvalidVote)
+		}
+	}
+}
+
+// knownBy returns the cache of the votes known by the peer
+func (r *VoteRelay) knownBy(peer *Peer) *hashCache {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	known, ok := r.known[peer.ID()]
+	if !ok {
+		known = newHashCache(knownCacheSize)
+		r.known[peer.ID()] = known
+	}
+	return known
+}
+
+// voteID identifies the vote of a validator for a checkpoint block
+func voteID(vote *proto.CheckpointVote) []byte {
+	hash := sha256.New()
+	hash.Write(finality.VoteHash(vote.Height, vote.BlockHash))
+	hash.Write(vote.PublicKey)
+	return hash.Sum(nil)
+}
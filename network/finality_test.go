@@ -0,0 +1,81 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/consensus/finality"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVoteRelayFinalizesCheckpoint(t *testing.T) {
+	nodes := newGossipLine(t, 3)
+	keys := make([]*crypto.PrivateKey, len(nodes))
+	validators := make(finality.StaticValidatorSet, len(nodes))
+	for i := range keys {
+		key, err := crypto.GeneratePrivateKey()
+		assert.Nil(t, err)
+		keys[i] = key
+		validators[i] = key.PublicKey().Bytes()
+	}
+	gadgets := make([]*finality.Gadget, len(nodes))
+	for i, node := range nodes {
+		gadget, err := finality.New(node.chain, validators, 2)
+		assert.Nil(t, err)
+		NewVoteRelay(node.server, gadget)
+		gadgets[i] = gadget
+	}
+
+	for i := 0; i < 2; i++ {
+		block, err := nodes[0].chain.BuildBlock(keys[0], nil)
+		assert.Nil(t, err)
+		assert.NoError(t, nodes[0].chain.AddBlock(block))
+	}
+	assert.Eventually(t, func() bool { return nodes[2].chain.Height() == 2 }, time.Second, 5*time.Millisecond)
+
+	// Every validator votes on its own node, the votes reach the other nodes through the middle one
+	for i, gadget := range gadgets {
+		_, err := gadget.Vote(keys[i])
+		assert.Nil(t, err)
+	}
+	for _, node := range nodes {
+		assert.Eventually(t, func() bool { return node.chain.FinalizedHeight() == 2 }, time.Second, 5*time.Millisecond)
+	}
+}
+
+func TestVoteRelayPenalizesInvalidVote(t *testing.T) {
+	nodes := newGossipLine(t, 2)
+	validator, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	outsider, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	validators := finality.StaticValidatorSet{validator.PublicKey().Bytes()}
+	for _, node := range nodes {
+		gadget, err := finality.New(node.chain, validators, 2)
+		assert.Nil(t, err)
+		NewVoteRelay(node.server, gadget)
+	}
+
+	for i := 0; i < 2; i++ {
+		block, err := nodes[0].chain.BuildBlock(validator, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, nodes[0].chain.AddBlock(block))
+	}
+	assert.Eventually(t, func() bool { return nodes[1].chain.Height() == 2 }, time.Second, 5*time.Millisecond)
+
+	// A vote from a node which is not a validator is refused and its sender penalized
+	header, err := nodes[0].chain.GetHeaderByHeight(2)
+	assert.Nil(t, err)
+	hash, err := types.HashHeader(header)
+	assert.Nil(t, err)
+	vote, err := finality.SignVote(outsider, 2, hash)
+	assert.Nil(t, err)
+	assert.NoError(t, nodes[0].server.Peers()[0].Send(&proto.Message{Payload: &proto.Message_CheckpointVote{CheckpointVote: vote}}))
+
+	peer := nodes[1].server.Peers()[0]
+	assert.Eventually(t, func() bool { return peer.Score() == OffenceInvalidVote.Penalty() }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 0, nodes[1].chain.FinalizedHeight())
+}
@@ -0,0 +1,368 @@
+package network
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+const (
+	// knownCacheSize is the number of hashes remembered as known by each peer
+	knownCacheSize = 4096
+	// maxInFlightRequests is the number of blocks and transactions requested from the peers at once
+	maxInFlightRequests = 4096
+	// requestTimeout is how long a requested block or transaction can take before it is requested from another peer announcing it
+	requestTimeout = 5 * time.Second
+)
+
+// GossipChain is the blockchain the gossip protocol relays the blocks of
+type GossipChain interface {
+	AddBlock(b *proto.Block) error
+	HasBlockHash(hash []byte) bool
+	GetBlockByHash(hash []byte) (*proto.Block, error)
+	OnBlockAdded(handler func(b *proto.Block))
+}
+
+// TxPool is the pool of transactions the gossip protocol relays
+type TxPool interface {
+	Add(tx *proto.Transaction) error
+	Get(hash []byte) *proto.Transaction
+	Pending() []*proto.Transaction
+	OnAdd(handler func(tx *proto.Transaction))
+}
+
+// Gossip propagates the blocks added to the blockchain and the transactions added to the mempool.
+// New blocks and transactions are announced by hash in inventory messages, and peers request the ones
+// they do not have with get-data messages. The hashes each peer knows are remembered so every block
+// and transaction is relayed once per peer. Blocks are requested as compact blocks, rebuilt from the
+// transactions of the mempool, so only the transactions missing from the mempool are transferred.
+type Gossip struct {
+	server  *Server
+	chain   GossipChain
+	mempool TxPool
+
+	lock           sync.Mutex
+	known          map[string]*hashCache
+	requests       map[string]*inFlightRequest
+	requestTimeout time.Duration
+	pending        map[string]*pendingBlock
+}
+
+// inFlightRequest is a block or transaction requested from a peer, with the other peers which announced it
+type inFlightRequest struct {
+	inventoryType proto.InventoryType
+	peer          *Peer
+	announcers    []*Peer
+	timer         *time.Timer
+}
+
+// NewGossip creates the gossip protocol on the server, relaying the blocks of the chain and the transactions of the mempool
+func NewGossip(server *Server, chain GossipChain, mempool TxPool) *Gossip {
+	g := &Gossip{
+		server:         server,
+		chain:          chain,
+		mempool:        mempool,
+		known:          make(map[string]*hashCache),
+		requests:       make(map[string]*inFlightRequest),
+		requestTimeout: requestTimeout,
+		pending:        make(map[string]*pendingBlock),
+	}
+
+	server.OnPeerDisconnected(func(peer *Peer) {
+		g.lock.Lock()
+		defer g.lock.Unlock()
+
+		delete(g.known, peer.ID())
+		g.removePending(peer)
+	})
+	server.OnMessage(g.handleMessage)
+	chain.OnBlockAdded(g.announceBlock)
+	mempool.OnAdd(g.announceTransaction)
+
+	return g
+}
+
+// announceBlock announces the block to the peers which do not know it
+func (g *Gossip) announceBlock(b *proto.Block) {
+	hash, err := types.HashBlock(b)
+	if err != nil {
+		return
+	}
+	g.completeRequest(hash)
+	g.announce(proto.InventoryType_INVENTORY_TYPE_BLOCK, hash)
+}
+
+// announceTransaction announces the transaction to the peers which do not know it
+func (g *Gossip) announceTransaction(tx *proto.Transaction) {
+	hash, err := types.HashTransaction(tx)
+	if err != nil {
+		return
+	}
+	g.completeRequest(hash)
+	g.announce(proto.InventoryType_INVENTORY_TYPE_TRANSACTION, hash)
+}
+
+func (g *Gossip) announce(inventoryType proto.InventoryType, hash []byte) {
+	msg := &proto.Message{Payload: &proto.Message_Inventory{Inventory: &proto.Inventory{
+		Type:   inventoryType,
+		Hashes: [][]byte{hash},
+	}}}
+	for _, peer := range g.server.Peers() {
+		if !g.knownBy(peer).Add(hash) {
+			continue
+		}
+		if err := peer.Send(msg); err != nil {
+			log.Debug().Err(err).Str("peer", peer.ID()).Msg("failed to announce inventory")
+		}
+	}
+}
+
+// handleMessage handles the gossip messages received from a peer
+func (g *Gossip) handleMessage(peer *Peer, msg *proto.Message) {
+	switch payload := msg.Payload.(type) {
+	case *proto.Message_Inventory:
+		g.handleInventory(peer, payload.Inventory)
+	case *proto.Message_GetData:
+		g.handleGetData(peer, payload.GetData)
+	case *proto.Message_Block:
+		g.handleBlock(peer, payload.Block)
+	case *proto.Message_Transaction:
+		g.handleTransaction(peer, payload.Transaction)
+	case *proto.Message_CompactBlock:
+		g.handleCompactBlock(peer, payload.CompactBlock)
+	case *proto.Message_BlockTransactionsRequest:
+		g.handleBlockTransactionsRequest(peer, payload.BlockTransactionsRequest)
+	case *proto.Message_BlockTransactionsResponse:
+		g.handleBlockTransactionsResponse(peer, payload.BlockTransactionsResponse)
+	}
+}
+
+// handleInventory requests the announced blocks and transactions that are not known yet.
+// The ones already requested from another peer are requested from this peer if the other peer does not answer in time.
+func (g *Gossip) handleInventory(peer *Peer, inventory *proto.Inventory) {
+	known := g.knownBy(peer)
+	missing := make([][]byte, 0, len(inventory.Hashes))
+	for _, hash := range inventory.Hashes {
+		known.Add(hash)
+		if g.has(inventory.Type, hash) || !g.addRequest(peer, inventory.Type, hash) {
+			continue
+		}
+		missing = append(missing, hash)
+	}
+	if len(missing) == 0 {
+		return
+	}
+	g.request(peer, inventory.Type, missing)
+}
+
+// request sends a get-data message for the hashes to the peer, blocks are requested as compact blocks
+func (g *Gossip) request(peer *Peer, inventoryType proto.InventoryType, hashes [][]byte) {
+	if inventoryType == proto.InventoryType_INVENTORY_TYPE_BLOCK {
+		inventoryType = proto.InventoryType_INVENTORY_TYPE_COMPACT_BLOCK
+	}
+	peer.Send(&proto.Message{Payload: &proto.Message_GetData{GetData: &proto.GetData{
+		Type:   inventoryType,
+		Hashes: hashes,
+	}}})
+}
+
+// addRequest records the hash as requested from the peer, returning false if it is already requested from
+// another peer, in which case the peer is remembered as an announcer to request it from after the timeout
+func (g *Gossip) addRequest(peer *Peer, inventoryType proto.InventoryType, hash []byte) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	key := string(hash)
+	if request, ok := g.requests[key]; ok {
+		if request.peer != peer && !containsPeer(request.announcers, peer) {
+			request.announcers = append(request.announcers, peer)
+		}
+		return false
+	}
+	if len(g.requests) >= maxInFlightRequests {
+		return false
+	}
+	g.requests[key] = &inFlightRequest{
+		inventoryType: inventoryType,
+		peer:          peer,
+		timer:         time.AfterFunc(g.requestTimeout, func() { g.retryRequest(hash) }),
+	}
+	return true
+}
+
+// retryRequest requests the hash from the next connected peer which announced it once the request timed out.
+// The request is forgotten when no other peer announced it, so the next announcement requests it again.
+func (g *Gossip) retryRequest(hash []byte) {
+	g.lock.Lock()
+	request, ok := g.requests[string(hash)]
+	g.lock.Unlock()
+	if !ok {
+		return
+	}
+	// The chain and the mempool are not called under the gossip lock, as they call the gossip when a block or a transaction is added
+	if g.has(request.inventoryType, hash) {
+		g.completeRequest(hash)
+		return
+	}
+
+	g.lock.Lock()
+	if g.requests[string(hash)] != request {
+		g.lock.Unlock()
+		return
+	}
+	var next *Peer
+	for next == nil && len(request.announcers) > 0 {
+		peer := request.announcers[0]
+		request.announcers = request.announcers[1:]
+		if g.server.Peer(peer.ID()) == peer {
+			next = peer
+		}
+	}
+	if next == nil {
+		delete(g.requests, string(hash))
+		g.lock.Unlock()
+		return
+	}
+	request.peer = next
+	request.timer.Reset(g.requestTimeout)
+	g.lock.Unlock()
+
+	log.Debug().Str("peer", next.ID()).Msg("request timed out, requesting from another peer")
+	g.request(next, request.inventoryType, [][]byte{hash})
+}
+
+// completeRequest forgets the request of the hash once the block or transaction is added
+func (g *Gossip) completeRequest(hash []byte) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if request, ok := g.requests[string(hash)]; ok {
+		request.timer.Stop()
+		delete(g.requests, string(hash))
+	}
+}
+
+func containsPeer(peers []*Peer, peer *Peer) bool {
+	for _, p := range peers {
+		if p == peer {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGetData sends the requested blocks and transactions
+func (g *Gossip) handleGetData(peer *Peer, request *proto.GetData) {
+	known := g.knownBy(peer)
+	for _, hash := range request.Hashes {
+		var msg *proto.Message
+		switch request.Type {
+		case proto.InventoryType_INVENTORY_TYPE_BLOCK:
+			block, err := g.chain.GetBlockByHash(hash)
+			if err != nil {
+				continue
+			}
+			msg = &proto.Message{Payload: &proto.Message_Block{Block: block}}
+		case proto.InventoryType_INVENTORY_TYPE_COMPACT_BLOCK:
+			block, err := g.chain.GetBlockByHash(hash)
+			if err != nil {
+				continue
+			}
+			compact, err := newCompactBlock(block)
+			if err != nil {
+				continue
+			}
+			msg = &proto.Message{Payload: &proto.Message_CompactBlock{CompactBlock: compact}}
+		case proto.InventoryType_INVENTORY_TYPE_TRANSACTION:
+			tx := g.mempool.Get(hash)
+			if tx == nil {
+				continue
+			}
+			msg = &proto.Message{Payload: &proto.Message_Transaction{Transaction: tx}}
+		default:
+			continue
+		}
+
+		known.Add(hash)
+		if err := peer.Send(msg); err != nil {
+			log.Debug().Err(err).Str("peer", peer.ID()).Msg("failed to send data")
+			return
+		}
+	}
+}
+
+// handleBlock adds a block received from a peer, which announces it to the other peers.
+// The peer is penalized if the block breaks the validation rules.
+func (g *Gossip) handleBlock(peer *Peer, b *proto.Block) {
+	hash, err := types.HashBlock(b)
+	if err != nil {
+		return
+	}
+	g.knownBy(peer).Add(hash)
+
+	// The height of the peer is only recorded once the block is known valid, so a peer cannot inflate it
+	if g.chain.HasBlockHash(hash) {
+		peer.SetHeight(b.Header.GetHeight())
+		return
+	}
+	if err := g.chain.AddBlock(b); err != nil {
+		log.Debug().Err(err).Str("peer", peer.ID()).Msg("gossiped block rejected")
+		if errors.Is(err, core.ErrInvalidBlock) {
+			g.server.Penalize(peer, OffenceInvalidBlock)
+		}
+		return
+	}
+	peer.SetHeight(b.Header.GetHeight())
+}
+
+// handleTransaction adds a transaction received from a peer to the mempool, which announces it to the other peers.
+// The peer is penalized if the transaction signature is invalid or the mempool finds it invalid against the state.
+func (g *Gossip) handleTransaction(peer *Peer, tx *proto.Transaction) {
+	hash, err := types.HashTransaction(tx)
+	if err != nil {
+		return
+	}
+	g.knownBy(peer).Add(hash)
+
+	if g.mempool.Get(hash) != nil {
+		return
+	}
+	if ok, err := types.VerifyTransaction(tx); err != nil || !ok {
+		log.Debug().Err(err).Str("peer", peer.ID()).Msg("gossiped transaction has an invalid signature")
+		g.server.Penalize(peer, OffenceInvalidTransaction)
+		return
+	}
+	if err := g.mempool.Add(tx); err != nil {
+		log.Debug().Err(err).Str("peer", peer.ID()).Msg("gossiped transaction rejected")
+		if errors.Is(err, core.ErrInvalidTransaction) {
+			g.server.Penalize(peer, OffenceInvalidTransaction)
+		}
+	}
+}
+
+// has returns true if the block or transaction with the hash is already known locally
+func (g *Gossip) has(inventoryType proto.InventoryType, hash []byte) bool {
+	if inventoryType == proto.InventoryType_INVENTORY_TYPE_BLOCK {
+		return g.chain.HasBlockHash(hash)
+	}
+	return g.mempool.Get(hash) != nil
+}
+
+// knownBy returns the cache of the hashes known by the peer
+func (g *Gossip) knownBy(peer *Peer) *hashCache {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	known, ok := g.known[peer.ID()]
+	if !ok {
+		known = newHashCache(knownCacheSize)
+		g.known[peer.ID()] = known
+	}
+	return known
+}
@@ -0,0 +1,287 @@
+package network
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// gossipNode is a node of the gossip tests
+type gossipNode struct {
+	server  *Server
+	gossip  *Gossip
+	chain   *core.Blockchain
+	mempool *core.Mempool
+}
+
+func TestGossipBlocks(t *testing.T) {
+	nodes := newGossipLine(t, 3)
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	// Count the block messages received by the middle node
+	var blocks, inventories atomic.Int32
+	nodes[1].server.OnMessage(func(peer *Peer, msg *proto.Message) {
+		switch msg.Payload.(type) {
+		case *proto.Message_CompactBlock:
+			blocks.Add(1)
+		case *proto.Message_Inventory:
+			inventories.Add(1)
+		}
+	})
+
+	for i := 1; i <= 3; i++ {
+		block, err := nodes[0].chain.BuildBlock(producer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, nodes[0].chain.AddBlock(block))
+
+		// The block reaches the last node through the middle one
+		assert.Eventually(t, func() bool { return nodes[2].chain.Height() == i }, time.Second, 5*time.Millisecond)
+	}
+
+	// Each block was announced and sent once to the middle node, the last node does not announce it back
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(3), blocks.Load())
+	assert.Equal(t, int32(3), inventories.Load())
+}
+
+func TestGossipInvalidBlockDoesNotRaisePeerHeight(t *testing.T) {
+	nodes := newGossipLine(t, 2)
+	peer := nodes[0].server.Peer(nodes[1].server.NodeID())
+	sent := nodes[1].server.Peer(nodes[0].server.NodeID())
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	valid, err := nodes[0].chain.BuildBlock(producer, nil)
+	assert.Nil(t, err)
+
+	// A block far ahead which is not added does not raise the height of the peer, the valid block sent after it does
+	invalid := &proto.Block{Header: &proto.Header{Height: 1000}}
+	assert.NoError(t, peer.Send(&proto.Message{Payload: &proto.Message_Block{Block: invalid}}))
+	assert.NoError(t, peer.Send(&proto.Message{Payload: &proto.Message_Block{Block: valid}}))
+	assert.Eventually(t, func() bool { return nodes[1].chain.Height() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, uint64(1), sent.Height())
+}
+
+func TestGossipInvalidCompactBlockDoesNotRaisePeerHeight(t *testing.T) {
+	nodes := newGossipLine(t, 2)
+	peer := nodes[0].server.Peer(nodes[1].server.NodeID())
+	sent := nodes[1].server.Peer(nodes[0].server.NodeID())
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	block, err := nodes[0].chain.BuildBlock(producer, nil)
+	assert.Nil(t, err)
+	valid, err := newCompactBlock(block)
+	assert.Nil(t, err)
+
+	// A compact header far ahead which is not added does not raise the height of the peer, the valid block sent after it does
+	invalid := &proto.CompactBlock{Header: &proto.Header{Height: 1000}}
+	assert.NoError(t, peer.Send(&proto.Message{Payload: &proto.Message_CompactBlock{CompactBlock: invalid}}))
+	assert.NoError(t, peer.Send(&proto.Message{Payload: &proto.Message_CompactBlock{CompactBlock: valid}}))
+	assert.Eventually(t, func() bool { return nodes[1].chain.Height() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, uint64(1), sent.Height())
+}
+
+func TestGossipTransactions(t *testing.T) {
+	sender, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	nodes := newGossipLine(t, 3, core.WithGenesisAlloc([]core.GenesisAccount{
+		{Address: sender.PublicKey().Address(), Balance: 1000},
+	}))
+
+	tx := &proto.Transaction{To: sender.PublicKey().Bytes(), Value: 1, Nonce: 1}
+	assert.Nil(t, types.SignTransaction(sender, tx))
+	assert.NoError(t, nodes[0].mempool.Add(tx))
+	assert.Eventually(t, func() bool { return nodes[2].mempool.Has(tx) }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, nodes[1].mempool.Len())
+
+	// A transaction with an invalid signature is not relayed, and the peer which sent it is penalized
+	invalid := &proto.Transaction{To: sender.PublicKey().Bytes(), Value: 1, Nonce: 2}
+	assert.Nil(t, types.SignTransaction(sender, invalid))
+	invalid.Value = 2
+	assert.NoError(t, nodes[0].mempool.Add(invalid))
+	peer := nodes[1].server.Peer(nodes[0].server.NodeID())
+	assert.Eventually(t, func() bool { return peer.Score() == OffenceInvalidTransaction.Penalty() }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, nodes[1].mempool.Len())
+}
+
+func TestGossipPenalizesTransactionsInvalidAgainstState(t *testing.T) {
+	sender, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	nodes := newGossipLine(t, 2, core.WithGenesisAlloc([]core.GenesisAccount{
+		{Address: sender.PublicKey().Address(), Balance: 1000},
+	}))
+	peer := nodes[0].server.Peer(nodes[1].server.NodeID())
+	sent := nodes[1].server.Peer(nodes[0].server.NodeID())
+
+	// A transaction the sender cannot pay for is rejected and the peer relaying it is penalized
+	tx := &proto.Transaction{To: sender.PublicKey().Bytes(), Value: 1001, Nonce: 1}
+	assert.Nil(t, types.SignTransaction(sender, tx))
+	assert.NoError(t, peer.Send(&proto.Message{Payload: &proto.Message_Transaction{Transaction: tx}}))
+	assert.Eventually(t, func() bool { return sent.Score() == OffenceInvalidTransaction.Penalty() }, time.Second, 5*time.Millisecond)
+
+	// A transaction whose nonce is already used may be an honest race with a block, it is rejected without a penalty
+	tx = &proto.Transaction{To: sender.PublicKey().Bytes(), Value: 1, Nonce: 1}
+	assert.Nil(t, types.SignTransaction(sender, tx))
+	block, err := nodes[0].chain.BuildBlock(sender, []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.NoError(t, nodes[0].chain.AddBlock(block))
+	assert.Eventually(t, func() bool { return nodes[1].chain.Height() == 1 }, time.Second, 5*time.Millisecond)
+
+	stale := &proto.Transaction{To: sender.PublicKey().Bytes(), Value: 2, Nonce: 1}
+	assert.Nil(t, types.SignTransaction(sender, stale))
+	assert.NoError(t, peer.Send(&proto.Message{Payload: &proto.Message_Transaction{Transaction: stale}}))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, OffenceInvalidTransaction.Penalty(), sent.Score())
+	assert.False(t, nodes[1].mempool.Has(stale))
+}
+
+func TestGossipCompactBlocks(t *testing.T) {
+	sender, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	nodes := newGossipLine(t, 2, core.WithGenesisAlloc([]core.GenesisAccount{
+		{Address: sender.PublicKey().Address(), Balance: 1000},
+	}))
+
+	var fullBlocks, requests atomic.Int32
+	nodes[1].server.OnMessage(func(peer *Peer, msg *proto.Message) {
+		if msg.GetBlock() != nil {
+			fullBlocks.Add(1)
+		}
+	})
+	nodes[0].server.OnMessage(func(peer *Peer, msg *proto.Message) {
+		if msg.GetBlockTransactionsRequest() != nil {
+			requests.Add(1)
+		}
+	})
+
+	// The transactions reached the mempool of the second node before the block, which is rebuilt from it
+	txs := make([]*proto.Transaction, 3)
+	for i := range txs {
+		txs[i] = &proto.Transaction{To: producer.PublicKey().Bytes(), Value: 1, Nonce: int64(i + 1)}
+		assert.Nil(t, types.SignTransaction(sender, txs[i]))
+		assert.NoError(t, nodes[0].mempool.Add(txs[i]))
+	}
+	assert.Eventually(t, func() bool { return nodes[1].mempool.Len() == 3 }, time.Second, 5*time.Millisecond)
+	block, err := nodes[0].chain.BuildBlock(producer, txs)
+	assert.Nil(t, err)
+	assert.NoError(t, nodes[0].chain.AddBlock(block))
+	assert.Eventually(t, func() bool { return nodes[1].chain.Height() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(0), requests.Load())
+
+	// A transaction missing from the mempool is requested from the peer
+	tx := &proto.Transaction{To: producer.PublicKey().Bytes(), Value: 1, Nonce: 4}
+	assert.Nil(t, types.SignTransaction(sender, tx))
+	block, err = nodes[0].chain.BuildBlock(producer, []*proto.Transaction{tx})
+	assert.Nil(t, err)
+	assert.NoError(t, nodes[0].chain.AddBlock(block))
+	assert.Eventually(t, func() bool { return nodes[1].chain.Height() == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, int32(0), fullBlocks.Load())
+}
+
+func TestGossipRetriesRequestFromAnotherAnnouncer(t *testing.T) {
+	sender, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	nodes := newGossipLine(t, 3, core.WithGenesisAlloc([]core.GenesisAccount{
+		{Address: sender.PublicKey().Address(), Balance: 1000},
+	}))
+	nodes[1].gossip.requestTimeout = 50 * time.Millisecond
+	tx := &proto.Transaction{To: sender.PublicKey().Bytes(), Value: 1, Nonce: 1}
+	assert.Nil(t, types.SignTransaction(sender, tx))
+	hash, err := types.HashTransaction(tx)
+	assert.Nil(t, err)
+
+	// The last node has the transaction without announcing it
+	nodes[2].gossip.knownBy(nodes[2].server.Peer(nodes[1].server.NodeID())).Add(hash)
+	assert.NoError(t, nodes[2].mempool.Add(tx))
+
+	// The first announcer does not have the transaction, it is requested from the second one after the timeout
+	inventory := &proto.Inventory{Type: proto.InventoryType_INVENTORY_TYPE_TRANSACTION, Hashes: [][]byte{hash}}
+	nodes[1].gossip.handleInventory(nodes[1].server.Peer(nodes[0].server.NodeID()), inventory)
+	nodes[1].gossip.handleInventory(nodes[1].server.Peer(nodes[2].server.NodeID()), inventory)
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, nodes[1].mempool.Has(tx))
+	assert.Eventually(t, func() bool { return nodes[1].mempool.Has(tx) }, time.Second, 5*time.Millisecond)
+
+	nodes[1].gossip.lock.Lock()
+	defer nodes[1].gossip.lock.Unlock()
+	assert.Empty(t, nodes[1].gossip.requests)
+}
+
+func TestCompactBlockShortIDs(t *testing.T) {
+	sender, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	tx := &proto.Transaction{To: sender.PublicKey().Bytes(), Value: 1, Nonce: 1}
+	assert.Nil(t, types.SignTransaction(sender, tx))
+	coinbase, err := types.NewCoinbaseTransaction(sender.PublicKey().Bytes(), 10, 1)
+	assert.Nil(t, err)
+	block := &proto.Block{Header: &proto.Header{Height: 1}, Transactions: []*proto.Transaction{coinbase, tx}}
+
+	// The coinbase transaction is prefilled and the other transactions are sent as short IDs
+	compact, err := newCompactBlock(block)
+	assert.Nil(t, err)
+	assert.Len(t, compact.Prefilled, 1)
+	assert.Equal(t, uint32(0), compact.Prefilled[0].Index)
+	assert.Len(t, compact.ShortIds, 1)
+	assert.Len(t, compact.ShortIds[0], shortIDSize)
+
+	// The short IDs depend on the salt
+	other, err := newCompactBlock(block)
+	assert.Nil(t, err)
+	assert.NotEqual(t, compact.Salt, other.Salt)
+	assert.NotEqual(t, compact.ShortIds[0], other.ShortIds[0])
+}
+
+func TestHashCache(t *testing.T) {
+	cache := newHashCache(2)
+	assert.True(t, cache.Add([]byte("a")))
+	assert.False(t, cache.Add([]byte("a")))
+	assert.True(t, cache.Add([]byte("b")))
+	assert.True(t, cache.Add([]byte("c")))
+
+	// The oldest hash is forgotten once the cache is full
+	assert.False(t, cache.Has([]byte("a")))
+	assert.True(t, cache.Has([]byte("b")))
+	assert.True(t, cache.Has([]byte("c")))
+}
+
+// newGossipLine creates nodes on a loopback network, each node connected to the next one
+func newGossipLine(t *testing.T, n int, opts ...core.Option) []*gossipNode {
+	network := NewLoopbackNetwork()
+	nodes := make([]*gossipNode, n)
+	for i := range nodes {
+		node := &gossipNode{
+			chain: core.NewBlockchain(core.NewMemorystore(), opts...),
+		}
+		node.mempool = core.NewMempool(core.WithMempoolState(node.chain.State()))
+		server, err := NewServer(DefaultConfig, network.NewTransport(string(rune('a'+i))), node.chain)
+		assert.Nil(t, err)
+		node.server = server
+		node.gossip = NewGossip(server, node.chain, node.mempool)
+		assert.NoError(t, server.Start())
+		t.Cleanup(server.Stop)
+		nodes[i] = node
+	}
+
+	for i := 1; i < n; i++ {
+		_, err := nodes[i].server.Connect(nodes[i-1].server.Addr())
+		assert.Nil(t, err)
+	}
+	for i := range nodes {
+		expected := 2
+		if i == 0 || i == n-1 {
+			expected = 1
+		}
+		assert.Eventually(t, func() bool { return len(nodes[i].server.Peers()) == expected }, time.Second, 5*time.Millisecond)
+	}
+	return nodes
+}
@@ -0,0 +1,53 @@
+package network
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// ProtocolVersion is the version of the network protocol, nodes only connect to nodes on the same version
+const ProtocolVersion = 1
+
+// localHandshake returns the handshake describing this node and its chain
+func (s *Server) localHandshake() (*proto.Handshake, error) {
+	genesis, err := s.chain.GetHeaderByHeight(0)
+	if err != nil {
+		return nil, err
+	}
+	genesisHash, err := types.HashHeader(genesis)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.Handshake{
+		Version:     ProtocolVersion,
+		ChainId:     s.config.ChainID,
+		GenesisHash: genesisHash,
+		Height:      uint64(s.chain.Height()),
+		NodeId:      s.nodeID,
+		ListenAddr:  s.transport.Addr(),
+	}, nil
+}
+
+// verifyHandshake checks that the remote node speaks the same protocol on the same chain
+func verifyHandshake(local *proto.Handshake, remote *proto.Handshake) error {
+	if remote.Version != local.Version {
+		return fmt.Errorf("protocol version %d is not supported, expected %d", remote.Version, local.Version)
+	}
+	if remote.ChainId != local.ChainId {
+		return fmt.Errorf("chain ID (%s) does not match (%s)", remote.ChainId, local.ChainId)
+	}
+	if !bytes.Equal(remote.GenesisHash, local.GenesisHash) {
+		return fmt.Errorf("genesis hash does not match")
+	}
+	if len(remote.NodeId) == 0 {
+		return fmt.Errorf("missing node ID")
+	}
+	if bytes.Equal(remote.NodeId, local.NodeId) {
+		return fmt.Errorf("connection to self")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package network
+
+import (
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// checkLimits checks the blocks and the transactions carried by a message received from a peer against
+// the consensus limits of the chain, and returns the offence of the peer when they are exceeded
+func checkLimits(msg *proto.Message, params types.ConsensusParams) (Offence, error) {
+	switch payload := msg.Payload.(type) {
+	case *proto.Message_Block:
+		if err := params.ValidateBlock(payload.Block); err != nil {
+			return OffenceInvalidBlock, err
+		}
+	case *proto.Message_BlocksResponse:
+		for _, b := range payload.BlocksResponse.GetBlocks() {
+			if err := params.ValidateBlock(b); err != nil {
+				return OffenceInvalidBlock, err
+			}
+		}
+	case *proto.Message_CompactBlock:
+		compact := payload.CompactBlock
+		if len(compact.GetShortIds())+len(compact.GetPrefilled()) > params.MaxBlockTransactions {
+			return OffenceInvalidBlock, types.ErrTooManyTransactions
+		}
+		for _, prefilled := range compact.GetPrefilled() {
+			if err := params.ValidateTransaction(prefilled.GetTransaction()); err != nil {
+				return OffenceInvalidBlock, err
+			}
+		}
+	case *proto.Message_BlockTransactionsResponse:
+		if len(payload.BlockTransactionsResponse.GetTransactions()) > params.MaxBlockTransactions {
+			return OffenceInvalidResponse, types.ErrTooManyTransactions
+		}
+		for _, tx := range payload.BlockTransactionsResponse.GetTransactions() {
+			if err := params.ValidateTransaction(tx); err != nil {
+				return OffenceInvalidTransaction, err
+			}
+		}
+	case *proto.Message_Transaction:
+		if err := params.ValidateTransaction(payload.Transaction); err != nil {
+			return OffenceInvalidTransaction, err
+		}
+	}
+	return 0, nil
+}
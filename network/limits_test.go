@@ -0,0 +1,44 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckLimits(t *testing.T) {
+	params := types.ConsensusParams{MaxBlockSize: 1024, MaxBlockTransactions: 2, MaxTxDataSize: 8}
+	tx := &proto.Transaction{Data: []byte("data")}
+	oversized := &proto.Transaction{Data: []byte("too much data")}
+	block := &proto.Block{Header: &proto.Header{Height: 1}, Transactions: []*proto.Transaction{tx}}
+
+	_, err := checkLimits(&proto.Message{Payload: &proto.Message_Block{Block: block}}, params)
+	assert.NoError(t, err)
+	_, err = checkLimits(&proto.Message{Payload: &proto.Message_Transaction{Transaction: tx}}, params)
+	assert.NoError(t, err)
+
+	offence, err := checkLimits(&proto.Message{Payload: &proto.Message_Transaction{Transaction: oversized}}, params)
+	assert.ErrorIs(t, err, types.ErrTxDataTooLarge)
+	assert.Equal(t, OffenceInvalidTransaction, offence)
+
+	large := &proto.Block{Header: &proto.Header{Height: 1}, Transactions: []*proto.Transaction{tx, tx, tx}}
+	offence, err = checkLimits(&proto.Message{Payload: &proto.Message_BlocksResponse{
+		BlocksResponse: &proto.BlocksResponse{Blocks: []*proto.Block{block, large}},
+	}}, params)
+	assert.ErrorIs(t, err, types.ErrTooManyTransactions)
+	assert.Equal(t, OffenceInvalidBlock, offence)
+
+	offence, err = checkLimits(&proto.Message{Payload: &proto.Message_CompactBlock{CompactBlock: &proto.CompactBlock{
+		Prefilled: []*proto.PrefilledTransaction{{Transaction: oversized}},
+	}}}, params)
+	assert.ErrorIs(t, err, types.ErrTxDataTooLarge)
+	assert.Equal(t, OffenceInvalidBlock, offence)
+
+	offence, err = checkLimits(&proto.Message{Payload: &proto.Message_BlockTransactionsResponse{
+		BlockTransactionsResponse: &proto.BlockTransactionsResponse{Transactions: []*proto.Transaction{{To: make([]byte, 2048)}}},
+	}}, params)
+	assert.ErrorIs(t, err, types.ErrTxTooLarge)
+	assert.Equal(t, OffenceInvalidTransaction, offence)
+}
@@ -0,0 +1,304 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+const (
+	// loopbackQueueSize is the number of messages in flight on a loopback connection
+	loopbackQueueSize = 1024
+	// loopbackBacklog is the number of dialed connections waiting to be accepted
+	loopbackBacklog = 64
+)
+
+// LoopbackNetwork connects loopback transports in memory. It simulates the network conditions between them:
+// every message is delayed by the latency, lost with the packet loss probability,
+// and dropped between transports in different partitions.
+type LoopbackNetwork struct {
+	lock       sync.Mutex
+	transports map[string]*LoopbackTransport
+	latency    time.Duration
+	packetLoss float64
+	partitions map[string]int
+	rand       *rand.Rand
+}
+
+// NewLoopbackNetwork creates an in-memory network without latency, packet loss or partitions
+func NewLoopbackNetwork() *LoopbackNetwork {
+	return &LoopbackNetwork{
+		transports: make(map[string]*LoopbackTransport),
+		partitions: make(map[string]int),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetLatency sets the delay of every message
+func (n *LoopbackNetwork) SetLatency(latency time.Duration) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.latency = latency
+}
+
+// SetPacketLoss sets the probability, between 0 and 1, of a message being lost
+func (n *LoopbackNetwork) SetPacketLoss(probability float64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.packetLoss = probability
+}
+
+// Partition splits the network into the given groups of addresses. Transports in different groups cannot
+// connect or exchange messages, the transports not listed in any group form a group of their own.
+func (n *LoopbackNetwork) Partition(groups ...[]string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.partitions = make(map[string]int)
+	for i, group := range groups {
+		for _, addr := range group {
+			n.partitions[addr] = i + 1
+		}
+	}
+}
+
+// Heal removes the partitions
+func (n *LoopbackNetwork) Heal() {
+	n.Partition()
+}
+
+// NewTransport creates a transport accepting connections on the address of the network
+func (n *LoopbackNetwork) NewTransport(addr string) *LoopbackTransport {
+	return &LoopbackTransport{
+		network: n,
+		addr:    addr,
+		accept:  make(chan Conn, loopbackBacklog),
+		closed:  make(chan struct{}),
+	}
+}
+
+// reachable returns true if the transports at the addresses are in the same partition
+func (n *LoopbackNetwork) reachable(from string, to string) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.partitions[from] == n.partitions[to]
+}
+
+// deliver decides if a message from an address to another is delivered, and after which delay
+func (n *LoopbackNetwork) deliver(from string, to string) (time.Duration, bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.partitions[from] != n.partitions[to] {
+		return 0, false
+	}
+	if n.packetLoss > 0 && n.rand.Float64() < n.packetLoss {
+		return 0, false
+	}
+	return n.latency, true
+}
+
+func (n *LoopbackNetwork) register(t *LoopbackTransport) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if _, ok := n.transports[t.addr]; ok {
+		return fmt.Errorf("address (%s) already in use", t.addr)
+	}
+	n.transports[t.addr] = t
+	return nil
+}
+
+func (n *LoopbackNetwork) unregister(t *LoopbackTransport) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.transports[t.addr] == t {
+		delete(n.transports, t.addr)
+	}
+}
+
+func (n *LoopbackNetwork) transport(addr string) *LoopbackTransport {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.transports[addr]
+}
+
+// LoopbackTransport is a transport connecting nodes of a loopback network through channels
+type LoopbackTransport struct {
+	network   *LoopbackNetwork
+	addr      string
+	accept    chan Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Listen registers the transport on the network so other transports can dial it
+func (t *LoopbackTransport) Listen() error {
+	return t.network.register(t)
+}
+
+// Accept waits for the next inbound connection
+func (t *LoopbackTransport) Accept() (Conn, error) {
+	select {
+	case conn := <-t.accept:
+		return conn, nil
+	case <-t.closed:
+		return nil, ErrTransportClosed
+	}
+}
+
+// Dial connects to the transport listening on the address
+func (t *LoopbackTransport) Dial(addr string) (Conn, error) {
+	select {
+	case <-t.closed:
+		return nil, ErrTransportClosed
+	default:
+	}
+
+	remote := t.network.transport(addr)
+	if remote == nil || !t.network.reachable(t.addr, addr) {
+		return nil, fmt.Errorf("dial (%s): connection refused", addr)
+	}
+
+	local, inbound := newLoopbackPipe(t.network, t.addr, addr)
+	select {
+	case remote.accept <- inbound:
+		return local, nil
+	case <-remote.closed:
+		local.Close()
+		return nil, fmt.Errorf("dial (%s): connection refused", addr)
+	}
+}
+
+// Addr returns the address of the transport on the network
+func (t *LoopbackTransport) Addr() string {
+	return t.addr
+}
+
+// Close stops accepting connections and frees the address
+func (t *LoopbackTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		t.network.unregister(t)
+	})
+	return nil
+}
+
+// loopbackPipe holds the state shared by both ends of a loopback connection
+type loopbackPipe struct {
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// delivery is a message in flight, delivered at the given time
+type delivery struct {
+	msg *proto.Message
+	at  time.Time
+}
+
+// loopbackConn is one end of a loopback connection
+type loopbackConn struct {
+	network *LoopbackNetwork
+	pipe    *loopbackPipe
+	local   string
+	remote  string
+	inbox   chan *proto.Message
+	outbox  chan delivery
+	peer    *loopbackConn
+}
+
+// newLoopbackPipe creates both ends of a connection from the local to the remote address
+func newLoopbackPipe(network *LoopbackNetwork, local string, remote string) (*loopbackConn, *loopbackConn) {
+	pipe := &loopbackPipe{closed: make(chan struct{})}
+	a := &loopbackConn{network: network, pipe: pipe, local: local, remote: remote,
+		inbox: make(chan *proto.Message, loopbackQueueSize), outbox: make(chan delivery, loopbackQueueSize)}
+	b := &loopbackConn{network: network, pipe: pipe, local: remote, remote: local,
+		inbox: make(chan *proto.Message, loopbackQueueSize), outbox: make(chan delivery, loopbackQueueSize)}
+	a.peer, b.peer = b, a
+
+	go a.deliverLoop()
+	go b.deliverLoop()
+	return a, b
+}
+
+// Send queues a copy of the message for delivery, unless the network loses it
+func (c *loopbackConn) Send(msg *proto.Message) error {
+	select {
+	case <-c.pipe.closed:
+		return errors.New("connection closed")
+	default:
+	}
+	if size := pb.Size(msg); size > MaxMessageSize {
+		return fmt.Errorf("message size %d exceeds the maximum of %d", size, MaxMessageSize)
+	}
+
+	latency, ok := c.network.deliver(c.local, c.remote)
+	if !ok {
+		return nil
+	}
+	select {
+	case c.outbox <- delivery{msg: pb.Clone(msg).(*proto.Message), at: time.Now().Add(latency)}:
+		return nil
+	case <-c.pipe.closed:
+		return errors.New("connection closed")
+	}
+}
+
+// Receive waits for the next message delivered to this end
+func (c *loopbackConn) Receive() (*proto.Message, error) {
+	select {
+	case msg := <-c.inbox:
+		return msg, nil
+	case <-c.pipe.closed:
+		return nil, io.EOF
+	}
+}
+
+// RemoteAddr returns the address of the other end
+func (c *loopbackConn) RemoteAddr() string {
+	return c.remote
+}
+
+// Close closes both ends of the connection
+func (c *loopbackConn) Close() error {
+	c.pipe.closeOnce.Do(func() {
+		close(c.pipe.closed)
+	})
+	return nil
+}
+
+// deliverLoop moves the sent messages to the inbox of the other end once their latency has passed, in order
+func (c *loopbackConn) deliverLoop() {
+	for {
+		select {
+		case <-c.pipe.closed:
+			return
+		case d := <-c.outbox:
+			if wait := time.Until(d.at); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-c.pipe.closed:
+					timer.Stop()
+					return
+				}
+			}
+			select {
+			case c.peer.inbox <- d.msg:
+			case <-c.pipe.closed:
+				return
+			}
+		}
+	}
+}
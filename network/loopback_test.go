@@ -0,0 +1,139 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoopbackTransport(t *testing.T) {
+	network := NewLoopbackNetwork()
+	a := network.NewTransport("a")
+	b := network.NewTransport("b")
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+	defer a.Close()
+	defer b.Close()
+
+	// The address can only be used once
+	assert.Error(t, network.NewTransport("a").Listen())
+
+	conn, err := b.Dial("a")
+	assert.Nil(t, err)
+	inbound, err := a.Accept()
+	assert.Nil(t, err)
+	assert.Equal(t, "a", conn.RemoteAddr())
+	assert.Equal(t, "b", inbound.RemoteAddr())
+
+	for i := uint64(1); i <= 3; i++ {
+		assert.NoError(t, conn.Send(pingMessage(i)))
+	}
+	for i := uint64(1); i <= 3; i++ {
+		msg, err := inbound.Receive()
+		assert.Nil(t, err)
+		assert.Equal(t, i, msg.GetPing().Nonce)
+	}
+
+	// Closing one end closes the other
+	conn.Close()
+	_, err = inbound.Receive()
+	assert.Error(t, err)
+	assert.Error(t, inbound.Send(pingMessage(4)))
+
+	_, err = b.Dial("unknown")
+	assert.Error(t, err)
+}
+
+func TestLoopbackLatency(t *testing.T) {
+	network := NewLoopbackNetwork()
+	network.SetLatency(50 * time.Millisecond)
+	conn, inbound := dialLoopback(t, network, "a", "b")
+
+	start := time.Now()
+	assert.NoError(t, conn.Send(pingMessage(1)))
+	_, err := inbound.Receive()
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestLoopbackPacketLoss(t *testing.T) {
+	network := NewLoopbackNetwork()
+	conn, inbound := dialLoopback(t, network, "a", "b")
+
+	network.SetPacketLoss(1)
+	assert.NoError(t, conn.Send(pingMessage(1)))
+	network.SetPacketLoss(0)
+	assert.NoError(t, conn.Send(pingMessage(2)))
+
+	msg, err := inbound.Receive()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), msg.GetPing().Nonce)
+}
+
+func TestLoopbackPartition(t *testing.T) {
+	network := NewLoopbackNetwork()
+	conn, inbound := dialLoopback(t, network, "a", "b")
+	c := network.NewTransport("c")
+	assert.NoError(t, c.Listen())
+	defer c.Close()
+
+	network.Partition([]string{"a"}, []string{"b", "c"})
+	_, err := c.Dial("a")
+	assert.Error(t, err)
+	assert.NoError(t, conn.Send(pingMessage(1)))
+
+	network.Heal()
+	assert.NoError(t, conn.Send(pingMessage(2)))
+	msg, err := inbound.Receive()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), msg.GetPing().Nonce)
+}
+
+func TestServerOverLoopback(t *testing.T) {
+	network := NewLoopbackNetwork()
+	network.SetLatency(time.Millisecond)
+	servers := make([]*Server, 3)
+	for i, addr := range []string{"a", "b", "c"} {
+		server, err := NewServer(DefaultConfig, network.NewTransport(addr), core.NewBlockchain(core.NewMemorystore()))
+		assert.Nil(t, err)
+		assert.NoError(t, server.Start())
+		defer server.Stop()
+		servers[i] = server
+	}
+
+	_, err := servers[1].Connect("a")
+	assert.Nil(t, err)
+	_, err = servers[2].Connect("a")
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return len(servers[0].Peers()) == 2 }, time.Second, 5*time.Millisecond)
+
+	// A partitioned node cannot connect
+	network.Partition([]string{"c"})
+	_, err = servers[2].Connect("b")
+	assert.Error(t, err)
+}
+
+// dialLoopback connects a transport at the from address to one at the to address and returns both ends
+func dialLoopback(t *testing.T, network *LoopbackNetwork, from string, to string) (Conn, Conn) {
+	local := network.NewTransport(from)
+	remote := network.NewTransport(to)
+	assert.NoError(t, local.Listen())
+	assert.NoError(t, remote.Listen())
+	t.Cleanup(func() {
+		local.Close()
+		remote.Close()
+	})
+
+	conn, err := local.Dial(to)
+	assert.Nil(t, err)
+	inbound, err := remote.Accept()
+	assert.Nil(t, err)
+	return conn, inbound
+}
+
+func pingMessage(nonce uint64) *proto.Message {
+	return &proto.Message{Payload: &proto.Message_Ping{Ping: &proto.Ping{Nonce: nonce}}}
+}
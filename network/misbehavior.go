@@ -0,0 +1,156 @@
+package network
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrBanned is returned when connecting to or from a banned node
+var ErrBanned = errors.New("node is banned")
+
+// Offence is a kind of misbehavior of a peer
+type Offence int
+
+const (
+	// OffenceInvalidBlock is a block breaking the validation rules
+	OffenceInvalidBlock Offence = iota
+	// OffenceInvalidHeader is a header breaking the consensus rules or not chained to the previous one
+	OffenceInvalidHeader
+	// OffenceInvalidTransaction is a transaction with an invalid signature
+	OffenceInvalidTransaction
+	// OffenceInvalidResponse is a response not matching its request
+	OffenceInvalidResponse
+	// OffenceRateLimit is a message received above the message rate limit
+	OffenceRateLimit
+	// OffenceInvalidVote is a checkpoint vote with an invalid signature or from a node which is not a validator
+	OffenceInvalidVote
+)
+
+// penalties are the misbehavior scores added for every offence
+var penalties = map[Offence]int{
+	OffenceInvalidBlock:       100,
+	OffenceInvalidHeader:      100,
+	OffenceInvalidTransaction: 20,
+	OffenceInvalidResponse:    25,
+	OffenceRateLimit:          10,
+	OffenceInvalidVote:        20,
+}
+
+// Penalty returns the misbehavior score added for the offence
+func (o Offence) Penalty() int {
+	return penalties[o]
+}
+
+func (o Offence) String() string {
+	switch o {
+	case OffenceInvalidBlock:
+		return "invalid block"
+	case OffenceInvalidHeader:
+		return "invalid header"
+	case OffenceInvalidTransaction:
+		return "invalid transaction"
+	case OffenceInvalidResponse:
+		return "invalid response"
+	case OffenceRateLimit:
+		return "rate limit exceeded"
+	case OffenceInvalidVote:
+		return "invalid vote"
+	default:
+		return "unknown offence"
+	}
+}
+
+// Penalize adds the penalty of the offence to the misbehavior score of the peer,
+// and bans the peer once its score reaches the ban threshold
+func (s *Server) Penalize(peer *Peer, offence Offence) {
+	score := peer.addScore(offence.Penalty())
+
+	log.Debug().Fields(map[string]interface{}{
+		"peer":    peer.ID(),
+		"offence": offence.String(),
+		"score":   score,
+	}).Msg("peer misbehaved")
+
+	if score >= s.config.BanThreshold {
+		s.Ban(peer, offence.String())
+	}
+}
+
+// Ban bans the node ID and the host of the peer for the ban duration and disconnects it
+func (s *Server) Ban(peer *Peer, reason string) {
+	until := time.Now().Add(s.config.BanDuration)
+	s.bans.Ban(peer.ID(), until)
+	if host := banHost(peer.Addr()); host != "" {
+		s.bans.Ban(host, until)
+	}
+	if err := s.bans.Save(); err != nil {
+		log.Error().Err(err).Msg("failed to save ban list")
+	}
+
+	log.Warn().Str("peer", peer.ID()).Str("reason", reason).Time("until", until).Msg("peer banned")
+	s.Disconnect(peer, "banned: "+reason)
+}
+
+// BanList returns the ban list of the server
+func (s *Server) BanList() *BanList {
+	return s.bans
+}
+
+// isBannedAddr returns true if the host of the address is banned
+func (s *Server) isBannedAddr(addr string) bool {
+	host := banHost(addr)
+	return host != "" && s.bans.IsBanned(host)
+}
+
+// banHost returns the host of the address banned along with the node ID. Loopback IPs are never banned,
+// as every node of a local network shares them.
+func banHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return ""
+	}
+	return host
+}
+
+// rateLimiter is a token bucket allowing a sustained rate of events with bursts up to its capacity
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a full rate limiter, a rate of zero or less allows every event
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket, returning false if it is empty
+func (r *rateLimiter) Allow(now time.Time) bool {
+	if r.rate <= 0 {
+		return true
+	}
+
+	r.tokens += now.Sub(r.last).Seconds() * r.rate
+	if r.tokens > r.burst {
+		r.tokens = r.burst
+	}
+	r.last = now
+
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
@@ -0,0 +1,117 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(10, 5)
+	limiter.last = now
+
+	// The burst is allowed at once, then the bucket refills at the rate
+	for i := 0; i < 5; i++ {
+		assert.True(t, limiter.Allow(now))
+	}
+	assert.False(t, limiter.Allow(now))
+	assert.True(t, limiter.Allow(now.Add(100*time.Millisecond)))
+	assert.False(t, limiter.Allow(now.Add(100*time.Millisecond)))
+
+	// The bucket never holds more than the burst
+	later := now.Add(time.Hour)
+	for i := 0; i < 5; i++ {
+		assert.True(t, limiter.Allow(later))
+	}
+	assert.False(t, limiter.Allow(later))
+
+	// A rate of zero disables the limit
+	unlimited := newRateLimiter(0, 0)
+	assert.True(t, unlimited.Allow(now))
+}
+
+func TestBanHost(t *testing.T) {
+	assert.Equal(t, "10.0.0.1", banHost("10.0.0.1:3000"))
+	assert.Equal(t, "node-a", banHost("node-a"))
+	assert.Equal(t, "", banHost("127.0.0.1:3000"))
+	assert.Equal(t, "", banHost("[::1]:3000"))
+}
+
+func TestServerPenalize(t *testing.T) {
+	network := NewLoopbackNetwork()
+	a := newLoopbackServer(t, network, "a", DefaultConfig)
+	b := newLoopbackServer(t, network, "b", DefaultConfig)
+
+	_, err := b.Connect("a")
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return len(a.Peers()) == 1 }, time.Second, 5*time.Millisecond)
+	peer := a.Peer(b.NodeID())
+
+	// The peer stays connected until its score reaches the ban threshold
+	a.Penalize(peer, OffenceInvalidResponse)
+	a.Penalize(peer, OffenceInvalidTransaction)
+	assert.Equal(t, 45, peer.Score())
+	assert.Len(t, a.Peers(), 1)
+
+	a.Penalize(peer, OffenceInvalidBlock)
+	assert.Empty(t, a.Peers())
+	assert.True(t, a.BanList().IsBanned(b.NodeID()))
+	assert.True(t, a.BanList().IsBanned("b"))
+	assert.Eventually(t, func() bool { return len(b.Peers()) == 0 }, time.Second, 5*time.Millisecond)
+
+	// The banned node can neither connect nor be connected to
+	_, err = b.Connect("a")
+	assert.Error(t, err)
+	_, err = a.Connect("b")
+	assert.ErrorIs(t, err, ErrBanned)
+	assert.Empty(t, a.Peers())
+}
+
+func TestServerBansFloodingPeer(t *testing.T) {
+	network := NewLoopbackNetwork()
+	config := DefaultConfig
+	config.MessageRate = 1
+	config.MessageBurst = 5
+	a := newLoopbackServer(t, network, "a", config)
+	b := newLoopbackServer(t, network, "b", DefaultConfig)
+
+	received := make(chan *proto.Message, 100)
+	a.OnMessage(func(peer *Peer, msg *proto.Message) { received <- msg })
+
+	peer, err := b.Connect("a")
+	assert.Nil(t, err)
+	for i := 0; i < 20; i++ {
+		peer.Send(&proto.Message{})
+	}
+
+	// The messages above the burst are dropped and the peer is banned after ten of them
+	assert.Eventually(t, func() bool { return a.BanList().IsBanned(b.NodeID()) }, time.Second, 5*time.Millisecond)
+	assert.Len(t, received, 5)
+}
+
+func TestServerBanListPersisted(t *testing.T) {
+	config := DefaultConfig
+	config.BanListPath = t.TempDir() + "/bans.json"
+	server, err := NewServer(config, NewLoopbackNetwork().NewTransport("a"), core.NewBlockchain(core.NewMemorystore()))
+	assert.Nil(t, err)
+	server.BanList().Ban("b", time.Now().Add(time.Hour))
+	server.Stop()
+
+	// The bans are loaded by the next server on the same ban list
+	restarted, err := NewServer(config, NewLoopbackNetwork().NewTransport("a"), core.NewBlockchain(core.NewMemorystore()))
+	assert.Nil(t, err)
+	assert.True(t, restarted.BanList().IsBanned("b"))
+}
+
+// newLoopbackServer starts a server on the loopback network, stopped when the test ends
+func newLoopbackServer(t *testing.T, network *LoopbackNetwork, addr string, config Config) *Server {
+	server, err := NewServer(config, network.NewTransport(addr), core.NewBlockchain(core.NewMemorystore()))
+	assert.Nil(t, err)
+	assert.NoError(t, server.Start())
+	t.Cleanup(server.Stop)
+	return server
+}
@@ -0,0 +1,196 @@
+package network
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+)
+
+// sendQueueSize is the number of messages that can wait to be written to a peer
+const sendQueueSize = 256
+
+// ErrSendQueueFull is returned when a peer does not keep up with the messages sent to it
+var ErrSendQueueFull = errors.New("peer send queue is full")
+
+// Peer is a remote node connected after a successful handshake
+type Peer struct {
+	conn       Conn
+	inbound    bool
+	id         []byte
+	version    uint32
+	listenAddr string
+
+	// limiter is only used by the read loop of the peer
+	limiter *rateLimiter
+
+	lock           sync.RWMutex
+	height         uint64
+	verifiedHeight uint64
+	lastSeen       time.Time
+	score          int
+
+	sendQueue chan *proto.Message
+	quit      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPeer(conn Conn, inbound bool, handshake *proto.Handshake, limiter *rateLimiter) *Peer {
+	return &Peer{
+		conn:       conn,
+		inbound:    inbound,
+		id:         handshake.NodeId,
+		version:    handshake.Version,
+		listenAddr: handshake.ListenAddr,
+		height:     handshake.Height,
+		limiter:    limiter,
+		lastSeen:   time.Now(),
+		sendQueue:  make(chan *proto.Message, sendQueueSize),
+		quit:       make(chan struct{}),
+	}
+}
+
+// ID returns the node ID of the peer as a hex string
+func (p *Peer) ID() string {
+	return hex.EncodeToString(p.id)
+}
+
+// Addr returns the address of the peer connection
+func (p *Peer) Addr() string {
+	return p.conn.RemoteAddr()
+}
+
+// ListenAddr returns the address the peer accepts connections on, empty if it does not accept any
+func (p *Peer) ListenAddr() string {
+	return p.listenAddr
+}
+
+// DialAddr returns the address the peer can be dialed at, empty if it does not accept connections. The advertised
+// listen address is often unspecified, like 0.0.0.0:3000, so only its port is used with the IP of the connection.
+func (p *Peer) DialAddr() string {
+	if !p.inbound {
+		return p.Addr()
+	}
+	_, port, err := net.SplitHostPort(p.listenAddr)
+	if err != nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr())
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Inbound returns true if the peer connected to us
+func (p *Peer) Inbound() bool {
+	return p.inbound
+}
+
+// Version returns the protocol version of the peer
+func (p *Peer) Version() uint32 {
+	return p.version
+}
+
+// Height returns the best height known of the peer chain
+func (p *Peer) Height() uint64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.height
+}
+
+// VerifiedHeight returns the height of the best block or header of the peer chain validated locally. Unlike Height,
+// it does not start from the height the peer claims in the handshake, so a peer cannot inflate it.
+func (p *Peer) VerifiedHeight() uint64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.verifiedHeight
+}
+
+// SetHeight records the height of a block or a header of the peer chain once it is validated
+func (p *Peer) SetHeight(height uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if height > p.height {
+		p.height = height
+	}
+	if height > p.verifiedHeight {
+		p.verifiedHeight = height
+	}
+}
+
+// resetHeight lowers the best height known of the peer chain, when the peer did not serve the blocks up to its height
+func (p *Peer) resetHeight(height uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if height < p.height {
+		p.height = height
+	}
+}
+
+// LastSeen returns when the last message was received from the peer
+func (p *Peer) LastSeen() time.Time {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.lastSeen
+}
+
+// Score returns the misbehavior score of the peer since it connected
+func (p *Peer) Score() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.score
+}
+
+// Send queues the message to be written to the peer
+func (p *Peer) Send(msg *proto.Message) error {
+	select {
+	case <-p.quit:
+		return errors.New("peer is disconnected")
+	default:
+	}
+
+	select {
+	case p.sendQueue <- msg:
+		return nil
+	default:
+		return ErrSendQueueFull
+	}
+}
+
+// touch records that a message was received from the peer
+func (p *Peer) touch() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.lastSeen = time.Now()
+}
+
+// addScore adds the penalty to the misbehavior score of the peer and returns the new score
+func (p *Peer) addScore(penalty int) int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.score += penalty
+	return p.score
+}
+
+// close closes the peer connection, returning false if it was already closed
+func (p *Peer) close() bool {
+	closed := false
+	p.closeOnce.Do(func() {
+		close(p.quit)
+		p.conn.Close()
+		closed = true
+	})
+	return closed
+}
@@ -0,0 +1,216 @@
+package network
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+)
+
+const (
+	// secureHandshakeDomain separates the handshake transcript from any other data signed with a node key
+	secureHandshakeDomain = "marvin/secure-handshake"
+	// secureKeysInfo is the HKDF info of the session keys derivation
+	secureKeysInfo = "marvin/secure-keys"
+)
+
+// secureConn encrypts and authenticates the messages of a connection with ChaCha20-Poly1305.
+// Each direction has its own key, and its nonce is a message counter, so a replayed,
+// reordered or dropped message fails to decrypt.
+type secureConn struct {
+	conn      Conn
+	remoteKey *crypto.PublicKey
+
+	sendLock  sync.Mutex
+	send      cipher.AEAD
+	sendNonce uint64
+
+	recvLock  sync.Mutex
+	recv      cipher.AEAD
+	recvNonce uint64
+}
+
+// newSecureConn runs the secure handshake on the connection and returns the encrypted connection.
+// Both nodes send an ephemeral X25519 key and derive the session keys from the shared secret,
+// then prove their Ed25519 identity by signing the handshake transcript in their first encrypted message.
+func newSecureConn(conn Conn, key *crypto.PrivateKey, initiator bool) (*secureConn, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	hello := &proto.SecureHello{EphemeralKey: ephemeral.PublicKey().Bytes()}
+	if err := conn.Send(&proto.Message{Payload: &proto.Message_SecureHello{SecureHello: hello}}); err != nil {
+		return nil, err
+	}
+
+	msg, err := conn.Receive()
+	if err != nil {
+		return nil, err
+	}
+	remoteHello := msg.GetSecureHello()
+	if remoteHello == nil {
+		return nil, errors.New("first message is not a secure hello")
+	}
+	remoteEphemeral, err := ecdh.X25519().NewPublicKey(remoteHello.EphemeralKey)
+	if err != nil {
+		return nil, errors.New("invalid ephemeral key")
+	}
+	shared, err := ephemeral.ECDH(remoteEphemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transcript binds the session to both ephemeral keys, in the initiator then responder order
+	initiatorKey, responderKey := hello.EphemeralKey, remoteHello.EphemeralKey
+	if !initiator {
+		initiatorKey, responderKey = responderKey, initiatorKey
+	}
+	transcript := sha256.Sum256(bytes.Join([][]byte{[]byte(secureHandshakeDomain), initiatorKey, responderKey}, nil))
+
+	kdf := hkdf.New(sha256.New, shared, transcript[:], []byte(secureKeysInfo))
+	initiatorSend := make([]byte, chacha20poly1305.KeySize)
+	responderSend := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(kdf, initiatorSend); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(kdf, responderSend); err != nil {
+		return nil, err
+	}
+	sendKey, recvKey := initiatorSend, responderSend
+	if !initiator {
+		sendKey, recvKey = recvKey, sendKey
+	}
+
+	c := &secureConn{conn: conn}
+	if c.send, err = chacha20poly1305.New(sendKey); err != nil {
+		return nil, err
+	}
+	if c.recv, err = chacha20poly1305.New(recvKey); err != nil {
+		return nil, err
+	}
+
+	if err := c.authenticate(key, transcript[:], initiator); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// authenticate exchanges the signatures of the transcript and verifies the identity of the remote node
+func (c *secureConn) authenticate(key *crypto.PrivateKey, transcript []byte, initiator bool) error {
+	sig, err := key.Sign(authData(transcript, initiator))
+	if err != nil {
+		return err
+	}
+	auth := &proto.SecureAuth{PublicKey: key.PublicKey().Bytes(), Signature: sig.Bytes()}
+	if err := c.Send(&proto.Message{Payload: &proto.Message_SecureAuth{SecureAuth: auth}}); err != nil {
+		return err
+	}
+
+	msg, err := c.Receive()
+	if err != nil {
+		return err
+	}
+	remoteAuth := msg.GetSecureAuth()
+	if remoteAuth == nil {
+		return errors.New("first encrypted message is not an authentication")
+	}
+	remoteKey, err := crypto.PublicKeyFromBytes(remoteAuth.PublicKey)
+	if err != nil {
+		return err
+	}
+	remoteSig, err := crypto.SignatureFromBytes(remoteAuth.Signature)
+	if err != nil {
+		return err
+	}
+	if !remoteSig.Verify(remoteKey, authData(transcript, !initiator)) {
+		return errors.New("invalid identity signature")
+	}
+	c.remoteKey = remoteKey
+	return nil
+}
+
+// authData is the data signed by a node to prove its identity. The role is part of it
+// so a node cannot send back the signature of the remote node as its own.
+func authData(transcript []byte, initiator bool) []byte {
+	role := byte(0)
+	if initiator {
+		role = 1
+	}
+	return append(append([]byte{}, transcript...), role)
+}
+
+// RemoteKey returns the authenticated public key of the remote node
+func (c *secureConn) RemoteKey() *crypto.PublicKey {
+	return c.remoteKey
+}
+
+// Send encrypts the message and writes it to the connection
+func (c *secureConn) Send(msg *proto.Message) error {
+	plaintext, err := pb.Marshal(msg)
+	if err != nil {
+		return errors.New("failed to marshal message")
+	}
+
+	// The lock keeps the messages written in the order of their nonces
+	c.sendLock.Lock()
+	defer c.sendLock.Unlock()
+
+	ciphertext := c.send.Seal(nil, counterNonce(c.sendNonce), plaintext, nil)
+	c.sendNonce++
+	return c.conn.Send(&proto.Message{Payload: &proto.Message_Encrypted{Encrypted: &proto.Encrypted{Ciphertext: ciphertext}}})
+}
+
+// Receive reads the next message from the connection and decrypts it
+func (c *secureConn) Receive() (*proto.Message, error) {
+	c.recvLock.Lock()
+	defer c.recvLock.Unlock()
+
+	msg, err := c.conn.Receive()
+	if err != nil {
+		return nil, err
+	}
+	encrypted := msg.GetEncrypted()
+	if encrypted == nil {
+		return nil, errors.New("received an unencrypted message")
+	}
+	plaintext, err := c.recv.Open(nil, counterNonce(c.recvNonce), encrypted.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt message")
+	}
+	c.recvNonce++
+
+	decrypted := &proto.Message{}
+	if err := pb.Unmarshal(plaintext, decrypted); err != nil {
+		return nil, errors.New("failed to unmarshal message")
+	}
+	return decrypted, nil
+}
+
+// Close closes the connection
+func (c *secureConn) Close() error {
+	return c.conn.Close()
+}
+
+// RemoteAddr returns the address of the remote node
+func (c *secureConn) RemoteAddr() string {
+	return c.conn.RemoteAddr()
+}
+
+// counterNonce returns the AEAD nonce of the message with the counter
+func counterNonce(counter uint64) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], counter)
+	return nonce
+}
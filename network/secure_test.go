@@ -0,0 +1,120 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecureConn(t *testing.T) {
+	network := NewLoopbackNetwork()
+	initiator, responder := newSecurePair(t, network)
+
+	assert.NoError(t, initiator.Send(pingMessage(1)))
+	msg, err := responder.Receive()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), msg.GetPing().GetNonce())
+
+	assert.NoError(t, responder.Send(pingMessage(2)))
+	msg, err = initiator.Receive()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), msg.GetPing().GetNonce())
+
+	// Only the ciphertext goes through the underlying connection, and a replayed ciphertext fails to decrypt
+	assert.NoError(t, initiator.Send(pingMessage(3)))
+	raw, err := responder.conn.Receive()
+	assert.Nil(t, err)
+	assert.NotNil(t, raw.GetEncrypted())
+	assert.NoError(t, initiator.conn.Send(raw))
+	assert.NoError(t, initiator.conn.Send(raw))
+	msg, err = responder.Receive()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), msg.GetPing().GetNonce())
+	_, err = responder.Receive()
+	assert.Error(t, err)
+}
+
+func TestSecureConnRejectsTampering(t *testing.T) {
+	network := NewLoopbackNetwork()
+	initiator, responder := newSecurePair(t, network)
+
+	assert.NoError(t, initiator.Send(pingMessage(1)))
+	raw, err := responder.conn.Receive()
+	assert.Nil(t, err)
+	raw.GetEncrypted().Ciphertext[0] ^= 1
+	assert.NoError(t, initiator.conn.Send(raw))
+	_, err = responder.Receive()
+	assert.Error(t, err)
+}
+
+func TestSecureHandshakeRejectsPlaintext(t *testing.T) {
+	network := NewLoopbackNetwork()
+	conn, inbound := dialLoopback(t, network, "a", "b")
+	key, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	// A node skipping the secure handshake is refused
+	go conn.Send(pingMessage(1))
+	_, err = newSecureConn(inbound, key, false)
+	assert.Error(t, err)
+}
+
+func TestServerNodeKey(t *testing.T) {
+	network := NewLoopbackNetwork()
+	key, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	config := DefaultConfig
+	config.NodeKey = key
+	a := newLoopbackServer(t, network, "a", config)
+	b := newLoopbackServer(t, network, "b", DefaultConfig)
+	assert.Equal(t, key.PublicKey().String(), a.NodeID())
+
+	// The node ID of the peer is its authenticated key
+	peer, err := b.Connect("a")
+	assert.Nil(t, err)
+	assert.Equal(t, a.NodeID(), peer.ID())
+
+	// A node announcing a node ID other than its key is refused
+	conn, err := network.NewTransport("c").Dial("a")
+	assert.Nil(t, err)
+	defer conn.Close()
+	impostor, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	secure, err := newSecureConn(conn, impostor, true)
+	assert.Nil(t, err)
+	handshake, err := b.localHandshake()
+	assert.Nil(t, err)
+	assert.NoError(t, secure.Send(&proto.Message{Payload: &proto.Message_Handshake{Handshake: handshake}}))
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, a.Peers(), 1)
+}
+
+// newSecurePair runs the secure handshake on the two ends of a loopback connection
+func newSecurePair(t *testing.T, network *LoopbackNetwork) (*secureConn, *secureConn) {
+	conn, inbound := dialLoopback(t, network, "a", "b")
+	initiatorKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	responderKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	type result struct {
+		conn *secureConn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		secure, err := newSecureConn(inbound, responderKey, false)
+		done <- result{secure, err}
+	}()
+	initiator, err := newSecureConn(conn, initiatorKey, true)
+	assert.Nil(t, err)
+	r := <-done
+	assert.Nil(t, r.err)
+
+	assert.Equal(t, responderKey.PublicKey().Bytes(), initiator.RemoteKey().Bytes())
+	assert.Equal(t, initiatorKey.PublicKey().Bytes(), r.conn.RemoteKey().Bytes())
+	return initiator, r.conn
+}
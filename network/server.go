@@ -0,0 +1,534 @@
+package network
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// Chain is the blockchain a server announces in its handshake, whose consensus limits the received messages are checked against
+type Chain interface {
+	Height() int
+	GetHeaderByHeight(height int) (*proto.Header, error)
+	Params() types.ConsensusParams
+}
+
+// Config holds the server parameters
+type Config struct {
+	// NodeKey is the Ed25519 identity key of the node, whose public key is the node ID. A random key is used when nil.
+	NodeKey *crypto.PrivateKey
+	// ChainID identifies the network, nodes on different chains do not connect
+	ChainID string
+	// MaxPeers is the maximum number of connected peers
+	MaxPeers int
+	// HandshakeTimeout is how long the handshake with a new connection can take
+	HandshakeTimeout time.Duration
+	// PingInterval is the interval between pings sent to the peers
+	PingInterval time.Duration
+	// IdleTimeout disconnects a peer after not receiving any message for this long
+	IdleTimeout time.Duration
+	// BanThreshold is the misbehavior score at which a peer is banned
+	BanThreshold int
+	// BanDuration is how long a misbehaving peer stays banned
+	BanDuration time.Duration
+	// BanListPath is the file the bans are persisted to, empty to keep them in memory
+	BanListPath string
+	// MessageRate is the sustained number of messages per second accepted from a peer, zero for no limit
+	MessageRate float64
+	// MessageBurst is the number of messages a peer can send at once above the message rate
+	MessageBurst int
+}
+
+// DefaultConfig is the server configuration used when none is provided
+var DefaultConfig = Config{
+	ChainID:          "marvin",
+	MaxPeers:         50,
+	HandshakeTimeout: 5 * time.Second,
+	PingInterval:     30 * time.Second,
+	IdleTimeout:      2 * time.Minute,
+	BanThreshold:     100,
+	BanDuration:      24 * time.Hour,
+	MessageRate:      200,
+	MessageBurst:     1000,
+}
+
+// MessageHandler handles a message received from a peer
+type MessageHandler func(peer *Peer, msg *proto.Message)
+
+// PeerHandler is notified when a peer connects or disconnects
+type PeerHandler func(peer *Peer)
+
+// Server manages the peers connected through a transport. Every connection starts with a secure handshake
+// authenticating the node keys and encrypting the following messages, then a handshake checking both nodes
+// are on the same chain. Peers are pinged periodically and disconnected after being idle for too long.
+// Messages other than the ones of the connection lifecycle are passed to the registered handlers.
+type Server struct {
+	config    Config
+	transport Transport
+	chain     Chain
+	key       *crypto.PrivateKey
+	nodeID    []byte
+	bans      *BanList
+	traffic   *trafficCounter
+
+	lock               sync.RWMutex
+	peers              map[string]*Peer
+	messageHandlers    []MessageHandler
+	connectHandlers    []PeerHandler
+	disconnectHandlers []PeerHandler
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewServer creates a server for the chain with the given configuration and transport
+func NewServer(config Config, transport Transport, chain Chain) (*Server, error) {
+	key := config.NodeKey
+	if key == nil {
+		generated, err := crypto.GeneratePrivateKey()
+		if err != nil {
+			return nil, err
+		}
+		key = generated
+	}
+	bans, err := NewBanList(config.BanListPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		config:    config,
+		transport: transport,
+		chain:     chain,
+		key:       key,
+		nodeID:    key.PublicKey().Bytes(),
+		bans:      bans,
+		traffic:   newTrafficCounter(),
+		peers:     make(map[string]*Peer),
+		quit:      make(chan struct{}),
+	}, nil
+}
+
+// NodeID returns the node ID of the server, the hex encoded public key of its node key
+func (s *Server) NodeID() string {
+	return hex.EncodeToString(s.nodeID)
+}
+
+// Addr returns the address the server accepts connections on
+func (s *Server) Addr() string {
+	return s.transport.Addr()
+}
+
+// Traffic returns the messages exchanged with the peers and their size by message type
+func (s *Server) Traffic() map[string]Traffic {
+	return s.traffic.snapshot()
+}
+
+// OnMessage registers a handler called with every message received from the peers
+func (s *Server) OnMessage(handler MessageHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.messageHandlers = append(s.messageHandlers, handler)
+}
+
+// OnPeerConnected registers a handler called when a peer completes the handshake
+func (s *Server) OnPeerConnected(handler PeerHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.connectHandlers = append(s.connectHandlers, handler)
+}
+
+// OnPeerDisconnected registers a handler called when a peer is disconnected
+func (s *Server) OnPeerDisconnected(handler PeerHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.disconnectHandlers = append(s.disconnectHandlers, handler)
+}
+
+// Start starts accepting connections and pinging the peers
+func (s *Server) Start() error {
+	if err := s.transport.Listen(); err != nil {
+		return err
+	}
+
+	log.Info().Str("addr", s.transport.Addr()).Str("node", s.NodeID()).Msg("network server started")
+
+	s.wg.Add(2)
+	go s.acceptLoop()
+	go s.pingLoop()
+	return nil
+}
+
+// Stop stops accepting connections, aborts the handshakes in progress and disconnects all the peers
+func (s *Server) Stop() {
+	// Closing under the lock orders it with the goroutines tracked and the peers added
+	s.lock.Lock()
+	close(s.quit)
+	s.lock.Unlock()
+	s.transport.Close()
+	for _, peer := range s.Peers() {
+		s.Disconnect(peer, "server stopped")
+	}
+	s.wg.Wait()
+	if err := s.bans.Save(); err != nil {
+		log.Error().Err(err).Msg("failed to save ban list")
+	}
+}
+
+// Connect opens an outbound connection to the node at the address and performs the handshake
+func (s *Server) Connect(addr string) (*Peer, error) {
+	if s.isBannedAddr(addr) {
+		return nil, ErrBanned
+	}
+	if !s.track() {
+		return nil, errors.New("server stopped")
+	}
+	defer s.wg.Done()
+
+	conn, err := s.transport.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return s.setupPeer(conn, false)
+}
+
+// track adds a goroutine setting up a peer to the goroutines Stop waits for, unless the server is stopped
+func (s *Server) track() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case <-s.quit:
+		return false
+	default:
+	}
+	s.wg.Add(1)
+	return true
+}
+
+// Peers returns the connected peers
+func (s *Server) Peers() []*Peer {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	peers := make([]*Peer, 0, len(s.peers))
+	for _, peer := range s.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
+// Peer returns the connected peer with the node ID, or nil
+func (s *Server) Peer(id string) *Peer {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.peers[id]
+}
+
+// Broadcast sends the message to all the connected peers
+func (s *Server) Broadcast(msg *proto.Message) {
+	for _, peer := range s.Peers() {
+		if err := peer.Send(msg); err != nil {
+			log.Debug().Err(err).Str("peer", peer.ID()).Msg("failed to send message")
+		}
+	}
+}
+
+// Disconnect tells the peer the reason of the disconnection and closes the connection
+func (s *Server) Disconnect(peer *Peer, reason string) {
+	// The message is written directly as the peer write loop stops with the connection
+	peer.conn.Send(&proto.Message{Payload: &proto.Message_Disconnect{Disconnect: &proto.Disconnect{Reason: reason}}})
+	s.removePeer(peer, errors.New(reason))
+}
+
+// acceptLoop sets up the inbound connections until the transport is closed
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.transport.Accept()
+		if err != nil {
+			if errors.Is(err, ErrTransportClosed) {
+				return
+			}
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+			log.Error().Err(err).Msg("failed to accept connection")
+			continue
+		}
+		if s.isBannedAddr(conn.RemoteAddr()) {
+			log.Debug().Str("addr", conn.RemoteAddr()).Msg("connection from banned host refused")
+			conn.Close()
+			continue
+		}
+
+		if !s.track() {
+			conn.Close()
+			return
+		}
+		go func() {
+			defer s.wg.Done()
+			if _, err := s.setupPeer(conn, true); err != nil {
+				log.Debug().Err(err).Str("addr", conn.RemoteAddr()).Msg("inbound connection rejected")
+			}
+		}()
+	}
+}
+
+// setupPeer performs the secure handshake and the handshake on a new connection and adds the peer
+func (s *Server) setupPeer(raw Conn, inbound bool) (*Peer, error) {
+	local, err := s.localHandshake()
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	conn, remote, err := s.handshake(raw, local, !inbound)
+	if err != nil {
+		raw.Close()
+		return nil, fmt.Errorf("handshake with (%s) failed: %v", raw.RemoteAddr(), err)
+	}
+
+	peer := newPeer(conn, inbound, remote, newRateLimiter(s.config.MessageRate, s.config.MessageBurst))
+	if err := s.addPeer(peer); err != nil {
+		conn.Send(&proto.Message{Payload: &proto.Message_Disconnect{Disconnect: &proto.Disconnect{Reason: err.Error()}}})
+		conn.Close()
+		return nil, err
+	}
+
+	log.Info().Fields(map[string]interface{}{
+		"peer":    peer.ID(),
+		"addr":    peer.Addr(),
+		"inbound": inbound,
+		"height":  peer.Height(),
+	}).Msg("peer connected")
+
+	s.wg.Add(2)
+	go s.readLoop(peer)
+	go s.writeLoop(peer)
+
+	for _, handler := range s.peerHandlers(true) {
+		handler(peer)
+	}
+	return peer, nil
+}
+
+// handshake secures the connection and exchanges the handshakes with the remote node within the handshake timeout.
+// The node ID of the remote handshake must be the key authenticated by the secure handshake.
+func (s *Server) handshake(raw Conn, local *proto.Handshake, initiator bool) (Conn, *proto.Handshake, error) {
+	type result struct {
+		conn      Conn
+		handshake *proto.Handshake
+		err       error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		conn, err := newSecureConn(raw, s.key, initiator)
+		if err != nil {
+			done <- result{err: fmt.Errorf("secure handshake failed: %v", err)}
+			return
+		}
+		if err := conn.Send(&proto.Message{Payload: &proto.Message_Handshake{Handshake: local}}); err != nil {
+			done <- result{err: err}
+			return
+		}
+		msg, err := conn.Receive()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		if disconnect := msg.GetDisconnect(); disconnect != nil {
+			done <- result{err: fmt.Errorf("disconnected by remote node: %s", disconnect.Reason)}
+			return
+		}
+		remote := msg.GetHandshake()
+		if remote == nil {
+			done <- result{err: errors.New("first message is not a handshake")}
+			return
+		}
+		if err := verifyHandshake(local, remote); err != nil {
+			done <- result{err: err}
+			return
+		}
+		if !bytes.Equal(remote.NodeId, conn.RemoteKey().Bytes()) {
+			done <- result{err: errors.New("node ID does not match the authenticated node key")}
+			return
+		}
+		done <- result{conn: conn, handshake: remote}
+	}()
+
+	timer := time.NewTimer(s.config.HandshakeTimeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.conn, r.handshake, r.err
+	case <-timer.C:
+		// Closing the connection unblocks the handshake goroutine
+		raw.Close()
+		return nil, nil, errors.New("handshake timeout")
+	case <-s.quit:
+		raw.Close()
+		return nil, nil, errors.New("server stopped")
+	}
+}
+
+// addPeer adds the peer unless it is already connected or the server has too many peers
+func (s *Server) addPeer(peer *Peer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	select {
+	case <-s.quit:
+		return errors.New("server stopped")
+	default:
+	}
+	if s.bans.IsBanned(peer.ID()) {
+		return ErrBanned
+	}
+	if _, ok := s.peers[peer.ID()]; ok {
+		return errors.New("already connected")
+	}
+	if len(s.peers) >= s.config.MaxPeers {
+		return errors.New("too many peers")
+	}
+	s.peers[peer.ID()] = peer
+	return nil
+}
+
+// removePeer closes the peer connection and notifies the disconnection once
+func (s *Server) removePeer(peer *Peer, reason error) {
+	if !peer.close() {
+		return
+	}
+
+	s.lock.Lock()
+	if s.peers[peer.ID()] == peer {
+		delete(s.peers, peer.ID())
+	}
+	s.lock.Unlock()
+
+	log.Info().Err(reason).Str("peer", peer.ID()).Msg("peer disconnected")
+
+	for _, handler := range s.peerHandlers(false) {
+		handler(peer)
+	}
+}
+
+// readLoop receives the messages of the peer until the connection is closed
+func (s *Server) readLoop(peer *Peer) {
+	defer s.wg.Done()
+
+	for {
+		msg, err := peer.conn.Receive()
+		if err != nil {
+			s.removePeer(peer, err)
+			return
+		}
+		s.traffic.add(msg, false)
+		peer.touch()
+		if !peer.limiter.Allow(time.Now()) {
+			// The message is dropped, and the peer is banned if it keeps flooding
+			s.Penalize(peer, OffenceRateLimit)
+			continue
+		}
+
+		switch payload := msg.Payload.(type) {
+		case *proto.Message_Ping:
+			peer.Send(&proto.Message{Payload: &proto.Message_Pong{Pong: &proto.Pong{Nonce: payload.Ping.Nonce}}})
+		case *proto.Message_Pong:
+		case *proto.Message_Disconnect:
+			s.removePeer(peer, fmt.Errorf("disconnected by peer: %s", payload.Disconnect.Reason))
+			return
+		case *proto.Message_Handshake:
+			s.removePeer(peer, errors.New("unexpected handshake"))
+			return
+		default:
+			// Blocks and transactions over the limits of the chain are dropped before reaching the handlers
+			if offence, err := checkLimits(msg, s.chain.Params()); err != nil {
+				log.Debug().Err(err).Str("peer", peer.ID()).Msg("message exceeds the consensus limits")
+				s.Penalize(peer, offence)
+				continue
+			}
+			for _, handler := range s.handlers() {
+				handler(peer, msg)
+			}
+		}
+	}
+}
+
+// writeLoop writes the queued messages to the peer until the connection is closed
+func (s *Server) writeLoop(peer *Peer) {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-peer.quit:
+			return
+		case msg := <-peer.sendQueue:
+			if err := peer.conn.Send(msg); err != nil {
+				s.removePeer(peer, err)
+				return
+			}
+			s.traffic.add(msg, true)
+		}
+	}
+}
+
+// pingLoop pings the peers and disconnects the idle ones
+func (s *Server) pingLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.PingInterval)
+	defer ticker.Stop()
+
+	var nonce uint64
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+			nonce++
+			for _, peer := range s.Peers() {
+				if time.Since(peer.LastSeen()) > s.config.IdleTimeout {
+					s.Disconnect(peer, "idle timeout")
+					continue
+				}
+				peer.Send(&proto.Message{Payload: &proto.Message_Ping{Ping: &proto.Ping{Nonce: nonce}}})
+			}
+		}
+	}
+}
+
+func (s *Server) handlers() []MessageHandler {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.messageHandlers
+}
+
+func (s *Server) peerHandlers(connected bool) []PeerHandler {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if connected {
+		return s.connectHandlers
+	}
+	return s.disconnectHandlers
+}
@@ -0,0 +1,205 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerHandshake(t *testing.T) {
+	a := newTestServer(t, DefaultConfig)
+	b := newTestServer(t, DefaultConfig)
+
+	connected := make(chan *Peer, 1)
+	a.OnPeerConnected(func(peer *Peer) { connected <- peer })
+
+	peer, err := b.Connect(a.Addr())
+	assert.Nil(t, err)
+	assert.Equal(t, a.NodeID(), peer.ID())
+	assert.False(t, peer.Inbound())
+	assert.Equal(t, uint32(ProtocolVersion), peer.Version())
+	assert.Equal(t, a.Addr(), peer.ListenAddr())
+
+	inbound := <-connected
+	assert.Equal(t, b.NodeID(), inbound.ID())
+	assert.True(t, inbound.Inbound())
+	assert.Len(t, a.Peers(), 1)
+	assert.Len(t, b.Peers(), 1)
+
+	// A second connection to the same node is refused
+	_, err = b.Connect(a.Addr())
+	assert.Error(t, err)
+
+	// A connection to self is refused
+	_, err = a.Connect(a.Addr())
+	assert.Error(t, err)
+}
+
+func TestServerRejectsOtherChain(t *testing.T) {
+	a := newTestServer(t, DefaultConfig)
+	config := DefaultConfig
+	config.ChainID = "other"
+	b := newTestServer(t, config)
+
+	_, err := b.Connect(a.Addr())
+	assert.Error(t, err)
+	assert.Empty(t, b.Peers())
+	assert.Eventually(t, func() bool { return len(a.Peers()) == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestServerMaxPeers(t *testing.T) {
+	config := DefaultConfig
+	config.MaxPeers = 1
+	a := newTestServer(t, config)
+	b := newTestServer(t, DefaultConfig)
+	c := newTestServer(t, DefaultConfig)
+
+	_, err := b.Connect(a.Addr())
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return len(a.Peers()) == 1 }, time.Second, 5*time.Millisecond)
+
+	// The handshake succeeds on the dialing side, but the peer is dropped straight away
+	c.Connect(a.Addr())
+	assert.Eventually(t, func() bool { return len(c.Peers()) == 0 }, time.Second, 5*time.Millisecond)
+	assert.Len(t, a.Peers(), 1)
+}
+
+func TestServerMessages(t *testing.T) {
+	a := newTestServer(t, DefaultConfig)
+	b := newTestServer(t, DefaultConfig)
+
+	received := make(chan *proto.Message, 1)
+	a.OnMessage(func(peer *Peer, msg *proto.Message) {
+		assert.Equal(t, b.NodeID(), peer.ID())
+		received <- msg
+	})
+
+	peer, err := b.Connect(a.Addr())
+	assert.Nil(t, err)
+
+	// Messages outside of the connection lifecycle are passed to the handlers
+	assert.NoError(t, peer.Send(&proto.Message{}))
+	select {
+	case got := <-received:
+		assert.Nil(t, got.Payload)
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+
+	// The traffic is counted by message type
+	assert.Equal(t, uint64(1), a.Traffic()["empty"].ReceivedMessages)
+	assert.Eventually(t, func() bool { return b.Traffic()["empty"].SentMessages == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "block", MessageType(&proto.Message{Payload: &proto.Message_Block{Block: &proto.Block{}}}))
+}
+
+func TestServerDisconnect(t *testing.T) {
+	a := newTestServer(t, DefaultConfig)
+	b := newTestServer(t, DefaultConfig)
+
+	disconnected := make(chan *Peer, 1)
+	a.OnPeerDisconnected(func(peer *Peer) { disconnected <- peer })
+
+	peer, err := b.Connect(a.Addr())
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return len(a.Peers()) == 1 }, time.Second, 5*time.Millisecond)
+
+	b.Disconnect(peer, "bye")
+	select {
+	case removed := <-disconnected:
+		assert.Equal(t, b.NodeID(), removed.ID())
+	case <-time.After(time.Second):
+		t.Fatal("disconnection not notified")
+	}
+	assert.Empty(t, a.Peers())
+	assert.Error(t, peer.Send(&proto.Message{}))
+}
+
+func TestServerIdleTimeout(t *testing.T) {
+	config := DefaultConfig
+	config.PingInterval = 10 * time.Millisecond
+	config.IdleTimeout = 50 * time.Millisecond
+	a := newTestServer(t, config)
+
+	// A peer answering pings stays connected
+	b := newTestServer(t, config)
+	_, err := b.Connect(a.Addr())
+	assert.Nil(t, err)
+	time.Sleep(4 * config.IdleTimeout)
+	assert.Len(t, a.Peers(), 1)
+
+	// A node that never sends anything after the handshake is disconnected
+	transport := NewTCPTransport("127.0.0.1:0")
+	conn, err := transport.Dial(a.Addr())
+	assert.Nil(t, err)
+	defer conn.Close()
+	silent := newTestServer(t, config)
+	local, err := silent.localHandshake()
+	assert.Nil(t, err)
+	secure, err := newSecureConn(conn, silent.key, true)
+	assert.Nil(t, err)
+	assert.NoError(t, secure.Send(&proto.Message{Payload: &proto.Message_Handshake{Handshake: local}}))
+	assert.Eventually(t, func() bool { return len(a.Peers()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return len(a.Peers()) == 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestServerHandshakeTimeout(t *testing.T) {
+	config := DefaultConfig
+	config.HandshakeTimeout = 50 * time.Millisecond
+	a := newTestServer(t, config)
+
+	// A connection that never sends its handshake is closed
+	conn, err := NewTCPTransport("127.0.0.1:0").Dial(a.Addr())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	msg, err := conn.Receive()
+	assert.Nil(t, err)
+	assert.NotNil(t, msg.GetSecureHello())
+	_, err = conn.Receive()
+	assert.Error(t, err)
+	assert.Empty(t, a.Peers())
+}
+
+func TestServerStopAbortsHandshakes(t *testing.T) {
+	config := DefaultConfig
+	config.HandshakeTimeout = time.Minute
+	server, err := NewServer(config, NewTCPTransport("127.0.0.1:0"), core.NewBlockchain(core.NewMemorystore()))
+	assert.Nil(t, err)
+	assert.NoError(t, server.Start())
+
+	// A connection stuck in the handshake does not delay Stop
+	conn, err := NewTCPTransport("127.0.0.1:0").Dial(server.Addr())
+	assert.Nil(t, err)
+	defer conn.Close()
+	msg, err := conn.Receive()
+	assert.Nil(t, err)
+	assert.NotNil(t, msg.GetSecureHello())
+
+	stopped := make(chan struct{})
+	go func() {
+		server.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stop waited for the handshake timeout")
+	}
+	assert.Empty(t, server.Peers())
+
+	// A stopped server does not dial new peers
+	_, err = server.Connect("127.0.0.1:1")
+	assert.Error(t, err)
+}
+
+// newTestServer starts a server on a local TCP port, stopped when the test ends
+func newTestServer(t *testing.T, config Config) *Server {
+	server, err := NewServer(config, NewTCPTransport("127.0.0.1:0"), core.NewBlockchain(core.NewMemorystore()))
+	assert.Nil(t, err)
+	assert.NoError(t, server.Start())
+	t.Cleanup(server.Stop)
+	return server
+}
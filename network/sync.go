@@ -0,0 +1,546 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protowire"
+	pb "google.golang.org/protobuf/proto"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+const (
+	// maxHeadersPerRequest is the maximum number of headers served for a request
+	maxHeadersPerRequest = 512
+	// maxBlocksPerRequest is the maximum number of blocks served for a request
+	maxBlocksPerRequest = 64
+	// messageOverhead is the room left in a message for the encryption envelope and the fields around the payload
+	messageOverhead = 1024
+	// maxBlocksResponseSize is the maximum encoded size of the blocks of a response, so it fits in a message
+	maxBlocksResponseSize = MaxMessageSize - messageOverhead
+)
+
+var (
+	// ErrNoSyncPeer is returned when no peer is ahead of the local chain
+	ErrNoSyncPeer = errors.New("no peer ahead of the local chain")
+	// errNoHeaders is returned when a peer ahead of the local chain has no headers to serve
+	errNoHeaders = errors.New("peer has no headers")
+)
+
+// SyncChain is the blockchain synchronized from the peers
+type SyncChain interface {
+	Chain
+	AddBlock(b *proto.Block) error
+	GetBlockByHash(hash []byte) (*proto.Block, error)
+	VerifyHeader(parent *proto.Header, header *proto.Header) error
+}
+
+// SyncConfig holds the synchronization parameters
+type SyncConfig struct {
+	// HeaderBatchSize is the number of headers requested at once
+	HeaderBatchSize int
+	// BlockBatchSize is the number of blocks requested at once from a peer
+	BlockBatchSize int
+	// RequestTimeout is how long a peer has to answer a request
+	RequestTimeout time.Duration
+	// SyncInterval is the interval between checks for peers ahead of the local chain
+	SyncInterval time.Duration
+}
+
+// DefaultSyncConfig is the synchronization configuration used when none is provided
+var DefaultSyncConfig = SyncConfig{
+	HeaderBatchSize: 192,
+	BlockBatchSize:  16,
+	RequestTimeout:  10 * time.Second,
+	SyncInterval:    10 * time.Second,
+}
+
+// SyncProgress reports the progress of the synchronization
+type SyncProgress struct {
+	Syncing        bool
+	StartingHeight uint64
+	CurrentHeight  uint64
+	HighestHeight  uint64
+}
+
+// Syncer downloads the blocks the local chain is missing from the peers, headers first.
+// The headers are fetched from the peer with the highest chain and validated as a chain,
+// then the block bodies are downloaded in parallel from all the peers having them and applied in order.
+type Syncer struct {
+	server *Server
+	chain  SyncChain
+	config SyncConfig
+
+	lock      sync.Mutex
+	progress  SyncProgress
+	requestID uint64
+	pending   map[uint64]*syncRequest
+
+	syncLock sync.Mutex
+	trigger  chan struct{}
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// syncRequest is a request waiting for the answer of a peer
+type syncRequest struct {
+	peer     string
+	response chan *proto.Message
+}
+
+// NewSyncer creates the synchronization of the chain from the peers of the server. It also serves the headers and blocks requested by the peers.
+func NewSyncer(server *Server, chain SyncChain, config SyncConfig) *Syncer {
+	s := &Syncer{
+		server:  server,
+		chain:   chain,
+		config:  config,
+		pending: make(map[uint64]*syncRequest),
+		trigger: make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+	}
+
+	server.OnMessage(s.handleMessage)
+	server.OnPeerConnected(func(peer *Peer) {
+		if peer.Height() > uint64(chain.Height()) {
+			s.Trigger()
+		}
+	})
+
+	return s
+}
+
+// Start starts synchronizing whenever a peer is ahead of the local chain
+func (s *Syncer) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop stops the synchronization
+func (s *Syncer) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// Trigger requests a synchronization round
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Progress returns the progress of the synchronization
+func (s *Syncer) Progress() SyncProgress {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	progress := s.progress
+	if !progress.Syncing {
+		progress.CurrentHeight = uint64(s.chain.Height())
+	}
+	return progress
+}
+
+// Sync downloads the blocks up to the highest chain of the peers
+func (s *Syncer) Sync() error {
+	s.syncLock.Lock()
+	defer s.syncLock.Unlock()
+
+	peer := s.bestPeer()
+	if peer == nil {
+		return ErrNoSyncPeer
+	}
+	target := peer.Height()
+	start := uint64(s.chain.Height())
+	s.setProgress(SyncProgress{Syncing: true, StartingHeight: start, CurrentHeight: start, HighestHeight: target})
+	defer func() { s.setProgress(SyncProgress{HighestHeight: target}) }()
+
+	log.Info().Fields(map[string]interface{}{
+		"peer":   peer.ID(),
+		"from":   start,
+		"target": target,
+	}).Msg("synchronization started")
+
+	for height := uint64(s.chain.Height()); height < target; height = uint64(s.chain.Height()) {
+		headers, err := s.fetchHeaders(peer, height+1)
+		if errors.Is(err, errNoHeaders) {
+			// The peer claimed a height it cannot serve, its height is lowered so the next best peer is synchronized from
+			log.Debug().Err(err).Str("peer", peer.ID()).Msg("synchronization peer has no headers")
+			s.server.Penalize(peer, OffenceInvalidResponse)
+			peer.resetHeight(height)
+			if peer = s.bestPeer(); peer == nil {
+				target = height
+				return err
+			}
+			target = peer.Height()
+			s.setProgress(SyncProgress{Syncing: true, StartingHeight: start, CurrentHeight: height, HighestHeight: target})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.downloadBlocks(headers); err != nil {
+			// The blocks match the headers, so the peer which served the headers announced invalid blocks
+			if errors.Is(err, core.ErrInvalidBlock) {
+				s.server.Penalize(peer, OffenceInvalidBlock)
+			}
+			return err
+		}
+		s.updateProgress(uint64(s.chain.Height()))
+
+		log.Info().Fields(map[string]interface{}{
+			"height": s.chain.Height(),
+			"target": target,
+		}).Msg("synchronization progress")
+	}
+
+	log.Info().Uint64("height", target).Msg("synchronization completed")
+	return nil
+}
+
+// bestPeer returns the peer with the highest chain, if it is ahead of the local chain
+func (s *Syncer) bestPeer() *Peer {
+	var best *Peer
+	for _, peer := range s.server.Peers() {
+		if best == nil || peer.Height() > best.Height() {
+			best = peer
+		}
+	}
+	if best == nil || best.Height() <= uint64(s.chain.Height()) {
+		return nil
+	}
+	return best
+}
+
+// fetchHeaders fetches a batch of headers starting at the height from the peer and validates them as a chain on top of the local chain
+func (s *Syncer) fetchHeaders(peer *Peer, from uint64) ([]*proto.Header, error) {
+	response, err := s.request(peer, func(id uint64) *proto.Message {
+		return &proto.Message{Payload: &proto.Message_HeadersRequest{HeadersRequest: &proto.HeadersRequest{
+			RequestId:  id,
+			FromHeight: from,
+			Count:      uint32(s.config.HeaderBatchSize),
+		}}}
+	})
+	if err != nil {
+		return nil, err
+	}
+	headers := response.GetHeadersResponse().GetHeaders()
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("%w from height %d (%s)", errNoHeaders, from, peer.ID())
+	}
+
+	parent, err := s.chain.GetHeaderByHeight(int(from - 1))
+	if err != nil {
+		return nil, err
+	}
+	for i, header := range headers {
+		if err := s.chain.VerifyHeader(parent, header); err != nil {
+			// The first header may extend another fork of the local chain, the following ones must be chained
+			if i > 0 || errors.Is(err, core.ErrInvalidBlock) {
+				s.server.Penalize(peer, OffenceInvalidHeader)
+			}
+			return nil, fmt.Errorf("invalid header at height %d from peer (%s): %v", header.GetHeight(), peer.ID(), err)
+		}
+		parent = header
+	}
+	// Only the height of validated headers is recorded
+	peer.SetHeight(headers[len(headers)-1].GetHeight())
+	return headers, nil
+}
+
+// blockBatch is a batch of consecutive blocks to download
+type blockBatch struct {
+	headers []*proto.Header
+	hashes  [][]byte
+}
+
+// batchResult is the outcome of the download of a batch by a peer
+type batchResult struct {
+	blocks []*proto.Block
+	err    error
+}
+
+// downloadBlocks downloads the blocks of the headers in parallel from the peers and adds them to the chain in order
+func (s *Syncer) downloadBlocks(headers []*proto.Header) error {
+	batches := make(chan *blockBatch, len(headers)/s.config.BlockBatchSize+1)
+	count := 0
+	for i := 0; i < len(headers); i += s.config.BlockBatchSize {
+		end := i + s.config.BlockBatchSize
+		if end > len(headers) {
+			end = len(headers)
+		}
+		batch := &blockBatch{headers: headers[i:end]}
+		for _, header := range batch.headers {
+			hash, err := types.HashHeader(header)
+			if err != nil {
+				return err
+			}
+			batch.hashes = append(batch.hashes, hash)
+		}
+		batches <- batch
+		count++
+	}
+
+	last := headers[len(headers)-1].GetHeight()
+	workers := 0
+	results := make(chan batchResult)
+	done := make(chan struct{})
+	defer close(done)
+	for _, peer := range s.server.Peers() {
+		if peer.Height() < last {
+			continue
+		}
+		workers++
+		go s.downloadWorker(peer, batches, results, done)
+	}
+	if workers == 0 {
+		return ErrNoSyncPeer
+	}
+
+	downloaded := make(map[uint64]*proto.Block, len(headers))
+	next := headers[0].GetHeight()
+	for next <= last {
+		result := <-results
+		if result.err != nil {
+			log.Debug().Err(result.err).Msg("block download failed")
+			workers--
+			if workers == 0 {
+				return fmt.Errorf("block download failed on every peer: %v", result.err)
+			}
+			continue
+		}
+
+		for _, b := range result.blocks {
+			downloaded[b.Header.GetHeight()] = b
+		}
+		for b, ok := downloaded[next]; ok; b, ok = downloaded[next] {
+			if err := s.chain.AddBlock(b); err != nil {
+				return fmt.Errorf("failed to add block at height %d: %w", next, err)
+			}
+			delete(downloaded, next)
+			next++
+		}
+		s.updateProgress(next - 1)
+	}
+	return nil
+}
+
+// downloadWorker downloads batches from the peer until there are none left, a failed batch is left for the other peers
+func (s *Syncer) downloadWorker(peer *Peer, batches chan *blockBatch, results chan<- batchResult, done <-chan struct{}) {
+	for {
+		var batch *blockBatch
+		select {
+		case batch = <-batches:
+		case <-done:
+			return
+		}
+
+		blocks, err := s.fetchBlocks(peer, batch)
+		if err != nil {
+			batches <- batch
+		}
+		select {
+		case results <- batchResult{blocks: blocks, err: err}:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// fetchBlocks requests the blocks of the batch from the peer and checks they match the headers.
+// A response cut short by the message size is followed by a request for the rest of the batch.
+func (s *Syncer) fetchBlocks(peer *Peer, batch *blockBatch) ([]*proto.Block, error) {
+	blocks := make([]*proto.Block, 0, len(batch.hashes))
+	for hashes := batch.hashes; len(hashes) > 0; hashes = batch.hashes[len(blocks):] {
+		response, err := s.request(peer, func(id uint64) *proto.Message {
+			return &proto.Message{Payload: &proto.Message_BlocksRequest{BlocksRequest: &proto.BlocksRequest{
+				RequestId: id,
+				Hashes:    hashes,
+			}}}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		received := response.GetBlocksResponse().GetBlocks()
+		if len(received) == 0 || len(received) > len(hashes) {
+			s.server.Penalize(peer, OffenceInvalidResponse)
+			return nil, fmt.Errorf("peer (%s) returned %d blocks, expected up to %d", peer.ID(), len(received), len(hashes))
+		}
+		for i, b := range received {
+			hash, err := types.HashBlock(b)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(hash, hashes[i]) {
+				s.server.Penalize(peer, OffenceInvalidResponse)
+				return nil, fmt.Errorf("peer (%s) returned an unexpected block at height %d", peer.ID(), b.Header.GetHeight())
+			}
+		}
+		blocks = append(blocks, received...)
+	}
+	return blocks, nil
+}
+
+// request sends the request built with a new request ID to the peer and waits for its response
+func (s *Syncer) request(peer *Peer, build func(id uint64) *proto.Message) (*proto.Message, error) {
+	s.lock.Lock()
+	s.requestID++
+	id := s.requestID
+	req := &syncRequest{peer: peer.ID(), response: make(chan *proto.Message, 1)}
+	s.pending[id] = req
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.pending, id)
+		s.lock.Unlock()
+	}()
+
+	if err := peer.Send(build(id)); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(s.config.RequestTimeout)
+	defer timer.Stop()
+
+	select {
+	case response := <-req.response:
+		return response, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("request to peer (%s) timed out", peer.ID())
+	case <-s.quit:
+		return nil, errors.New("syncer stopped")
+	}
+}
+
+// handleMessage serves the requests of the peers and delivers the responses to the pending requests
+func (s *Syncer) handleMessage(peer *Peer, msg *proto.Message) {
+	switch payload := msg.Payload.(type) {
+	case *proto.Message_HeadersRequest:
+		s.serveHeaders(peer, payload.HeadersRequest)
+	case *proto.Message_BlocksRequest:
+		s.serveBlocks(peer, payload.BlocksRequest)
+	case *proto.Message_HeadersResponse:
+		s.deliver(peer, payload.HeadersResponse.RequestId, msg)
+	case *proto.Message_BlocksResponse:
+		s.deliver(peer, payload.BlocksResponse.RequestId, msg)
+	case *proto.Message_Block:
+		// A gossiped block further ahead than the next block means the local chain is behind
+		if payload.Block.GetHeader().GetHeight() > uint64(s.chain.Height())+1 {
+			s.Trigger()
+		}
+	}
+}
+
+// deliver passes the response to the pending request with the ID, if it was sent to the peer
+func (s *Syncer) deliver(peer *Peer, id uint64, msg *proto.Message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	req, ok := s.pending[id]
+	if !ok || req.peer != peer.ID() {
+		return
+	}
+	select {
+	case req.response <- msg:
+	default:
+	}
+}
+
+// serveHeaders answers with the consecutive headers of the local chain from the requested height
+func (s *Syncer) serveHeaders(peer *Peer, request *proto.HeadersRequest) {
+	count := int(request.Count)
+	if count > maxHeadersPerRequest {
+		count = maxHeadersPerRequest
+	}
+
+	headers := make([]*proto.Header, 0, count)
+	// A height above the local chain is answered with no headers, it is checked before it is converted as it may not fit in an int
+	if request.FromHeight <= uint64(s.chain.Height()) {
+		for height := int(request.FromHeight); height <= s.chain.Height() && len(headers) < count; height++ {
+			header, err := s.chain.GetHeaderByHeight(height)
+			if err != nil {
+				break
+			}
+			headers = append(headers, header)
+		}
+	}
+
+	peer.Send(&proto.Message{Payload: &proto.Message_HeadersResponse{HeadersResponse: &proto.HeadersResponse{
+		RequestId: request.RequestId,
+		Headers:   headers,
+	}}})
+}
+
+// serveBlocks answers with the requested blocks of the local chain, stopping at the first missing block
+// or at the block which would not fit in the message. The peer requests the remaining blocks again.
+func (s *Syncer) serveBlocks(peer *Peer, request *proto.BlocksRequest) {
+	blocks := make([]*proto.Block, 0, len(request.Hashes))
+	size := 0
+	for _, hash := range request.Hashes {
+		if len(blocks) == maxBlocksPerRequest {
+			break
+		}
+		b, err := s.chain.GetBlockByHash(hash)
+		if err != nil {
+			break
+		}
+		// Each block is a length prefixed field of the response
+		blockSize := pb.Size(b)
+		size += protowire.SizeTag(2) + protowire.SizeBytes(blockSize)
+		if size > maxBlocksResponseSize {
+			break
+		}
+		blocks = append(blocks, b)
+	}
+
+	peer.Send(&proto.Message{Payload: &proto.Message_BlocksResponse{BlocksResponse: &proto.BlocksResponse{
+		RequestId: request.RequestId,
+		Blocks:    blocks,
+	}}})
+}
+
+// loop runs a synchronization round when triggered or on the sync interval
+func (s *Syncer) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+		if err := s.Sync(); err != nil && !errors.Is(err, ErrNoSyncPeer) {
+			log.Error().Err(err).Msg("synchronization failed")
+		}
+	}
+}
+
+func (s *Syncer) setProgress(progress SyncProgress) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.progress = progress
+}
+
+func (s *Syncer) updateProgress(height uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.progress.CurrentHeight = height
+}
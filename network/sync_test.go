@@ -0,0 +1,281 @@
+package network
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// testSyncConfig uses small batches so a test chain spans several batches
+var testSyncConfig = SyncConfig{
+	HeaderBatchSize: 20,
+	BlockBatchSize:  4,
+	RequestTimeout:  time.Second,
+	SyncInterval:    time.Hour,
+}
+
+func TestSyncFromPeers(t *testing.T) {
+	network := NewLoopbackNetwork()
+	network.SetLatency(time.Millisecond)
+	blocks := buildTestBlocks(t, 50)
+
+	// Three peers have the whole chain, count the blocks requests each of them serves
+	addrs := []string{"a", "b", "c"}
+	var lock sync.Mutex
+	requests := make(map[string]int)
+	for _, addr := range addrs {
+		addr := addr
+		chain := core.NewBlockchain(core.NewMemorystore())
+		for _, b := range blocks {
+			assert.NoError(t, chain.AddBlock(b))
+		}
+		peerServer, _ := newSyncNode(t, network, addr, chain)
+		peerServer.OnMessage(func(peer *Peer, msg *proto.Message) {
+			if msg.GetBlocksRequest() != nil {
+				lock.Lock()
+				requests[addr]++
+				lock.Unlock()
+			}
+		})
+	}
+
+	chain := core.NewBlockchain(core.NewMemorystore())
+	server, syncer := newSyncNode(t, network, "new", chain)
+	assert.Equal(t, SyncProgress{}, syncer.Progress())
+
+	for _, addr := range addrs {
+		_, err := server.Connect(addr)
+		assert.Nil(t, err)
+	}
+	for _, peer := range server.Peers() {
+		assert.Equal(t, uint64(50), peer.Height())
+	}
+
+	assert.NoError(t, syncer.Sync())
+	assert.Equal(t, 50, chain.Height())
+	assert.Equal(t, SyncProgress{CurrentHeight: 50, HighestHeight: 50}, syncer.Progress())
+
+	// The blocks were downloaded from every peer
+	lock.Lock()
+	for _, addr := range addrs {
+		assert.Greater(t, requests[addr], 0)
+	}
+	lock.Unlock()
+
+	// Nothing left to synchronize
+	assert.ErrorIs(t, syncer.Sync(), ErrNoSyncPeer)
+}
+
+func TestSyncTriggeredByPeer(t *testing.T) {
+	network := NewLoopbackNetwork()
+	blocks := buildTestBlocks(t, 10)
+
+	chain := core.NewBlockchain(core.NewMemorystore())
+	for _, b := range blocks {
+		assert.NoError(t, chain.AddBlock(b))
+	}
+	newSyncNode(t, network, "a", chain)
+
+	local := core.NewBlockchain(core.NewMemorystore())
+	server, syncer := newSyncNode(t, network, "b", local)
+	syncer.Start()
+	defer syncer.Stop()
+
+	// Connecting to a peer ahead of the local chain starts the synchronization
+	_, err := server.Connect("a")
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool { return local.Height() == 10 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSyncMaxSizeBlocks(t *testing.T) {
+	network := NewLoopbackNetwork()
+	sender, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	alloc := core.WithGenesisAlloc([]core.GenesisAccount{{Address: sender.PublicKey().Address(), Balance: 1_000_000}})
+
+	// Blocks filled up to the maximum block size, a batch of them does not fit in one message
+	params := types.DefaultConsensusParams
+	pending := make([]*proto.Transaction, 0, 200)
+	for nonce := int64(1); nonce <= 200; nonce++ {
+		tx := &proto.Transaction{To: sender.PublicKey().Bytes(), Value: 1, Nonce: nonce, Data: make([]byte, params.MaxTxDataSize)}
+		assert.Nil(t, types.SignTransaction(sender, tx))
+		pending = append(pending, tx)
+	}
+	chain := core.NewBlockchain(core.NewMemorystore(), alloc)
+	for i := 0; i < 3; i++ {
+		block, err := chain.BuildBlock(sender, pending)
+		assert.Nil(t, err)
+		assert.Greater(t, pb.Size(block), params.MaxBlockSize*9/10)
+		assert.NoError(t, chain.AddBlock(block))
+		pending = pending[len(block.Transactions)-1:]
+	}
+
+	var lock sync.Mutex
+	requests := 0
+	peerServer, _ := newSyncNode(t, network, "a", chain)
+	peerServer.OnMessage(func(peer *Peer, msg *proto.Message) {
+		if msg.GetBlocksRequest() != nil {
+			lock.Lock()
+			requests++
+			lock.Unlock()
+		}
+	})
+
+	// The responses are cut short by the message size and the rest of the batch is requested again
+	local := core.NewBlockchain(core.NewMemorystore(), alloc)
+	server, syncer := newSyncNode(t, network, "b", local)
+	_, err = server.Connect("a")
+	assert.Nil(t, err)
+	assert.NoError(t, syncer.Sync())
+	assert.Equal(t, 3, local.Height())
+	assert.Equal(t, 0, server.Peer(peerServer.NodeID()).Score())
+	lock.Lock()
+	assert.Greater(t, requests, 1)
+	lock.Unlock()
+}
+
+func TestSyncRejectsInvalidHeaders(t *testing.T) {
+	network := NewLoopbackNetwork()
+	blocks := buildTestBlocks(t, 5)
+
+	chain := core.NewBlockchain(core.NewMemorystore())
+	for _, b := range blocks {
+		assert.NoError(t, chain.AddBlock(b))
+	}
+	peerServer, err := NewServer(DefaultConfig, network.NewTransport("bad"), chain)
+	assert.Nil(t, err)
+	assert.NoError(t, peerServer.Start())
+	defer peerServer.Stop()
+
+	// The peer answers with headers that do not link to each other
+	peerServer.OnMessage(func(peer *Peer, msg *proto.Message) {
+		request := msg.GetHeadersRequest()
+		if request == nil {
+			return
+		}
+		headers := []*proto.Header{blocks[0].Header, blocks[2].Header}
+		peer.Send(&proto.Message{Payload: &proto.Message_HeadersResponse{HeadersResponse: &proto.HeadersResponse{
+			RequestId: request.RequestId,
+			Headers:   headers,
+		}}})
+	})
+
+	local := core.NewBlockchain(core.NewMemorystore())
+	server, syncer := newSyncNode(t, network, "b", local)
+	_, err = server.Connect("bad")
+	assert.Nil(t, err)
+
+	assert.Error(t, syncer.Sync())
+	assert.Equal(t, 0, local.Height())
+
+	// The peer is banned and cannot connect again
+	assert.Eventually(t, func() bool { return len(server.Peers()) == 0 }, time.Second, 5*time.Millisecond)
+	assert.True(t, server.BanList().IsBanned(peerServer.NodeID()))
+	_, err = server.Connect("bad")
+	assert.ErrorIs(t, err, ErrBanned)
+}
+
+func TestSyncFallsBackFromPeerWithoutHeaders(t *testing.T) {
+	network := NewLoopbackNetwork()
+	blocks := buildTestBlocks(t, 20)
+
+	// The liar claims the height of its chain but serves no headers
+	liarChain := core.NewBlockchain(core.NewMemorystore())
+	for _, b := range blocks {
+		assert.NoError(t, liarChain.AddBlock(b))
+	}
+	liar, err := NewServer(DefaultConfig, network.NewTransport("liar"), liarChain)
+	assert.Nil(t, err)
+	assert.NoError(t, liar.Start())
+	defer liar.Stop()
+	liar.OnMessage(func(peer *Peer, msg *proto.Message) {
+		if request := msg.GetHeadersRequest(); request != nil {
+			peer.Send(&proto.Message{Payload: &proto.Message_HeadersResponse{HeadersResponse: &proto.HeadersResponse{
+				RequestId: request.RequestId,
+			}}})
+		}
+	})
+
+	honestChain := core.NewBlockchain(core.NewMemorystore())
+	for _, b := range blocks[:10] {
+		assert.NoError(t, honestChain.AddBlock(b))
+	}
+	newSyncNode(t, network, "honest", honestChain)
+
+	local := core.NewBlockchain(core.NewMemorystore())
+	server, syncer := newSyncNode(t, network, "local", local)
+	for _, addr := range []string{"liar", "honest"} {
+		_, err := server.Connect(addr)
+		assert.Nil(t, err)
+	}
+
+	// The liar is penalized and its height lowered, the chain is synchronized from the next best peer
+	assert.NoError(t, syncer.Sync())
+	assert.Equal(t, 10, local.Height())
+	peer := server.Peer(liar.NodeID())
+	assert.Equal(t, OffenceInvalidResponse.Penalty(), peer.Score())
+	assert.Equal(t, uint64(0), peer.Height())
+	assert.Equal(t, uint64(10), syncer.Progress().HighestHeight)
+	assert.ErrorIs(t, syncer.Sync(), ErrNoSyncPeer)
+}
+
+func TestServeHeadersOutOfRange(t *testing.T) {
+	network := NewLoopbackNetwork()
+	newSyncNode(t, network, "a", core.NewBlockchain(core.NewMemorystore()))
+	server, _ := newSyncNode(t, network, "b", core.NewBlockchain(core.NewMemorystore()))
+	responses := make(chan *proto.HeadersResponse, 1)
+	server.OnMessage(func(peer *Peer, msg *proto.Message) {
+		if response := msg.GetHeadersResponse(); response != nil {
+			responses <- response
+		}
+	})
+	peer, err := server.Connect("a")
+	assert.Nil(t, err)
+
+	// A height that does not fit in an int is answered with no headers
+	assert.NoError(t, peer.Send(&proto.Message{Payload: &proto.Message_HeadersRequest{HeadersRequest: &proto.HeadersRequest{
+		RequestId:  1,
+		FromHeight: math.MaxUint64,
+		Count:      10,
+	}}}))
+	select {
+	case response := <-responses:
+		assert.Empty(t, response.Headers)
+	case <-time.After(time.Second):
+		t.Fatal("no headers response")
+	}
+}
+
+// newSyncNode starts a server with a syncer for the chain on the loopback network
+func newSyncNode(t *testing.T, network *LoopbackNetwork, addr string, chain *core.Blockchain) (*Server, *Syncer) {
+	server, err := NewServer(DefaultConfig, network.NewTransport(addr), chain)
+	assert.Nil(t, err)
+	syncer := NewSyncer(server, chain, testSyncConfig)
+	assert.NoError(t, server.Start())
+	t.Cleanup(server.Stop)
+	return server, syncer
+}
+
+// buildTestBlocks builds a chain of n blocks on top of the genesis block
+func buildTestBlocks(t *testing.T, n int) []*proto.Block {
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	chain := core.NewBlockchain(core.NewMemorystore())
+	blocks := make([]*proto.Block, 0, n)
+	for i := 0; i < n; i++ {
+		block, err := chain.BuildBlock(producer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, chain.AddBlock(block))
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
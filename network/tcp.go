@@ -0,0 +1,155 @@
+package network
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+)
+
+const (
+	// DefaultDialTimeout is how long dialing a node can take
+	DefaultDialTimeout = 5 * time.Second
+	// DefaultWriteTimeout is how long writing a message to a node can take
+	DefaultWriteTimeout = 10 * time.Second
+)
+
+// TCPTransport is the transport connecting nodes over TCP, framing messages with their length
+type TCPTransport struct {
+	listenAddr   string
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+
+	lock     sync.Mutex
+	listener net.Listener
+	closed   bool
+}
+
+// NewTCPTransport creates a TCP transport listening on the given address
+func NewTCPTransport(listenAddr string) *TCPTransport {
+	return &TCPTransport{
+		listenAddr:   listenAddr,
+		dialTimeout:  DefaultDialTimeout,
+		writeTimeout: DefaultWriteTimeout,
+	}
+}
+
+// Listen starts listening on the TCP address
+func (t *TCPTransport) Listen() error {
+	listener, err := net.Listen("tcp", t.listenAddr)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.listener = listener
+	return nil
+}
+
+// Accept waits for the next inbound TCP connection
+func (t *TCPTransport) Accept() (Conn, error) {
+	t.lock.Lock()
+	listener := t.listener
+	t.lock.Unlock()
+	if listener == nil {
+		return nil, errors.New("transport is not listening")
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		if t.isClosed() {
+			return nil, ErrTransportClosed
+		}
+		return nil, err
+	}
+	return newTCPConn(conn, t.writeTimeout), nil
+}
+
+// Dial opens a TCP connection to the address
+func (t *TCPTransport) Dial(addr string) (Conn, error) {
+	if t.isClosed() {
+		return nil, ErrTransportClosed
+	}
+	conn, err := net.DialTimeout("tcp", addr, t.dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newTCPConn(conn, t.writeTimeout), nil
+}
+
+// Addr returns the address of the listener, or the configured address before listening
+func (t *TCPTransport) Addr() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.listener != nil {
+		return t.listener.Addr().String()
+	}
+	return t.listenAddr
+}
+
+// Close stops listening
+func (t *TCPTransport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.closed = true
+	if t.listener != nil {
+		return t.listener.Close()
+	}
+	return nil
+}
+
+func (t *TCPTransport) isClosed() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.closed
+}
+
+// tcpConn is a TCP connection exchanging length prefixed messages
+type tcpConn struct {
+	conn         net.Conn
+	reader       *bufio.Reader
+	writeTimeout time.Duration
+	writeLock    sync.Mutex
+}
+
+func newTCPConn(conn net.Conn, writeTimeout time.Duration) *tcpConn {
+	return &tcpConn{
+		conn:         conn,
+		reader:       bufio.NewReader(conn),
+		writeTimeout: writeTimeout,
+	}
+}
+
+// Send writes the message to the connection
+func (c *tcpConn) Send(msg *proto.Message) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return err
+	}
+	return WriteMessage(c.conn, msg)
+}
+
+// Receive reads the next message from the connection
+func (c *tcpConn) Receive() (*proto.Message, error) {
+	return ReadMessage(c.reader)
+}
+
+// RemoteAddr returns the address of the remote end of the connection
+func (c *tcpConn) RemoteAddr() string {
+	return c.conn.RemoteAddr().String()
+}
+
+// Close closes the connection
+func (c *tcpConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,73 @@
+package network
+
+import (
+	"sync"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// Traffic counts the messages of a type exchanged with the peers and their encoded size in bytes
+type Traffic struct {
+	SentMessages     uint64
+	SentBytes        uint64
+	ReceivedMessages uint64
+	ReceivedBytes    uint64
+}
+
+// trafficCounter counts the traffic of every message type
+type trafficCounter struct {
+	lock   sync.Mutex
+	byType map[string]*Traffic
+}
+
+// newTrafficCounter creates a counter with no traffic
+func newTrafficCounter() *trafficCounter {
+	return &trafficCounter{
+		byType: make(map[string]*Traffic),
+	}
+}
+
+// add counts a message sent to or received from a peer
+func (c *trafficCounter) add(msg *proto.Message, sent bool) {
+	msgType := MessageType(msg)
+	size := uint64(pb.Size(msg))
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	traffic, ok := c.byType[msgType]
+	if !ok {
+		traffic = &Traffic{}
+		c.byType[msgType] = traffic
+	}
+	if sent {
+		traffic.SentMessages++
+		traffic.SentBytes += size
+	} else {
+		traffic.ReceivedMessages++
+		traffic.ReceivedBytes += size
+	}
+}
+
+// snapshot returns a copy of the traffic by message type
+func (c *trafficCounter) snapshot() map[string]Traffic {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	traffic := make(map[string]Traffic, len(c.byType))
+	for msgType, t := range c.byType {
+		traffic[msgType] = *t
+	}
+	return traffic
+}
+
+// MessageType returns the name of the payload of the message, like block or get_data
+func MessageType(msg *proto.Message) string {
+	m := msg.ProtoReflect()
+	field := m.WhichOneof(m.Descriptor().Oneofs().ByName("payload"))
+	if field == nil {
+		return "empty"
+	}
+	return string(field.Name())
+}
@@ -0,0 +1,82 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// MaxMessageSize is the maximum encoded size of a message, large enough for a block at the default consensus limits
+const MaxMessageSize = 4 * 1024 * 1024
+
+// ErrTransportClosed is returned when accepting or dialing on a closed transport
+var ErrTransportClosed = errors.New("transport closed")
+
+// Conn is a connection to a remote node exchanging messages
+type Conn interface {
+	// Send sends a message to the remote node, it is safe to call concurrently
+	Send(msg *proto.Message) error
+	// Receive blocks until a message is received from the remote node
+	Receive() (*proto.Message, error)
+	// RemoteAddr returns the address of the remote node
+	RemoteAddr() string
+	// Close closes the connection, unblocking Receive
+	Close() error
+}
+
+// Transport creates the connections between nodes
+type Transport interface {
+	// Listen starts accepting inbound connections
+	Listen() error
+	// Accept blocks until an inbound connection is established
+	Accept() (Conn, error)
+	// Dial opens an outbound connection to the node at the address
+	Dial(addr string) (Conn, error)
+	// Addr returns the address the transport accepts connections on
+	Addr() string
+	// Close stops accepting connections
+	Close() error
+}
+
+// WriteMessage writes the message prefixed with its length as a 4 bytes big endian integer
+func WriteMessage(w io.Writer, msg *proto.Message) error {
+	data, err := pb.Marshal(msg)
+	if err != nil {
+		return errors.New("failed to marshal message")
+	}
+	if len(data) > MaxMessageSize {
+		return fmt.Errorf("message size %d exceeds the maximum of %d", len(data), MaxMessageSize)
+	}
+
+	frame := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	copy(frame[4:], data)
+	_, err = w.Write(frame)
+	return err
+}
+
+// ReadMessage reads a message prefixed with its length, refusing messages larger than the maximum message size
+func ReadMessage(r io.Reader) (*proto.Message, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size > MaxMessageSize {
+		return nil, fmt.Errorf("message size %d exceeds the maximum of %d", size, MaxMessageSize)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	msg := &proto.Message{}
+	if err := pb.Unmarshal(data, msg); err != nil {
+		return nil, errors.New("failed to unmarshal message")
+	}
+	return msg, nil
+}
@@ -0,0 +1,37 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteReadMessage(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ping := &proto.Message{Payload: &proto.Message_Ping{Ping: &proto.Ping{Nonce: 42}}}
+	assert.NoError(t, WriteMessage(buf, ping))
+	assert.Equal(t, uint32(buf.Len()-4), binary.BigEndian.Uint32(buf.Bytes()[:4]))
+
+	msg, err := ReadMessage(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(42), msg.GetPing().Nonce)
+
+	// Truncated frame
+	assert.NoError(t, WriteMessage(buf, ping))
+	buf.Truncate(buf.Len() - 1)
+	_, err = ReadMessage(buf)
+	assert.Error(t, err)
+}
+
+func TestReadMessageTooLarge(t *testing.T) {
+	buf := &bytes.Buffer{}
+	prefix := make([]byte, 4)
+	binary.BigEndian.PutUint32(prefix, MaxMessageSize+1)
+	buf.Write(prefix)
+
+	_, err := ReadMessage(buf)
+	assert.Error(t, err)
+}
@@ -0,0 +1,220 @@
+package node
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/consensus/poa"
+	"github.com/joaoh82/marvinblockchain/consensus/pow"
+	"github.com/joaoh82/marvinblockchain/health"
+	"github.com/joaoh82/marvinblockchain/network"
+	"github.com/joaoh82/marvinblockchain/rpc"
+)
+
+// envPrefix is the prefix of the environment variables overriding the configuration
+const envPrefix = "MARVIN_"
+
+// Consensus engines a node can run. The instant seal engine accepts blocks sealed by any key,
+// it is only used by the dev node.
+const (
+	ConsensusPoW = "pow"
+	ConsensusPoA = "poa"
+)
+
+// Config holds the node parameters. It is read from a YAML file, then overridden by the
+// environment variables and the command line flags.
+type Config struct {
+	// DataDir is the directory of the block store, the node key, the address book and the ban list
+	DataDir string `yaml:"data_dir"`
+	// ChainID identifies the network, nodes on different chains do not connect
+	ChainID string `yaml:"chain_id"`
+	// Consensus is the consensus engine validating the blocks, pow or poa
+	Consensus string `yaml:"consensus"`
+	// Authorities are the hex encoded public keys of the genesis authorities of a Proof-of-Authority chain
+	Authorities []string `yaml:"authorities"`
+	// ValidatorKeyFile is the file holding the hex encoded seed of the key signing the checkpoint votes of a
+	// Proof-of-Authority authority, empty for a node which does not vote
+	ValidatorKeyFile string `yaml:"validator_key_file"`
+	// ListenAddr is the address accepting the peer connections
+	ListenAddr string `yaml:"listen_addr"`
+	// Bootnodes are the addresses of the nodes dialed first to join the network
+	Bootnodes []string `yaml:"bootnodes"`
+	// MaxPeers is the maximum number of connected peers
+	MaxPeers int `yaml:"max_peers"`
+	// TargetOutbound is the number of outbound connections the node maintains
+	TargetOutbound int `yaml:"target_outbound"`
+	// RPCAddr is the address of the JSON-RPC server, empty to disable it
+	RPCAddr string `yaml:"rpc_addr"`
+	// GRPCAddr is the address of the gRPC server, empty to disable it
+	GRPCAddr string `yaml:"grpc_addr"`
+	// HealthMinPeers is the minimum number of connected peers of a ready node
+	HealthMinPeers int `yaml:"health_min_peers"`
+	// HealthMaxHeightLag is the maximum number of blocks a ready node is behind its best peer
+	HealthMaxHeightLag int `yaml:"health_max_height_lag"`
+	// HealthMaxBlockAge is the maximum age of the last block of a ready node, 0 to not check it
+	HealthMaxBlockAge time.Duration `yaml:"health_max_block_age"`
+	// LogLevel is the minimum level of the logged messages (debug, info, warn or error)
+	LogLevel string `yaml:"log_level"`
+}
+
+// DefaultConfig is the node configuration used when none is provided
+var DefaultConfig = Config{
+	DataDir:            "data",
+	ChainID:            network.DefaultConfig.ChainID,
+	Consensus:          ConsensusPoW,
+	ListenAddr:         "0.0.0.0:3000",
+	MaxPeers:           network.DefaultConfig.MaxPeers,
+	TargetOutbound:     network.DefaultDiscoveryConfig.TargetOutbound,
+	RPCAddr:            rpc.DefaultConfig.ListenAddr,
+	GRPCAddr:           rpc.DefaultGRPCAddr,
+	HealthMinPeers:     health.DefaultConfig.MinPeers,
+	HealthMaxHeightLag: int(health.DefaultConfig.MaxHeightLag),
+	HealthMaxBlockAge:  health.DefaultConfig.MaxBlockAge,
+	LogLevel:           "info",
+}
+
+// LoadConfig reads the YAML configuration file at the path on top of the default configuration.
+// An empty path returns the default configuration.
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config file (%s): %v", path, err)
+	}
+	return config, nil
+}
+
+// ApplyEnv overrides the configuration with the MARVIN_ prefixed environment variables found by the lookup,
+// like MARVIN_DATA_DIR. The bootnodes are separated by commas.
+func (c *Config) ApplyEnv(lookup func(key string) (string, bool)) error {
+	stringFields := map[string]*string{
+		"DATA_DIR":           &c.DataDir,
+		"CHAIN_ID":           &c.ChainID,
+		"CONSENSUS":          &c.Consensus,
+		"VALIDATOR_KEY_FILE": &c.ValidatorKeyFile,
+		"LISTEN_ADDR":        &c.ListenAddr,
+		"RPC_ADDR":           &c.RPCAddr,
+		"GRPC_ADDR":          &c.GRPCAddr,
+		"LOG_LEVEL":          &c.LogLevel,
+	}
+	for name, field := range stringFields {
+		if value, ok := lookup(envPrefix + name); ok {
+			*field = value
+		}
+	}
+
+	intFields := map[string]*int{
+		"MAX_PEERS":             &c.MaxPeers,
+		"TARGET_OUTBOUND":       &c.TargetOutbound,
+		"HEALTH_MIN_PEERS":      &c.HealthMinPeers,
+		"HEALTH_MAX_HEIGHT_LAG": &c.HealthMaxHeightLag,
+	}
+	for name, field := range intFields {
+		if value, ok := lookup(envPrefix + name); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s%s (%s)", envPrefix, name, value)
+			}
+			*field = n
+		}
+	}
+
+	if value, ok := lookup(envPrefix + "HEALTH_MAX_BLOCK_AGE"); ok {
+		age, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %sHEALTH_MAX_BLOCK_AGE (%s)", envPrefix, value)
+		}
+		c.HealthMaxBlockAge = age
+	}
+	if value, ok := lookup(envPrefix + "BOOTNODES"); ok {
+		c.Bootnodes = SplitList(value)
+	}
+	if value, ok := lookup(envPrefix + "AUTHORITIES"); ok {
+		c.Authorities = SplitList(value)
+	}
+	return nil
+}
+
+// Validate checks that the configuration can run a node
+func (c *Config) Validate() error {
+	if c.DataDir == "" {
+		return errors.New("data directory is required")
+	}
+	if c.ChainID == "" {
+		return errors.New("chain ID is required")
+	}
+	if _, err := c.Engine(); err != nil {
+		return err
+	}
+	if c.MaxPeers < 1 {
+		return fmt.Errorf("max peers must be at least 1 (%d)", c.MaxPeers)
+	}
+	if c.TargetOutbound < 0 {
+		return fmt.Errorf("target outbound connections cannot be negative (%d)", c.TargetOutbound)
+	}
+	if c.HealthMinPeers < 0 || c.HealthMaxHeightLag < 0 || c.HealthMaxBlockAge < 0 {
+		return errors.New("health thresholds cannot be negative")
+	}
+	return nil
+}
+
+// Engine creates the consensus engine of the configuration with the default reward schedule
+func (c *Config) Engine() (consensus.Engine, error) {
+	switch c.Consensus {
+	case ConsensusPoW:
+		if len(c.Authorities) > 0 {
+			return nil, errors.New("authorities are only used by the poa consensus")
+		}
+		if c.ValidatorKeyFile != "" {
+			return nil, errors.New("validator key is only used by the poa consensus")
+		}
+		return pow.New(pow.DefaultConfig), nil
+	case ConsensusPoA:
+		authorities := make([][]byte, 0, len(c.Authorities))
+		for _, authority := range c.Authorities {
+			publicKey, err := hex.DecodeString(strings.TrimPrefix(authority, "0x"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid authority public key (%s)", authority)
+			}
+			authorities = append(authorities, publicKey)
+		}
+		return poa.New(authorities, consensus.DefaultRewardSchedule)
+	default:
+		return nil, fmt.Errorf("unknown consensus (%s), expected %s or %s", c.Consensus, ConsensusPoW, ConsensusPoA)
+	}
+}
+
+// HealthConfig returns the thresholds of the readiness of the node
+func (c *Config) HealthConfig() health.Config {
+	return health.Config{
+		MinPeers:     c.HealthMinPeers,
+		MaxHeightLag: uint64(c.HealthMaxHeightLag),
+		MaxBlockAge:  c.HealthMaxBlockAge,
+	}
+}
+
+// SplitList splits a comma separated list, leaving out the empty items
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,309 @@
+package node
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/consensus/finality"
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/explorer"
+	"github.com/joaoh82/marvinblockchain/health"
+	"github.com/joaoh82/marvinblockchain/metrics"
+	"github.com/joaoh82/marvinblockchain/network"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/rpc"
+)
+
+// Files of the data directory
+const (
+	blocksFile      = "blocks.dat"
+	nodeKeyFile     = "node.key"
+	addressBookFile = "peers.json"
+	banListFile     = "bans.json"
+)
+
+// Node is a full node. It stores the blockchain in its data directory, keeps a mempool of the pending
+// transactions, relays blocks and transactions to its peers and synchronizes its chain with them.
+// On a chain whose consensus engine has validators, it relays the checkpoint votes finalizing the blocks.
+type Node struct {
+	config    Config
+	store     *core.FileStore
+	chain     *core.Blockchain
+	mempool   *core.Mempool
+	server    *network.Server
+	gossip    *network.Gossip
+	syncer    *network.Syncer
+	discovery *network.Discovery
+	finality  *finality.Gadget
+	votes     *network.VoteRelay
+	rpc       *rpc.Server
+	grpc      *rpc.GRPCServer
+}
+
+// New opens the data directory of the configuration and loads the blockchain stored in it.
+// The blockchain runs the consensus engine of the configuration, and the options configure it further.
+func New(config Config, opts ...core.Option) (*Node, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	engine, err := config.Engine()
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]core.Option{core.WithEngine(engine)}, opts...)
+	if err := os.MkdirAll(config.DataDir, 0o700); err != nil {
+		return nil, err
+	}
+	key, err := LoadNodeKey(filepath.Join(config.DataDir, nodeKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := core.NewFileStore(filepath.Join(config.DataDir, blocksFile))
+	if err != nil {
+		return nil, err
+	}
+	chain, err := core.LoadBlockchain(store, opts...)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	mempool := core.NewMempool(core.WithMempoolState(chain.State()))
+	// The transactions included in a block leave the mempool
+	chain.OnBlockAdded(func(b *proto.Block) {
+		mempool.Remove(b.Transactions)
+	})
+
+	serverConfig := network.DefaultConfig
+	serverConfig.NodeKey = key
+	serverConfig.ChainID = config.ChainID
+	serverConfig.MaxPeers = config.MaxPeers
+	serverConfig.BanListPath = filepath.Join(config.DataDir, banListFile)
+	server, err := network.NewServer(serverConfig, network.NewTCPTransport(config.ListenAddr), chain)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	discoveryConfig := network.DefaultDiscoveryConfig
+	discoveryConfig.Bootnodes = config.Bootnodes
+	discoveryConfig.TargetOutbound = config.TargetOutbound
+	discoveryConfig.AddressBookPath = filepath.Join(config.DataDir, addressBookFile)
+	discovery, err := network.NewDiscovery(server, discoveryConfig)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	n := &Node{
+		config:    config,
+		store:     store,
+		chain:     chain,
+		mempool:   mempool,
+		server:    server,
+		gossip:    network.NewGossip(server, chain, mempool),
+		syncer:    network.NewSyncer(server, chain, network.DefaultSyncConfig),
+		discovery: discovery,
+	}
+	if validators, ok := engine.(finality.ValidatorSet); ok {
+		if err := n.startFinality(validators); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	if config.RPCAddr != "" {
+		rpcConfig := rpc.DefaultConfig
+		rpcConfig.ListenAddr = config.RPCAddr
+		n.rpc = rpc.NewServer(rpcConfig)
+		rpc.NewAPI(chain, mempool).Register(n.rpc)
+		rpc.NewEthAPI(chain, mempool, rpc.EthChainID(config.ChainID)).Register(n.rpc)
+		rpc.NewSubscriptions(chain, mempool).Register(n.rpc)
+		rpc.NewREST(chain, mempool).Register(n.rpc)
+		n.rpc.Handle(explorer.Path, explorer.Handler())
+		n.rpc.Handle(metrics.Path, metrics.New(chain, mempool, server, store).Handler())
+		checker := health.NewChecker(config.HealthConfig(), chain, server, n.syncer, store)
+		n.rpc.Handle(health.HealthPath, checker.HealthHandler())
+		n.rpc.Handle(health.ReadyPath, checker.ReadyHandler())
+	}
+	if config.GRPCAddr != "" {
+		n.grpc = rpc.NewGRPCServer(config.GRPCAddr, rpc.NewNodeService(chain, mempool))
+	}
+	return n, nil
+}
+
+// startFinality runs the finality gadget of the validators and relays their checkpoint votes.
+// With a validator key, the node votes for every checkpoint added to its chain.
+func (n *Node) startFinality(validators finality.ValidatorSet) error {
+	gadget, err := finality.New(n.chain, validators, finality.DefaultCheckpointInterval)
+	if err != nil {
+		return err
+	}
+	n.finality = gadget
+	n.votes = network.NewVoteRelay(n.server, gadget)
+	if n.config.ValidatorKeyFile == "" {
+		return nil
+	}
+
+	key, err := LoadValidatorKey(n.config.ValidatorKeyFile)
+	if err != nil {
+		return err
+	}
+	// The gadget records the validator set of the checkpoint before the node votes, its handler is registered first
+	n.chain.OnBlockAdded(func(b *proto.Block) {
+		if !gadget.IsCheckpoint(b.Header.GetHeight()) {
+			return
+		}
+		if _, err := gadget.Vote(key); err != nil {
+			log.Debug().Err(err).Uint64("height", b.Header.GetHeight()).Msg("failed to vote for checkpoint")
+		}
+	})
+	return nil
+}
+
+// LoadValidatorKey reads the validator key stored as a hex encoded seed at the path
+func LoadValidatorKey(path string) (*crypto.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.NewPrivateKeyfromString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.New("invalid validator key file")
+	}
+	return &key, nil
+}
+
+// LoadNodeKey reads the node key stored as a hex encoded seed at the path, generating and storing a new key
+// the first time so the node ID stays the same across restarts
+func LoadNodeKey(path string) (*crypto.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := crypto.NewPrivateKeyfromString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, errors.New("invalid node key file")
+		}
+		return &key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := crypto.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key.Key.Seed())), 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Config returns the configuration of the node
+func (n *Node) Config() Config {
+	return n.config
+}
+
+// Blockchain returns the blockchain of the node
+func (n *Node) Blockchain() *core.Blockchain {
+	return n.chain
+}
+
+// Mempool returns the mempool of the node
+func (n *Node) Mempool() *core.Mempool {
+	return n.mempool
+}
+
+// Server returns the network server of the node
+func (n *Node) Server() *network.Server {
+	return n.server
+}
+
+// Finality returns the finality gadget of the node, nil when the consensus engine has no validators
+func (n *Node) Finality() *finality.Gadget {
+	return n.finality
+}
+
+// Syncer returns the chain synchronization of the node
+func (n *Node) Syncer() *network.Syncer {
+	return n.syncer
+}
+
+// RPC returns the JSON-RPC server of the node, nil when disabled
+func (n *Node) RPC() *rpc.Server {
+	return n.rpc
+}
+
+// GRPC returns the gRPC server of the node, nil when disabled
+func (n *Node) GRPC() *rpc.GRPCServer {
+	return n.grpc
+}
+
+// Start starts accepting peers, dialing the known nodes and synchronizing the chain, then serving the RPC requests.
+// If a server fails to start, the components already started are stopped before the error is returned.
+func (n *Node) Start() error {
+	if err := n.server.Start(); err != nil {
+		return err
+	}
+	n.syncer.Start()
+	n.discovery.Start()
+	if n.rpc != nil {
+		if err := n.rpc.Start(); err != nil {
+			n.stopNetwork()
+			return err
+		}
+	}
+	if n.grpc != nil {
+		if err := n.grpc.Start(); err != nil {
+			n.stopRPC()
+			n.stopNetwork()
+			return err
+		}
+	}
+
+	log.Info().Fields(map[string]interface{}{
+		"node":    n.server.NodeID(),
+		"addr":    n.server.Addr(),
+		"height":  n.chain.Height(),
+		"dataDir": n.config.DataDir,
+	}).Msg("node started")
+	return nil
+}
+
+// Stop stops serving the RPC requests and disconnects the peers, then flushes the address book,
+// the ban list and the block store to the data directory
+func (n *Node) Stop() error {
+	if n.grpc != nil {
+		n.grpc.Stop()
+	}
+	n.stopRPC()
+	n.stopNetwork()
+	if err := n.store.Close(); err != nil {
+		return err
+	}
+
+	log.Info().Int("height", n.chain.Height()).Msg("node stopped")
+	return nil
+}
+
+// stopRPC stops serving the JSON-RPC requests
+func (n *Node) stopRPC() {
+	if n.rpc == nil {
+		return
+	}
+	if err := n.rpc.Stop(); err != nil {
+		log.Error().Err(err).Msg("failed to stop rpc server")
+	}
+}
+
+// stopNetwork stops dialing the known nodes and synchronizing the chain, then disconnects the peers
+func (n *Node) stopNetwork() {
+	n.discovery.Stop()
+	n.syncer.Stop()
+	n.server.Stop()
+}
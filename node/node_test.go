@@ -0,0 +1,187 @@
+package node
+
+import (
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/consensus/finality"
+	"github.com/joaoh82/marvinblockchain/consensus/poa"
+	"github.com/joaoh82/marvinblockchain/consensus/pow"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := LoadConfig("")
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultConfig, config)
+
+	path := filepath.Join(t.TempDir(), "marvin.yaml")
+	data := "data_dir: /var/lib/marvin\nbootnodes:\n  - 10.0.0.1:3000\nmax_peers: 10\nhealth_max_block_age: 10m\n"
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+	config, err = LoadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "/var/lib/marvin", config.DataDir)
+	assert.Equal(t, []string{"10.0.0.1:3000"}, config.Bootnodes)
+	assert.Equal(t, 10, config.MaxPeers)
+	assert.Equal(t, 10*time.Minute, config.HealthMaxBlockAge)
+	// The fields missing from the file keep their default value
+	assert.Equal(t, DefaultConfig.ListenAddr, config.ListenAddr)
+
+	assert.NoError(t, os.WriteFile(path, []byte("max_peers: many\n"), 0o644))
+	_, err = LoadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestConfigApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"MARVIN_DATA_DIR":             "/tmp/marvin",
+		"MARVIN_BOOTNODES":            "10.0.0.1:3000, 10.0.0.2:3000,",
+		"MARVIN_MAX_PEERS":            "20",
+		"MARVIN_HEALTH_MAX_BLOCK_AGE": "30s",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	config := DefaultConfig
+	assert.NoError(t, config.ApplyEnv(lookup))
+	assert.Equal(t, "/tmp/marvin", config.DataDir)
+	assert.Equal(t, []string{"10.0.0.1:3000", "10.0.0.2:3000"}, config.Bootnodes)
+	assert.Equal(t, 20, config.MaxPeers)
+	assert.Equal(t, 30*time.Second, config.HealthMaxBlockAge)
+	assert.Equal(t, DefaultConfig.ChainID, config.ChainID)
+
+	env["MARVIN_MAX_PEERS"] = "many"
+	assert.Error(t, config.ApplyEnv(lookup))
+}
+
+func TestConfigEngine(t *testing.T) {
+	config := DefaultConfig
+	engine, err := config.Engine()
+	assert.Nil(t, err)
+	assert.IsType(t, &pow.Engine{}, engine)
+
+	authority, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	config.Consensus = ConsensusPoA
+	config.Authorities = []string{hex.EncodeToString(authority.PublicKey().Bytes())}
+	engine, err = config.Engine()
+	assert.Nil(t, err)
+	assert.IsType(t, &poa.Engine{}, engine)
+
+	// Proof-of-Authority needs valid authorities, and the instant seal engine is not available to the daemon
+	config.Authorities = []string{"zz"}
+	assert.Error(t, config.Validate())
+	config.Authorities = nil
+	assert.Error(t, config.Validate())
+	config.Consensus = "instant"
+	assert.Error(t, config.Validate())
+
+	// The validator key only votes on a Proof-of-Authority chain
+	config.Consensus = ConsensusPoW
+	config.ValidatorKeyFile = "validator.key"
+	assert.Error(t, config.Validate())
+}
+
+func TestNodeRestart(t *testing.T) {
+	config := DefaultConfig
+	config.DataDir = t.TempDir()
+	config.ListenAddr = "127.0.0.1:0"
+	config.RPCAddr = "127.0.0.1:0"
+	config.GRPCAddr = "127.0.0.1:0"
+
+	n, err := New(config)
+	assert.Nil(t, err)
+	assert.NoError(t, n.Start())
+	nodeID := n.Server().NodeID()
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		block, err := n.Blockchain().BuildBlock(producer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, n.Blockchain().AddBlock(block))
+	}
+	assert.NoError(t, n.Stop())
+
+	// The node key and the blocks are loaded back from the data directory
+	n, err = New(config)
+	assert.Nil(t, err)
+	assert.NoError(t, n.Start())
+	defer n.Stop()
+	assert.Equal(t, nodeID, n.Server().NodeID())
+	assert.Equal(t, 2, n.Blockchain().Height())
+}
+
+func TestNodeStartFailureStopsStartedServers(t *testing.T) {
+	// The gRPC address is already in use
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	config := DefaultConfig
+	config.DataDir = t.TempDir()
+	config.ListenAddr = "127.0.0.1:0"
+	config.RPCAddr = "127.0.0.1:0"
+	config.GRPCAddr = listener.Addr().String()
+
+	n, err := New(config)
+	assert.Nil(t, err)
+	assert.Error(t, n.Start())
+
+	// The p2p and RPC servers started before the gRPC server no longer accept connections
+	for _, addr := range []string{n.Server().Addr(), n.RPC().Addr()} {
+		_, err := net.DialTimeout("tcp", addr, time.Second)
+		assert.Error(t, err, addr)
+	}
+}
+
+func TestNodeFinalizesCheckpoints(t *testing.T) {
+	authority, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	config := DefaultConfig
+	config.DataDir = t.TempDir()
+	config.ListenAddr = "127.0.0.1:0"
+	config.RPCAddr = ""
+	config.GRPCAddr = ""
+	config.Consensus = ConsensusPoA
+	config.Authorities = []string{hex.EncodeToString(authority.PublicKey().Bytes())}
+	config.ValidatorKeyFile = filepath.Join(config.DataDir, "validator.key")
+	assert.NoError(t, os.WriteFile(config.ValidatorKeyFile, []byte(hex.EncodeToString(authority.Key.Seed())), 0o600))
+
+	n, err := New(config)
+	assert.Nil(t, err)
+	assert.NotNil(t, n.Finality())
+	assert.NoError(t, n.Start())
+
+	// The only authority votes for the checkpoint as soon as it is added, which finalizes it
+	for i := 0; i < finality.DefaultCheckpointInterval+1; i++ {
+		block, err := n.Blockchain().BuildBlock(authority, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, n.Blockchain().AddBlock(block))
+	}
+	assert.Equal(t, finality.DefaultCheckpointInterval, n.Blockchain().FinalizedHeight())
+	assert.NoError(t, n.Stop())
+
+	// The finalized height is restored with the blocks
+	n, err = New(config)
+	assert.Nil(t, err)
+	defer n.Stop()
+	assert.Equal(t, finality.DefaultCheckpointInterval, n.Blockchain().FinalizedHeight())
+
+	// A node without validators has no finality gadget
+	config.DataDir = t.TempDir()
+	config.Consensus = ConsensusPoW
+	config.Authorities = nil
+	config.ValidatorKeyFile = ""
+	other, err := New(config)
+	assert.Nil(t, err)
+	defer other.Stop()
+	assert.Nil(t, other.Finality())
+}
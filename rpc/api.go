@@ -0,0 +1,221 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/joaoh82/marvinblockchain/consensus"
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// Chain is the blockchain served by the API
+type Chain interface {
+	Height() int
+	GetBlockByHash(hash []byte) (*proto.Block, error)
+	GetBlockByHeight(height int) (*proto.Block, error)
+	GetTransaction(hash []byte) (*proto.Transaction, core.TxLocation, error)
+	FinalizedHeight() int
+	State() consensus.StateReader
+	Params() types.ConsensusParams
+}
+
+// TxPool is the pool the transactions submitted to the API are added to
+type TxPool interface {
+	Add(tx *proto.Transaction) error
+	Get(hash []byte) *proto.Transaction
+	Len() int
+}
+
+// API serves the blockchain and the mempool under the marvin_ methods
+type API struct {
+	chain   Chain
+	mempool TxPool
+}
+
+// NewAPI creates the API of the chain and the mempool
+func NewAPI(chain Chain, mempool TxPool) *API {
+	return &API{
+		chain:   chain,
+		mempool: mempool,
+	}
+}
+
+// Register registers the methods of the API on the server
+func (api *API) Register(server *Server) {
+	server.Register("marvin_height", api.height)
+	server.Register("marvin_getBlockByHash", api.getBlockByHash)
+	server.Register("marvin_getBlockByHeight", api.getBlockByHeight)
+	server.Register("marvin_getTransaction", api.getTransaction)
+	server.Register("marvin_getBalance", api.getBalance)
+	server.Register("marvin_getNonce", api.getNonce)
+	server.Register("marvin_sendRawTransaction", api.sendRawTransaction)
+}
+
+// height returns the height of the blockchain
+func (api *API) height(params json.RawMessage) (interface{}, error) {
+	if err := DecodeParams(params, 0); err != nil {
+		return nil, err
+	}
+	return api.chain.Height(), nil
+}
+
+// getBlockByHash returns the block with the hash, or null if unknown. Params: [hash, fullTransactions?]
+func (api *API) getBlockByHash(params json.RawMessage) (interface{}, error) {
+	var hashParam string
+	var fullTxs bool
+	if err := DecodeParams(params, 1, &hashParam, &fullTxs); err != nil {
+		return nil, err
+	}
+	hash, err := decodeHex(hashParam)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := api.chain.GetBlockByHash(hash)
+	if err != nil {
+		return nil, nil
+	}
+	return NewBlock(b, fullTxs)
+}
+
+// getBlockByHeight returns the block at the height, or null if the blockchain is not that high. Params: [height, fullTransactions?]
+func (api *API) getBlockByHeight(params json.RawMessage) (interface{}, error) {
+	var height int
+	var fullTxs bool
+	if err := DecodeParams(params, 1, &height, &fullTxs); err != nil {
+		return nil, err
+	}
+	if height < 0 {
+		return nil, NewError(CodeInvalidParams, "height cannot be negative")
+	}
+
+	b, err := api.chain.GetBlockByHeight(height)
+	if err != nil {
+		return nil, nil
+	}
+	return NewBlock(b, fullTxs)
+}
+
+// getTransaction returns the transaction with the hash from the blockchain or the mempool, or null if unknown. Params: [hash]
+func (api *API) getTransaction(params json.RawMessage) (interface{}, error) {
+	var hashParam string
+	if err := DecodeParams(params, 1, &hashParam); err != nil {
+		return nil, err
+	}
+	hash, err := decodeHex(hashParam)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx, location, err := api.chain.GetTransaction(hash); err == nil {
+		return NewTransaction(tx, &location)
+	}
+	if tx := api.mempool.Get(hash); tx != nil {
+		return NewTransaction(tx, nil)
+	}
+	return nil, nil
+}
+
+// getBalance returns the balance of the account. Params: [address or public key]
+func (api *API) getBalance(params json.RawMessage) (interface{}, error) {
+	address, err := addressParam(params)
+	if err != nil {
+		return nil, err
+	}
+	return api.chain.State().Balance(address), nil
+}
+
+// getNonce returns the nonce of the last transaction sent by the account. Params: [address or public key]
+func (api *API) getNonce(params json.RawMessage) (interface{}, error) {
+	address, err := addressParam(params)
+	if err != nil {
+		return nil, err
+	}
+	return api.chain.State().Nonce(address), nil
+}
+
+// sendRawTransaction verifies the serialized transaction, adds it to the mempool and returns its hash. Params: [hex transaction]
+func (api *API) sendRawTransaction(params json.RawMessage) (interface{}, error) {
+	hash, err := api.submitRaw(params)
+	if err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(hash), nil
+}
+
+// submitRaw deserializes the hex transaction of the params, submits it and returns its hash
+func (api *API) submitRaw(params json.RawMessage) ([]byte, error) {
+	var dataParam string
+	if err := DecodeParams(params, 1, &dataParam); err != nil {
+		return nil, err
+	}
+	data, err := decodeHex(dataParam)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := types.DeserializeTransactionWithParams(data, api.chain.Params())
+	if err != nil {
+		return nil, NewError(CodeInvalidParams, "invalid transaction: %v", err)
+	}
+
+	if err := api.submit(tx); err != nil {
+		return nil, err
+	}
+	return types.HashTransaction(tx)
+}
+
+// submit verifies the transaction signature and nonce, then adds it to the mempool
+func (api *API) submit(tx *proto.Transaction) error {
+	if types.IsCoinbase(tx) {
+		return NewError(CodeTransactionRejected, "coinbase transactions cannot be submitted")
+	}
+	if ok, err := types.VerifyTransaction(tx); err != nil || !ok {
+		return NewError(CodeTransactionRejected, "invalid transaction signature")
+	}
+	from, err := types.AccountAddress(tx.From)
+	if err != nil {
+		return NewError(CodeTransactionRejected, "%v", err)
+	}
+	if nonce := api.chain.State().Nonce(from); tx.Nonce <= nonce {
+		return NewError(CodeTransactionRejected, "nonce %d already used by account (%s), next nonce is %d", tx.Nonce, from, nonce+1)
+	}
+	if err := api.mempool.Add(tx); err != nil {
+		return NewError(CodeTransactionRejected, "%v", err)
+	}
+	return nil
+}
+
+// addressParam decodes the account of the params, given by its address or its public key
+func addressParam(params json.RawMessage) (crypto.Address, error) {
+	var accountParam string
+	if err := DecodeParams(params, 1, &accountParam); err != nil {
+		return crypto.Address{}, err
+	}
+	return decodeAddress(accountParam)
+}
+
+// decodeAddress decodes the hex address or public key of an account
+func decodeAddress(value string) (crypto.Address, error) {
+	account, err := decodeHex(value)
+	if err != nil {
+		return crypto.Address{}, err
+	}
+	address, err := types.AccountAddress(account)
+	if err != nil {
+		return crypto.Address{}, NewError(CodeInvalidParams, "%v", err)
+	}
+	return address, nil
+}
+
+// decodeHex decodes a hex param, with or without the 0x prefix
+func decodeHex(value string) ([]byte, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		return nil, NewError(CodeInvalidParams, "invalid hex value (%s)", value)
+	}
+	return data, nil
+}
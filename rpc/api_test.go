@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// call calls the method with the params and decodes the response
+func call(t *testing.T, s *Server, method string, params ...interface{}) Response {
+	if params == nil {
+		params = []interface{}{}
+	}
+	request, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	assert.Nil(t, err)
+	_, body := post(t, s, string(request))
+
+	var response Response
+	assert.NoError(t, json.Unmarshal([]byte(body), &response))
+	return response
+}
+
+func TestAPI(t *testing.T) {
+	bc := core.NewBlockchain(core.NewMemorystore())
+	mempool := core.NewMempool()
+	s := NewServer(DefaultConfig)
+	NewAPI(bc, mempool).Register(s)
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	block, err := bc.BuildBlock(producer, nil)
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+
+	assert.Equal(t, json.RawMessage("1"), call(t, s, "marvin_height").Result)
+
+	var encoded Block
+	assert.NoError(t, json.Unmarshal(call(t, s, "marvin_getBlockByHeight", 1, true).Result, &encoded))
+	blockHash, err := types.HashBlock(block)
+	assert.Nil(t, err)
+	assert.Equal(t, hex.EncodeToString(blockHash), encoded.Hash)
+	assert.Len(t, encoded.Transactions, 1)
+	assert.Equal(t, json.RawMessage("null"), call(t, s, "marvin_getBlockByHeight", 5).Result)
+	assert.NoError(t, json.Unmarshal(call(t, s, "marvin_getBlockByHash", "0x"+encoded.Hash).Result, &encoded))
+	assert.Equal(t, uint64(1), encoded.Height)
+	assert.Equal(t, CodeInvalidParams, call(t, s, "marvin_getBlockByHash", "zz").Error.Code)
+
+	address := producer.PublicKey().Address()
+	var balance uint64
+	assert.NoError(t, json.Unmarshal(call(t, s, "marvin_getBalance", address.String()).Result, &balance))
+	assert.Equal(t, bc.State().Balance(address), balance)
+	assert.Greater(t, balance, uint64(0))
+
+	// Submit a transfer, found in the mempool then in the blockchain
+	tx := &proto.Transaction{From: producer.PublicKey().Bytes(), To: make([]byte, 20), Value: 10, Fee: 1, Nonce: 1}
+	assert.NoError(t, types.SignTransaction(producer, tx))
+	data, err := types.SerializeTransaction(tx)
+	assert.Nil(t, err)
+	response := call(t, s, "marvin_sendRawTransaction", hex.EncodeToString(data))
+	assert.Nil(t, response.Error)
+	var txHash string
+	assert.NoError(t, json.Unmarshal(response.Result, &txHash))
+	assert.Equal(t, 1, mempool.Len())
+	assert.Equal(t, CodeTransactionRejected, call(t, s, "marvin_sendRawTransaction", hex.EncodeToString(data)).Error.Code)
+
+	var pending Transaction
+	assert.NoError(t, json.Unmarshal(call(t, s, "marvin_getTransaction", txHash).Result, &pending))
+	assert.Equal(t, uint64(10), pending.Value)
+	assert.Nil(t, pending.BlockHash)
+
+	block, err = bc.BuildBlock(producer, mempool.Pending())
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	var included Transaction
+	assert.NoError(t, json.Unmarshal(call(t, s, "marvin_getTransaction", txHash).Result, &included))
+	assert.Equal(t, uint64(2), *included.BlockHeight)
+	assert.Equal(t, 1, *included.Index)
+	assert.Equal(t, json.RawMessage("1"), call(t, s, "marvin_getNonce", hex.EncodeToString(producer.PublicKey().Bytes())).Result)
+
+	// A transaction with an already used nonce is rejected
+	mempool.Remove(block.Transactions)
+	assert.Equal(t, CodeTransactionRejected, call(t, s, "marvin_sendRawTransaction", hex.EncodeToString(data)).Error.Code)
+	assert.Equal(t, CodeInvalidParams, call(t, s, "marvin_sendRawTransaction", "00ff").Error.Code)
+}
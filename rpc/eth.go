@@ -0,0 +1,219 @@
+package rpc
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+)
+
+// EthAPI serves the blockchain and the mempool under a subset of the Ethereum eth_ and net_ methods,
+// mapping the Marvin blocks, transactions and receipts to their Ethereum JSON encoding
+type EthAPI struct {
+	api     *API
+	chainID uint64
+}
+
+// NewEthAPI creates the Ethereum API of the chain and the mempool, reporting the numeric chain ID
+func NewEthAPI(chain Chain, mempool TxPool, chainID uint64) *EthAPI {
+	return &EthAPI{
+		api:     NewAPI(chain, mempool),
+		chainID: chainID,
+	}
+}
+
+// Register registers the methods of the API on the server
+func (eth *EthAPI) Register(server *Server) {
+	server.Register("eth_chainId", eth.chainIDMethod)
+	server.Register("net_version", eth.netVersion)
+	server.Register("eth_blockNumber", eth.blockNumber)
+	server.Register("eth_getBlockByNumber", eth.getBlockByNumber)
+	server.Register("eth_getBlockByHash", eth.getBlockByHash)
+	server.Register("eth_getBalance", eth.getBalance)
+	server.Register("eth_getTransactionCount", eth.getTransactionCount)
+	server.Register("eth_getTransactionByHash", eth.getTransactionByHash)
+	server.Register("eth_getTransactionReceipt", eth.getTransactionReceipt)
+	server.Register("eth_sendRawTransaction", eth.sendRawTransaction)
+}
+
+// chainIDMethod returns the numeric chain ID as a quantity
+func (eth *EthAPI) chainIDMethod(params json.RawMessage) (interface{}, error) {
+	if err := DecodeParams(params, 0); err != nil {
+		return nil, err
+	}
+	return encodeUint(eth.chainID), nil
+}
+
+// netVersion returns the numeric chain ID in decimal
+func (eth *EthAPI) netVersion(params json.RawMessage) (interface{}, error) {
+	if err := DecodeParams(params, 0); err != nil {
+		return nil, err
+	}
+	return strconv.FormatUint(eth.chainID, 10), nil
+}
+
+// blockNumber returns the height of the blockchain
+func (eth *EthAPI) blockNumber(params json.RawMessage) (interface{}, error) {
+	if err := DecodeParams(params, 0); err != nil {
+		return nil, err
+	}
+	return encodeUint(uint64(eth.api.chain.Height())), nil
+}
+
+// getBlockByNumber returns the block at the number or tag, or null if unknown. Params: [number or tag, fullTransactions]
+func (eth *EthAPI) getBlockByNumber(params json.RawMessage) (interface{}, error) {
+	var number string
+	var fullTxs bool
+	if err := DecodeParams(params, 1, &number, &fullTxs); err != nil {
+		return nil, err
+	}
+	height, err := eth.blockHeight(number)
+	if err != nil {
+		return nil, err
+	}
+
+	// A number above the blockchain is unknown, it is checked before it is converted as it may not fit in an int
+	if height > uint64(eth.api.chain.Height()) {
+		return nil, nil
+	}
+	b, err := eth.api.chain.GetBlockByHeight(int(height))
+	if err != nil {
+		return nil, nil
+	}
+	return NewEthBlock(b, fullTxs, eth.chainID)
+}
+
+// getBlockByHash returns the block with the hash, or null if unknown. Params: [hash, fullTransactions]
+func (eth *EthAPI) getBlockByHash(params json.RawMessage) (interface{}, error) {
+	var hashParam string
+	var fullTxs bool
+	if err := DecodeParams(params, 1, &hashParam, &fullTxs); err != nil {
+		return nil, err
+	}
+	hash, err := decodeHex(hashParam)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := eth.api.chain.GetBlockByHash(hash)
+	if err != nil {
+		return nil, nil
+	}
+	return NewEthBlock(b, fullTxs, eth.chainID)
+}
+
+// getBalance returns the balance of the account. Only the state of the last block is kept,
+// so the block must be the last one. Params: [address, number or tag]
+func (eth *EthAPI) getBalance(params json.RawMessage) (interface{}, error) {
+	var account, number string
+	if err := DecodeParams(params, 1, &account, &number); err != nil {
+		return nil, err
+	}
+	address, err := eth.latestAccount(account, number)
+	if err != nil {
+		return nil, err
+	}
+	return encodeUint(eth.api.chain.State().Balance(address)), nil
+}
+
+// getTransactionCount returns the nonce the next transaction of the account must use. Marvin nonces
+// start at 1, so the count is the nonce of the last transaction plus one. Params: [address, number or tag]
+func (eth *EthAPI) getTransactionCount(params json.RawMessage) (interface{}, error) {
+	var account, number string
+	if err := DecodeParams(params, 1, &account, &number); err != nil {
+		return nil, err
+	}
+	address, err := eth.latestAccount(account, number)
+	if err != nil {
+		return nil, err
+	}
+	return encodeUint(uint64(eth.api.chain.State().Nonce(address) + 1)), nil
+}
+
+// getTransactionByHash returns the transaction with the hash from the blockchain or the mempool, or null if unknown. Params: [hash]
+func (eth *EthAPI) getTransactionByHash(params json.RawMessage) (interface{}, error) {
+	hash, err := eth.hashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if tx, location, err := eth.api.chain.GetTransaction(hash); err == nil {
+		return NewEthTransaction(tx, &location, eth.chainID)
+	}
+	if tx := eth.api.mempool.Get(hash); tx != nil {
+		return NewEthTransaction(tx, nil, eth.chainID)
+	}
+	return nil, nil
+}
+
+// getTransactionReceipt returns the receipt of the transaction, or null while it is not in a block. Params: [hash]
+func (eth *EthAPI) getTransactionReceipt(params json.RawMessage) (interface{}, error) {
+	hash, err := eth.hashParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	_, location, err := eth.api.chain.GetTransaction(hash)
+	if err != nil {
+		return nil, nil
+	}
+	b, err := eth.api.chain.GetBlockByHash(location.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	return NewEthReceipt(b, location.Index)
+}
+
+// sendRawTransaction verifies the serialized transaction, adds it to the mempool and returns its hash.
+// Marvin transactions are signed with Ed25519, so the data is a serialized Marvin transaction and not
+// an RLP encoded Ethereum transaction. Params: [hex transaction]
+func (eth *EthAPI) sendRawTransaction(params json.RawMessage) (interface{}, error) {
+	hash, err := eth.api.submitRaw(params)
+	if err != nil {
+		return nil, err
+	}
+	return encodeBytes(hash), nil
+}
+
+// blockHeight returns the height of the block number or tag. The pending block is the last block,
+// as blocks are sealed from the mempool by their producer.
+func (eth *EthAPI) blockHeight(number string) (uint64, error) {
+	switch number {
+	case "", "latest", "pending":
+		return uint64(eth.api.chain.Height()), nil
+	case "earliest":
+		return 0, nil
+	case "safe", "finalized":
+		return uint64(eth.api.chain.FinalizedHeight()), nil
+	}
+	height, err := decodeUint(number)
+	if err != nil {
+		return 0, NewError(CodeInvalidParams, "%v", err)
+	}
+	return height, nil
+}
+
+// latestAccount decodes the account address and checks that the block is the last one, the only state kept
+func (eth *EthAPI) latestAccount(account string, number string) (crypto.Address, error) {
+	address, err := decodeAddress(account)
+	if err != nil {
+		return crypto.Address{}, err
+	}
+	height, err := eth.blockHeight(number)
+	if err != nil {
+		return crypto.Address{}, err
+	}
+	if latest := eth.api.chain.Height(); height != uint64(latest) {
+		return crypto.Address{}, NewError(CodeInvalidParams, "state of block %d is not available, only the state of the last block %d is kept", height, latest)
+	}
+	return address, nil
+}
+
+// hashParam decodes the hash of the params
+func (eth *EthAPI) hashParam(params json.RawMessage) ([]byte, error) {
+	var hashParam string
+	if err := DecodeParams(params, 1, &hashParam); err != nil {
+		return nil, err
+	}
+	return decodeHex(hashParam)
+}
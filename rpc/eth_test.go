@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEthQuantities(t *testing.T) {
+	assert.Equal(t, "0x0", encodeUint(0))
+	assert.Equal(t, "0x1f", encodeUint(31))
+	n, err := decodeUint("0x1f")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(31), n)
+	_, err = decodeUint("31")
+	assert.Error(t, err)
+	_, err = decodeUint("0x")
+	assert.Error(t, err)
+	assert.Equal(t, EthChainID("marvin"), EthChainID("marvin"))
+	assert.NotEqual(t, EthChainID("marvin"), EthChainID("other"))
+}
+
+func TestEthAPI(t *testing.T) {
+	bc := core.NewBlockchain(core.NewMemorystore())
+	mempool := core.NewMempool()
+	s := NewServer(DefaultConfig)
+	NewEthAPI(bc, mempool, 1337).Register(s)
+
+	assert.Equal(t, json.RawMessage(`"0x539"`), call(t, s, "eth_chainId").Result)
+	assert.Equal(t, json.RawMessage(`"1337"`), call(t, s, "net_version").Result)
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	block, err := bc.BuildBlock(producer, nil)
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	assert.Equal(t, json.RawMessage(`"0x1"`), call(t, s, "eth_blockNumber").Result)
+
+	address := producer.PublicKey().Address()
+	var balance string
+	assert.NoError(t, json.Unmarshal(call(t, s, "eth_getBalance", "0x"+address.String(), "latest").Result, &balance))
+	assert.Equal(t, encodeUint(bc.State().Balance(address)), balance)
+	// Only the state of the last block is kept
+	assert.Equal(t, CodeInvalidParams, call(t, s, "eth_getBalance", "0x"+address.String(), "0x0").Error.Code)
+	assert.Equal(t, json.RawMessage(`"0x1"`), call(t, s, "eth_getTransactionCount", "0x"+address.String(), "latest").Result)
+
+	// Send a transfer and read its receipt once included in a block
+	tx := &proto.Transaction{From: producer.PublicKey().Bytes(), To: make([]byte, 20), Value: 10, Fee: 2, Nonce: 1}
+	assert.NoError(t, types.SignTransaction(producer, tx))
+	data, err := types.SerializeTransaction(tx)
+	assert.Nil(t, err)
+	var txHash string
+	assert.NoError(t, json.Unmarshal(call(t, s, "eth_sendRawTransaction", "0x"+hex.EncodeToString(data)).Result, &txHash))
+	assert.Equal(t, json.RawMessage("null"), call(t, s, "eth_getTransactionReceipt", txHash).Result)
+	var pending EthTransaction
+	assert.NoError(t, json.Unmarshal(call(t, s, "eth_getTransactionByHash", txHash).Result, &pending))
+	assert.Equal(t, "0x"+address.String(), pending.From)
+	assert.Nil(t, pending.BlockNumber)
+
+	block, err = bc.BuildBlock(producer, mempool.Pending())
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+
+	var receipt EthReceipt
+	assert.NoError(t, json.Unmarshal(call(t, s, "eth_getTransactionReceipt", txHash).Result, &receipt))
+	assert.Equal(t, "0x1", receipt.Status)
+	assert.Equal(t, "0x2", receipt.BlockNumber)
+	assert.Equal(t, "0x1", receipt.TransactionIndex)
+	assert.Equal(t, "0x2", receipt.EffectiveGasPrice)
+
+	var encoded EthBlock
+	assert.NoError(t, json.Unmarshal(call(t, s, "eth_getBlockByNumber", "latest", false).Result, &encoded))
+	assert.Equal(t, "0x2", encoded.Number)
+	assert.Equal(t, receipt.BlockHash, encoded.Hash)
+	assert.Equal(t, []interface{}{encoded.Transactions[0], txHash}, encoded.Transactions)
+	assert.NoError(t, json.Unmarshal(call(t, s, "eth_getBlockByHash", encoded.ParentHash, true).Result, &encoded))
+	assert.Equal(t, "0x1", encoded.Number)
+	assert.Len(t, encoded.Transactions, 1)
+	assert.Equal(t, json.RawMessage("null"), call(t, s, "eth_getBlockByNumber", "0x10", false).Result)
+	assert.Equal(t, json.RawMessage("null"), call(t, s, "eth_getBlockByNumber", "0xffffffffffffffff", false).Result)
+	assert.Equal(t, CodeInvalidParams, call(t, s, "eth_getBlockByNumber", "next", false).Error.Code)
+}
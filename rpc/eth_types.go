@@ -0,0 +1,410 @@
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// Marvin has no gas, every transaction is reported as using one unit of gas priced at the transaction fee
+const ethTxGas = 1
+
+var (
+	// ethEmptyUncleHash is the keccak256 of the RLP encoding of an empty list, the uncles hash of every block
+	ethEmptyUncleHash = "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347"
+	// ethZeroHash fills the roots Marvin does not have
+	ethZeroHash = encodeBytes(make([]byte, 32))
+	// ethNativeAddress is the address of the logs of the value transfers
+	ethNativeAddress = make([]byte, 20)
+	// ethTransferTopic is the keccak256 of the ERC-20 Transfer(address,address,uint256) event signature
+	ethTransferTopic, _ = hex.DecodeString("ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+)
+
+// EthHeader is the Ethereum JSON encoding of a block header
+type EthHeader struct {
+	Number           string `json:"number"`
+	Hash             string `json:"hash"`
+	ParentHash       string `json:"parentHash"`
+	Nonce            string `json:"nonce"`
+	Sha3Uncles       string `json:"sha3Uncles"`
+	LogsBloom        string `json:"logsBloom"`
+	TransactionsRoot string `json:"transactionsRoot"`
+	StateRoot        string `json:"stateRoot"`
+	ReceiptsRoot     string `json:"receiptsRoot"`
+	Miner            string `json:"miner"`
+	Difficulty       string `json:"difficulty"`
+	ExtraData        string `json:"extraData"`
+	GasLimit         string `json:"gasLimit"`
+	GasUsed          string `json:"gasUsed"`
+	Timestamp        string `json:"timestamp"`
+}
+
+// EthBlock is the Ethereum JSON encoding of a block. The transactions are their hashes, or the full transactions when requested.
+type EthBlock struct {
+	EthHeader
+	TotalDifficulty string        `json:"totalDifficulty"`
+	Size            string        `json:"size"`
+	Transactions    []interface{} `json:"transactions"`
+	Uncles          []string      `json:"uncles"`
+}
+
+// EthTransaction is the Ethereum JSON encoding of a transaction. The block fields are null while the transaction is pending.
+// The Ed25519 signature is split in the r and s fields.
+type EthTransaction struct {
+	Hash             string  `json:"hash"`
+	Nonce            string  `json:"nonce"`
+	BlockHash        *string `json:"blockHash"`
+	BlockNumber      *string `json:"blockNumber"`
+	TransactionIndex *string `json:"transactionIndex"`
+	From             string  `json:"from"`
+	To               string  `json:"to"`
+	Value            string  `json:"value"`
+	Gas              string  `json:"gas"`
+	GasPrice         string  `json:"gasPrice"`
+	Input            string  `json:"input"`
+	Type             string  `json:"type"`
+	ChainID          string  `json:"chainId"`
+	V                string  `json:"v"`
+	R                string  `json:"r"`
+	S                string  `json:"s"`
+}
+
+// EthReceipt is the Ethereum JSON encoding of the receipt of a transaction included in a block.
+// Only the transactions that apply are included in Marvin blocks, so the status is always successful.
+type EthReceipt struct {
+	TransactionHash   string    `json:"transactionHash"`
+	TransactionIndex  string    `json:"transactionIndex"`
+	BlockHash         string    `json:"blockHash"`
+	BlockNumber       string    `json:"blockNumber"`
+	From              string    `json:"from"`
+	To                string    `json:"to"`
+	CumulativeGasUsed string    `json:"cumulativeGasUsed"`
+	GasUsed           string    `json:"gasUsed"`
+	EffectiveGasPrice string    `json:"effectiveGasPrice"`
+	ContractAddress   *string   `json:"contractAddress"`
+	Logs              []*EthLog `json:"logs"`
+	LogsBloom         string    `json:"logsBloom"`
+	Type              string    `json:"type"`
+	Status            string    `json:"status"`
+}
+
+// EthLog is the Ethereum JSON encoding of a log. Marvin has no contracts, every value transfer is logged
+// like an ERC-20 Transfer event emitted by the zero address, with the sender and recipient as topics
+// and the value as data. The sender of a coinbase transaction is the zero address.
+type EthLog struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+
+	// address and topics are the raw log fields filters are matched against
+	address []byte
+	topics  [][]byte
+}
+
+// EthChainID returns the numeric Ethereum chain ID of the Marvin chain ID, the first 4 bytes of its sha256 hash
+func EthChainID(chainID string) uint64 {
+	hash := sha256.Sum256([]byte(chainID))
+	return uint64(binary.BigEndian.Uint32(hash[:4]))
+}
+
+// NewEthHeader encodes the header of the block
+func NewEthHeader(b *proto.Block) (*EthHeader, error) {
+	hash, err := types.HashBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	miner, err := ethAddress(b.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := ethBlockLogs(b)
+	if err != nil {
+		return nil, err
+	}
+	header := b.Header
+	nonce := make([]byte, 8)
+	binary.BigEndian.PutUint64(nonce, header.GetNonce())
+
+	return &EthHeader{
+		Number:           encodeUint(header.GetHeight()),
+		Hash:             encodeBytes(hash),
+		ParentHash:       encodeBytes(header.GetPrevBlockHash()),
+		Nonce:            encodeBytes(nonce),
+		Sha3Uncles:       ethEmptyUncleHash,
+		LogsBloom:        encodeBytes(ethBloom(logs)),
+		TransactionsRoot: encodeBytes(header.GetTxHash()),
+		StateRoot:        ethZeroHash,
+		ReceiptsRoot:     ethZeroHash,
+		Miner:            miner,
+		Difficulty:       encodeUint(uint64(header.GetDifficulty())),
+		ExtraData:        encodeBytes(header.GetExtraData()),
+		GasLimit:         encodeUint(uint64(len(b.Transactions) * ethTxGas)),
+		GasUsed:          encodeUint(uint64(len(b.Transactions) * ethTxGas)),
+		Timestamp:        encodeUint(uint64(header.GetTimestamp()) / 1e9),
+	}, nil
+}
+
+// NewEthBlock encodes the block, with the full transactions or only their hashes
+func NewEthBlock(b *proto.Block, fullTxs bool, chainID uint64) (*EthBlock, error) {
+	header, err := NewEthHeader(b)
+	if err != nil {
+		return nil, err
+	}
+	data, err := types.SerializeBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := types.HashBlock(b)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &EthBlock{
+		EthHeader:       *header,
+		TotalDifficulty: encodeUint(0),
+		Size:            encodeUint(uint64(len(data))),
+		Transactions:    make([]interface{}, 0, len(b.Transactions)),
+		Uncles:          []string{},
+	}
+	for i, tx := range b.Transactions {
+		if !fullTxs {
+			txHash, err := types.HashTransaction(tx)
+			if err != nil {
+				return nil, err
+			}
+			block.Transactions = append(block.Transactions, encodeBytes(txHash))
+			continue
+		}
+		encoded, err := NewEthTransaction(tx, &core.TxLocation{BlockHash: hash, Height: b.Header.GetHeight(), Index: i}, chainID)
+		if err != nil {
+			return nil, err
+		}
+		block.Transactions = append(block.Transactions, encoded)
+	}
+	return block, nil
+}
+
+// NewEthTransaction encodes the transaction at the location, a nil location for a pending transaction
+func NewEthTransaction(tx *proto.Transaction, location *core.TxLocation, chainID uint64) (*EthTransaction, error) {
+	hash, err := types.HashTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := ethAccounts(tx)
+	if err != nil {
+		return nil, err
+	}
+	r, s := make([]byte, 32), make([]byte, 32)
+	if len(tx.Signature) == 64 {
+		copy(r, tx.Signature[:32])
+		copy(s, tx.Signature[32:])
+	}
+
+	encoded := &EthTransaction{
+		Hash:     encodeBytes(hash),
+		Nonce:    encodeUint(uint64(tx.Nonce)),
+		From:     from,
+		To:       to,
+		Value:    encodeUint(tx.Value),
+		Gas:      encodeUint(ethTxGas),
+		GasPrice: encodeUint(tx.Fee),
+		Input:    encodeBytes(tx.Data),
+		Type:     encodeUint(uint64(tx.Type)),
+		ChainID:  encodeUint(chainID),
+		V:        encodeUint(0),
+		R:        encodeBytes(r),
+		S:        encodeBytes(s),
+	}
+	if location != nil {
+		blockHash, height, index := encodeBytes(location.BlockHash), encodeUint(location.Height), encodeUint(uint64(location.Index))
+		encoded.BlockHash, encoded.BlockNumber, encoded.TransactionIndex = &blockHash, &height, &index
+	}
+	return encoded, nil
+}
+
+// NewEthReceipt encodes the receipt of the transaction at the index of the block
+func NewEthReceipt(b *proto.Block, index int) (*EthReceipt, error) {
+	tx := b.Transactions[index]
+	hash, err := types.HashTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	blockHash, err := types.HashBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := ethAccounts(tx)
+	if err != nil {
+		return nil, err
+	}
+	blockLogs, err := ethBlockLogs(b)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*EthLog{}
+	for _, log := range blockLogs {
+		if log.TransactionHash == encodeBytes(hash) {
+			logs = append(logs, log)
+		}
+	}
+
+	return &EthReceipt{
+		TransactionHash:   encodeBytes(hash),
+		TransactionIndex:  encodeUint(uint64(index)),
+		BlockHash:         encodeBytes(blockHash),
+		BlockNumber:       encodeUint(b.Header.GetHeight()),
+		From:              from,
+		To:                to,
+		CumulativeGasUsed: encodeUint(uint64((index + 1) * ethTxGas)),
+		GasUsed:           encodeUint(ethTxGas),
+		EffectiveGasPrice: encodeUint(tx.Fee),
+		Logs:              logs,
+		LogsBloom:         encodeBytes(ethBloom(logs)),
+		Type:              encodeUint(uint64(tx.Type)),
+		Status:            encodeUint(1),
+	}, nil
+}
+
+// ethBlockLogs returns the logs of the value transfers of the block
+func ethBlockLogs(b *proto.Block) ([]*EthLog, error) {
+	blockHash, err := types.HashBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	logs := []*EthLog{}
+	for i, tx := range b.Transactions {
+		if tx.Value == 0 || types.IsVote(tx) {
+			continue
+		}
+		txHash, err := types.HashTransaction(tx)
+		if err != nil {
+			return nil, err
+		}
+		from := make([]byte, 20)
+		if len(tx.From) > 0 {
+			address, err := types.AccountAddress(tx.From)
+			if err != nil {
+				return nil, err
+			}
+			from = address.Bytes()
+		}
+		address, err := types.AccountAddress(tx.To)
+		if err != nil {
+			return nil, err
+		}
+		to := address.Bytes()
+
+		value := make([]byte, 32)
+		binary.BigEndian.PutUint64(value[24:], tx.Value)
+		topics := [][]byte{ethTransferTopic, leftPad32(from), leftPad32(to)}
+		log := &EthLog{
+			Address:          encodeBytes(ethNativeAddress),
+			Topics:           make([]string, 0, len(topics)),
+			Data:             encodeBytes(value),
+			BlockNumber:      encodeUint(b.Header.GetHeight()),
+			BlockHash:        encodeBytes(blockHash),
+			TransactionHash:  encodeBytes(txHash),
+			TransactionIndex: encodeUint(uint64(i)),
+			LogIndex:         encodeUint(uint64(len(logs))),
+			address:          ethNativeAddress,
+			topics:           topics,
+		}
+		for _, topic := range topics {
+			log.Topics = append(log.Topics, encodeBytes(topic))
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
+
+// ethBloom returns the Ethereum bloom filter of the addresses and topics of the logs
+func ethBloom(logs []*EthLog) []byte {
+	bloom := make([]byte, 256)
+	add := func(data []byte) {
+		hash := sha3.NewLegacyKeccak256()
+		hash.Write(data)
+		sum := hash.Sum(nil)
+		// Three bits of the 2048 bits bloom are set, from the first three pairs of bytes of the hash
+		for i := 0; i < 6; i += 2 {
+			bit := (uint(sum[i])<<8 | uint(sum[i+1])) & 2047
+			bloom[255-bit/8] |= 1 << (bit % 8)
+		}
+	}
+	for _, log := range logs {
+		add(log.address)
+		for _, topic := range log.topics {
+			add(topic)
+		}
+	}
+	return bloom
+}
+
+// leftPad32 pads the data with leading zeros to 32 bytes
+func leftPad32(data []byte) []byte {
+	padded := make([]byte, 32)
+	copy(padded[32-len(data):], data)
+	return padded
+}
+
+// ethAccounts returns the sender and recipient addresses of the transaction, the sender of a coinbase transaction is the zero address
+func ethAccounts(tx *proto.Transaction) (string, string, error) {
+	from := encodeBytes(make([]byte, 20))
+	if len(tx.From) > 0 {
+		address, err := ethAddress(tx.From)
+		if err != nil {
+			return "", "", err
+		}
+		from = address
+	}
+	to, err := ethAddress(tx.To)
+	if err != nil {
+		return "", "", err
+	}
+	return from, to, nil
+}
+
+// ethAddress returns the address of the account given by its public key or address
+func ethAddress(account []byte) (string, error) {
+	address, err := types.AccountAddress(account)
+	if err != nil {
+		return "", err
+	}
+	return encodeBytes(address.Bytes()), nil
+}
+
+// encodeUint encodes an Ethereum quantity, hex with the 0x prefix and no leading zeros
+func encodeUint(value uint64) string {
+	return "0x" + strconv.FormatUint(value, 16)
+}
+
+// decodeUint decodes an Ethereum quantity
+func decodeUint(value string) (uint64, error) {
+	if !strings.HasPrefix(value, "0x") || len(value) < 3 {
+		return 0, fmt.Errorf("invalid quantity (%s)", value)
+	}
+	n, err := strconv.ParseUint(value[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity (%s)", value)
+	}
+	return n, nil
+}
+
+// encodeBytes encodes Ethereum data, hex with the 0x prefix
+func encodeBytes(data []byte) string {
+	return "0x" + hex.EncodeToString(data)
+}
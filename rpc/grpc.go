@@ -0,0 +1,226 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// DefaultGRPCAddr is the default address of the gRPC server
+const DefaultGRPCAddr = "127.0.0.1:9090"
+
+// grpcStopTimeout is how long stopping the gRPC server waits for the calls being handled
+const grpcStopTimeout = 5 * time.Second
+
+// ServiceChain is the blockchain served by the gRPC service, which streams its new blocks
+type ServiceChain interface {
+	Chain
+	SubscriptionChain
+}
+
+// NodeService serves the gRPC NodeService of the blockchain and the mempool
+type NodeService struct {
+	proto.UnimplementedNodeServiceServer
+
+	api *API
+
+	// added is closed and replaced when a block is added, waking up the block streams
+	lock  sync.Mutex
+	added chan struct{}
+}
+
+// NewNodeService creates the gRPC service of the chain and the mempool
+func NewNodeService(chain ServiceChain, mempool TxPool) *NodeService {
+	s := &NodeService{
+		api:   NewAPI(chain, mempool),
+		added: make(chan struct{}),
+	}
+	chain.OnBlockAdded(func(b *proto.Block) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		close(s.added)
+		s.added = make(chan struct{})
+	})
+	return s
+}
+
+// GetBlock returns the block with the hash or at the height
+func (s *NodeService) GetBlock(ctx context.Context, request *proto.BlockRequest) (*proto.Block, error) {
+	return s.block(request)
+}
+
+// GetHeader returns the header of the block with the hash or at the height
+func (s *NodeService) GetHeader(ctx context.Context, request *proto.BlockRequest) (*proto.Header, error) {
+	b, err := s.block(request)
+	if err != nil {
+		return nil, err
+	}
+	return b.Header, nil
+}
+
+// StreamBlocks sends the blocks from the height, then every block added to the blockchain until the call is canceled.
+// The height can be the one of the next block at most.
+func (s *NodeService) StreamBlocks(request *proto.StreamBlocksRequest, stream proto.NodeService_StreamBlocksServer) error {
+	if height := s.api.chain.Height(); request.FromHeight > uint64(height)+1 {
+		return status.Errorf(codes.InvalidArgument, "height %d is after the next block %d", request.FromHeight, height+1)
+	}
+	next := int(request.FromHeight)
+	for {
+		// Get the channel before reading the height so no block added in between is missed
+		added := s.blockAdded()
+		for ; next <= s.api.chain.Height(); next++ {
+			b, err := s.api.chain.GetBlockByHeight(next)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := stream.Send(b); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-added:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// SubmitTransaction verifies the signed transaction and adds it to the mempool
+func (s *NodeService) SubmitTransaction(ctx context.Context, request *proto.SubmitTransactionRequest) (*proto.SubmitTransactionResponse, error) {
+	tx := request.Transaction
+	if tx == nil {
+		return nil, status.Error(codes.InvalidArgument, "transaction is required")
+	}
+	if err := s.api.chain.Params().ValidateTransaction(tx); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.api.submit(tx); err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			return nil, status.Error(codes.FailedPrecondition, rpcErr.Message)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	hash, err := types.HashTransaction(tx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &proto.SubmitTransactionResponse{Hash: hash}, nil
+}
+
+// GetAccount returns the balance and the nonce of the account
+func (s *NodeService) GetAccount(ctx context.Context, request *proto.AccountRequest) (*proto.Account, error) {
+	address, err := types.AccountAddress(request.Address)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	state := s.api.chain.State()
+	return &proto.Account{
+		Address: address.Bytes(),
+		Balance: state.Balance(address),
+		Nonce:   state.Nonce(address),
+	}, nil
+}
+
+// block returns the block selected by the request
+func (s *NodeService) block(request *proto.BlockRequest) (*proto.Block, error) {
+	switch selector := request.Block.(type) {
+	case *proto.BlockRequest_Hash:
+		b, err := s.api.chain.GetBlockByHash(selector.Hash)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return b, nil
+	case *proto.BlockRequest_Height:
+		// The height is checked before it is converted, a height above the blockchain may not fit in an int
+		if height := s.api.chain.Height(); selector.Height > uint64(height) {
+			return nil, status.Errorf(codes.NotFound, "blockchain does not have block at height (%d)", selector.Height)
+		}
+		b, err := s.api.chain.GetBlockByHeight(int(selector.Height))
+		if err != nil {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return b, nil
+	default:
+		return nil, status.Error(codes.InvalidArgument, "block hash or height is required")
+	}
+}
+
+// blockAdded returns the channel closed when the next block is added
+func (s *NodeService) blockAdded() chan struct{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.added
+}
+
+// GRPCServer serves the node service over gRPC
+type GRPCServer struct {
+	addr     string
+	server   *grpc.Server
+	listener net.Listener
+}
+
+// NewGRPCServer creates the gRPC server of the service listening on the address
+func NewGRPCServer(addr string, service *NodeService) *GRPCServer {
+	server := grpc.NewServer()
+	proto.RegisterNodeServiceServer(server, service)
+	return &GRPCServer{
+		addr:   addr,
+		server: server,
+	}
+}
+
+// Start starts serving the calls on the listen address
+func (s *GRPCServer) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Error().Err(err).Msg("grpc server stopped")
+		}
+	}()
+
+	log.Info().Str("addr", s.Addr()).Msg("grpc server started")
+	return nil
+}
+
+// Addr returns the address the server listens on
+func (s *GRPCServer) Addr() string {
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop stops accepting calls and waits for the calls being handled, the block streams are canceled
+func (s *GRPCServer) Stop() {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(grpcStopTimeout):
+		s.server.Stop()
+	}
+}
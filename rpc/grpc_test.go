@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestNodeService(t *testing.T) {
+	bc := core.NewBlockchain(core.NewMemorystore())
+	mempool := core.NewMempool()
+	s := NewGRPCServer("127.0.0.1:0", NewNodeService(bc, mempool))
+	assert.NoError(t, s.Start())
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(s.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nil(t, err)
+	defer conn.Close()
+	client := proto.NewNodeServiceClient(conn)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	block, err := bc.BuildBlock(producer, nil)
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	blockHash, err := types.HashBlock(block)
+	assert.Nil(t, err)
+
+	b, err := client.GetBlock(ctx, &proto.BlockRequest{Block: &proto.BlockRequest_Height{Height: 1}})
+	assert.Nil(t, err)
+	assert.Len(t, b.Transactions, 1)
+	header, err := client.GetHeader(ctx, &proto.BlockRequest{Block: &proto.BlockRequest_Hash{Hash: blockHash}})
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), header.Height)
+	_, err = client.GetBlock(ctx, &proto.BlockRequest{Block: &proto.BlockRequest_Height{Height: 5}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetBlock(ctx, &proto.BlockRequest{Block: &proto.BlockRequest_Height{Height: math.MaxUint64}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetBlock(ctx, &proto.BlockRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	address := producer.PublicKey().Address()
+	account, err := client.GetAccount(ctx, &proto.AccountRequest{Address: producer.PublicKey().Bytes()})
+	assert.Nil(t, err)
+	assert.Equal(t, address.Bytes(), account.Address)
+	assert.Equal(t, bc.State().Balance(address), account.Balance)
+	_, err = client.GetAccount(ctx, &proto.AccountRequest{Address: []byte{1, 2}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Submit a transfer, added to the mempool once
+	tx := &proto.Transaction{From: producer.PublicKey().Bytes(), To: make([]byte, 20), Value: 10, Fee: 1, Nonce: 1}
+	assert.NoError(t, types.SignTransaction(producer, tx))
+	submitted, err := client.SubmitTransaction(ctx, &proto.SubmitTransactionRequest{Transaction: tx})
+	assert.Nil(t, err)
+	txHash, err := types.HashTransaction(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, txHash, submitted.Hash)
+	assert.Equal(t, 1, mempool.Len())
+	_, err = client.SubmitTransaction(ctx, &proto.SubmitTransactionRequest{Transaction: tx})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// A stream cannot start after the next block
+	stream, err := client.StreamBlocks(ctx, &proto.StreamBlocksRequest{FromHeight: math.MaxUint64})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// The stream sends the stored blocks, then the new ones
+	stream, err = client.StreamBlocks(ctx, &proto.StreamBlocksRequest{FromHeight: 1})
+	assert.Nil(t, err)
+	streamed, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), streamed.Header.Height)
+
+	block, err = bc.BuildBlock(producer, mempool.Pending())
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	streamed, err = stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), streamed.Header.Height)
+	assert.Len(t, streamed.Transactions, 2)
+}
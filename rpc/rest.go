@@ -0,0 +1,278 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+const (
+	// defaultPageSize is the number of blocks of a page when the limit is not given
+	defaultPageSize = 20
+	// maxPageSize is the maximum number of blocks of a page
+	maxPageSize = 100
+)
+
+// restHandler handles a GET request of the REST API and returns the value encoded in the response
+type restHandler func(r *http.Request) (interface{}, error)
+
+// restError is an error of the REST API, returned with its HTTP status
+type restError struct {
+	status  int
+	message string
+}
+
+func (e *restError) Error() string {
+	return e.message
+}
+
+// notFound returns a 404 error with the formatted message
+func notFound(format string, args ...interface{}) error {
+	return &restError{status: http.StatusNotFound, message: fmt.Sprintf(format, args...)}
+}
+
+// badRequest returns a 400 error with the formatted message
+func badRequest(format string, args ...interface{}) error {
+	return &restError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// REST serves the blockchain and the mempool as a read-only REST API. Blocks and transactions are
+// encoded with hex bytes, and the accounts are shown by their address derived from their public key.
+type REST struct {
+	api *API
+}
+
+// NewREST creates the REST API of the chain and the mempool
+func NewREST(chain Chain, mempool TxPool) *REST {
+	return &REST{
+		api: NewAPI(chain, mempool),
+	}
+}
+
+// Register registers the routes of the API on the server
+func (rest *REST) Register(server *Server) {
+	server.Handle("/blocks", rest.get(rest.blocks))
+	server.Handle("/blocks/", rest.get(rest.block))
+	server.Handle("/tx/", rest.get(rest.transaction))
+	server.Handle("/accounts/", rest.get(rest.account))
+	server.Handle("/mempool", rest.get(rest.mempool))
+	server.Handle("/search", rest.get(rest.search))
+}
+
+// blocks returns a page of blocks in descending height, without their transactions.
+// GET /blocks?from={height}&limit={count}, from the last block by default.
+func (rest *REST) blocks(r *http.Request) (interface{}, error) {
+	height := uint64(rest.api.chain.Height())
+	from := height
+	if value := r.URL.Query().Get("from"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, badRequest("invalid from height (%s)", value)
+		}
+		if n < from {
+			from = n
+		}
+	}
+	limit := defaultPageSize
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxPageSize {
+			return nil, badRequest("limit must be between 1 and %d (%s)", maxPageSize, value)
+		}
+		limit = n
+	}
+
+	page := &BlockPage{Height: height, Blocks: make([]*RESTBlock, 0, limit)}
+	for next := int64(from); next >= 0; next-- {
+		if len(page.Blocks) == limit {
+			nextFrom := uint64(next)
+			page.Next = &nextFrom
+			break
+		}
+		b, err := rest.api.chain.GetBlockByHeight(int(next))
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := NewRESTBlock(b, false)
+		if err != nil {
+			return nil, err
+		}
+		page.Blocks = append(page.Blocks, encoded)
+	}
+	return page, nil
+}
+
+// block returns the block with its transactions. GET /blocks/{height}, /blocks/latest or /blocks/hash/{hash}
+func (rest *REST) block(r *http.Request) (interface{}, error) {
+	param := strings.TrimPrefix(r.URL.Path, "/blocks/")
+	if hashParam, ok := strings.CutPrefix(param, "hash/"); ok {
+		hash, err := restHex(hashParam)
+		if err != nil {
+			return nil, err
+		}
+		b, err := rest.api.chain.GetBlockByHash(hash)
+		if err != nil {
+			return nil, notFound("block with hash (%s) not found", hashParam)
+		}
+		return NewRESTBlock(b, true)
+	}
+
+	height := rest.api.chain.Height()
+	if param != "latest" {
+		n, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			return nil, badRequest("invalid block height (%s)", param)
+		}
+		height = int(n)
+	}
+	b, err := rest.api.chain.GetBlockByHeight(height)
+	if err != nil {
+		return nil, notFound("block at height %d not found", height)
+	}
+	return NewRESTBlock(b, true)
+}
+
+// transaction returns the transaction from the blockchain or the mempool. GET /tx/{hash}
+func (rest *REST) transaction(r *http.Request) (interface{}, error) {
+	hash, err := restHex(strings.TrimPrefix(r.URL.Path, "/tx/"))
+	if err != nil {
+		return nil, err
+	}
+	return rest.findTransaction(hash)
+}
+
+// account returns the balance and the nonce of the account. GET /accounts/{address or public key}
+func (rest *REST) account(r *http.Request) (interface{}, error) {
+	account, err := restHex(strings.TrimPrefix(r.URL.Path, "/accounts/"))
+	if err != nil {
+		return nil, err
+	}
+	return rest.findAccount(account)
+}
+
+// mempool returns the number of pending transactions. GET /mempool
+func (rest *REST) mempool(r *http.Request) (interface{}, error) {
+	return &RESTMempool{Size: rest.api.mempool.Len()}, nil
+}
+
+// search finds the block, the transaction or the account of the query. GET /search?q={height, hash or address}.
+// A 32 bytes value is looked up as a block hash, then as a transaction hash, then as an account public key.
+func (rest *REST) search(r *http.Request) (interface{}, error) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		return nil, badRequest("search query is required")
+	}
+
+	if height, err := strconv.ParseUint(query, 10, 32); err == nil {
+		b, err := rest.api.chain.GetBlockByHeight(int(height))
+		if err != nil {
+			return nil, notFound("block at height %d not found", height)
+		}
+		return blockResult(b)
+	}
+
+	value, err := restHex(query)
+	if err != nil {
+		return nil, err
+	}
+	if b, err := rest.api.chain.GetBlockByHash(value); err == nil {
+		return blockResult(b)
+	}
+	if tx, err := rest.findTransaction(value); err == nil {
+		return &SearchResult{Type: "transaction", Transaction: tx}, nil
+	}
+	if account, err := rest.findAccount(value); err == nil {
+		return &SearchResult{Type: "account", Account: account}, nil
+	}
+	return nil, notFound("no block, transaction or account found for (%s)", query)
+}
+
+// findTransaction returns the transaction with the hash from the blockchain or the mempool
+func (rest *REST) findTransaction(hash []byte) (*RESTTransaction, error) {
+	if tx, location, err := rest.api.chain.GetTransaction(hash); err == nil {
+		return NewRESTTransaction(tx, &location)
+	}
+	if tx := rest.api.mempool.Get(hash); tx != nil {
+		return NewRESTTransaction(tx, nil)
+	}
+	return nil, notFound("transaction with hash (%s) not found", hex.EncodeToString(hash))
+}
+
+// findAccount returns the state of the account given by its address or its public key
+func (rest *REST) findAccount(account []byte) (*RESTAccount, error) {
+	address, err := types.AccountAddress(account)
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+
+	state := rest.api.chain.State()
+	return &RESTAccount{
+		Address: address.String(),
+		Balance: state.Balance(address),
+		Nonce:   state.Nonce(address),
+	}, nil
+}
+
+// blockResult returns the search result of the block, without its transactions
+func blockResult(b *proto.Block) (*SearchResult, error) {
+	encoded, err := NewRESTBlock(b, false)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchResult{Type: "block", Block: encoded}, nil
+}
+
+// get serves the handler on the GET requests, encoding its result or its error as JSON
+func (rest *REST) get(handler restHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJSON(w, http.StatusMethodNotAllowed, restErrorBody{Error: "method not allowed"})
+			return
+		}
+
+		result, err := handler(r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if restErr, ok := err.(*restError); ok {
+				status = restErr.status
+			} else {
+				log.Error().Err(err).Str("path", r.URL.Path).Msg("rest request failed")
+			}
+			writeJSON(w, status, restErrorBody{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+}
+
+// restErrorBody is the JSON body of an error response
+type restErrorBody struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes the value as the JSON body of the response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Debug().Err(err).Msg("failed to write http response")
+	}
+}
+
+// restHex decodes a hex path parameter, with or without the 0x prefix
+func restHex(value string) ([]byte, error) {
+	data, err := decodeHex(value)
+	if err != nil || len(data) == 0 {
+		return nil, badRequest("invalid hex value (%s)", value)
+	}
+	return data, nil
+}
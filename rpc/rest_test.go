@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// get sends a GET request for the path to the server and decodes the JSON response into the value
+func get(t *testing.T, s *Server, path string, value interface{}) int {
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	if value != nil {
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), value))
+	}
+	return recorder.Code
+}
+
+func TestREST(t *testing.T) {
+	bc := core.NewBlockchain(core.NewMemorystore())
+	mempool := core.NewMempool()
+	s := NewServer(DefaultConfig)
+	NewREST(bc, mempool).Register(s)
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		block, err := bc.BuildBlock(producer, nil)
+		assert.Nil(t, err)
+		assert.NoError(t, bc.AddBlock(block))
+	}
+	address := producer.PublicKey().Address()
+
+	var block RESTBlock
+	assert.Equal(t, http.StatusOK, get(t, s, "/blocks/2", &block))
+	assert.Equal(t, uint64(2), block.Height)
+	assert.Equal(t, address.String(), block.Producer)
+	assert.Len(t, block.Transactions, 1)
+	assert.Equal(t, address.String(), block.Transactions[0].ToAddress)
+	assert.Equal(t, "", block.Transactions[0].FromAddress)
+
+	var byHash RESTBlock
+	assert.Equal(t, http.StatusOK, get(t, s, "/blocks/hash/0x"+block.Hash, &byHash))
+	assert.Equal(t, block.Hash, byHash.Hash)
+	assert.Equal(t, http.StatusOK, get(t, s, "/blocks/latest", &byHash))
+	assert.Equal(t, uint64(3), byHash.Height)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/blocks/9", nil))
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/blocks/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/blocks/hash/zz", nil))
+
+	// Pages go down from the last block to the genesis block
+	var page BlockPage
+	assert.Equal(t, http.StatusOK, get(t, s, "/blocks?limit=2", &page))
+	assert.Equal(t, uint64(3), page.Height)
+	assert.Len(t, page.Blocks, 2)
+	assert.Equal(t, uint64(3), page.Blocks[0].Height)
+	assert.Equal(t, 1, page.Blocks[0].TxCount)
+	assert.Nil(t, page.Blocks[0].Transactions)
+	assert.Equal(t, uint64(1), *page.Next)
+	page = BlockPage{}
+	assert.Equal(t, http.StatusOK, get(t, s, "/blocks?from=1&limit=2", &page))
+	assert.Len(t, page.Blocks, 2)
+	assert.Equal(t, uint64(0), page.Blocks[1].Height)
+	assert.Nil(t, page.Next)
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/blocks?limit=1000", nil))
+
+	// A pending transaction, then the same transaction in a block
+	tx := &proto.Transaction{From: producer.PublicKey().Bytes(), To: make([]byte, 20), Value: 10, Fee: 1, Nonce: 1}
+	assert.NoError(t, types.SignTransaction(producer, tx))
+	assert.NoError(t, mempool.Add(tx))
+	txHash, err := types.HashTransaction(tx)
+	assert.Nil(t, err)
+
+	var pending RESTTransaction
+	assert.Equal(t, http.StatusOK, get(t, s, "/tx/"+hex.EncodeToString(txHash), &pending))
+	assert.Equal(t, address.String(), pending.FromAddress)
+	assert.Nil(t, pending.BlockHeight)
+	var pool RESTMempool
+	assert.Equal(t, http.StatusOK, get(t, s, "/mempool", &pool))
+	assert.Equal(t, 1, pool.Size)
+
+	b, err := bc.BuildBlock(producer, mempool.Pending())
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(b))
+	var included RESTTransaction
+	assert.Equal(t, http.StatusOK, get(t, s, "/tx/"+hex.EncodeToString(txHash), &included))
+	assert.Equal(t, uint64(4), *included.BlockHeight)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/tx/00ff", nil))
+
+	var account RESTAccount
+	assert.Equal(t, http.StatusOK, get(t, s, "/accounts/"+hex.EncodeToString(producer.PublicKey().Bytes()), &account))
+	assert.Equal(t, address.String(), account.Address)
+	assert.Equal(t, bc.State().Balance(address), account.Balance)
+	assert.Equal(t, int64(1), account.Nonce)
+	assert.Equal(t, http.StatusBadRequest, get(t, s, "/accounts/00ff", nil))
+
+	// Search by height, block hash, transaction hash and public key
+	var result SearchResult
+	assert.Equal(t, http.StatusOK, get(t, s, "/search?q=2", &result))
+	assert.Equal(t, "block", result.Type)
+	assert.Equal(t, block.Hash, result.Block.Hash)
+	result = SearchResult{}
+	assert.Equal(t, http.StatusOK, get(t, s, "/search?q="+block.Hash, &result))
+	assert.Equal(t, uint64(2), result.Block.Height)
+	result = SearchResult{}
+	assert.Equal(t, http.StatusOK, get(t, s, "/search?q="+hex.EncodeToString(txHash), &result))
+	assert.Equal(t, "transaction", result.Type)
+	assert.Equal(t, hex.EncodeToString(txHash), result.Transaction.Hash)
+	result = SearchResult{}
+	assert.Equal(t, http.StatusOK, get(t, s, "/search?q="+hex.EncodeToString(producer.PublicKey().Bytes()), &result))
+	assert.Equal(t, "account", result.Type)
+	assert.Equal(t, address.String(), result.Account.Address)
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/search?q=99", nil))
+	assert.Equal(t, http.StatusNotFound, get(t, s, "/search?q=00ff", nil))
+
+	// The JSON-RPC requests are still served on the root path
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/blocks", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	code, _ := post(t, s, `{"jsonrpc":"2.0","id":1,"method":"marvin_height","params":[]}`)
+	assert.Equal(t, http.StatusOK, code)
+}
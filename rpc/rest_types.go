@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// RESTBlock is the REST encoding of a block, adding the address of its producer and its time
+// to the JSON-RPC encoding. The transactions are left out of the block pages.
+type RESTBlock struct {
+	*Block
+	Producer     string             `json:"producer"`
+	Time         string             `json:"time"`
+	TxCount      int                `json:"txCount"`
+	Transactions []*RESTTransaction `json:"transactions,omitempty"`
+}
+
+// RESTTransaction is the REST encoding of a transaction, adding the addresses of its sender and its recipient
+// to the JSON-RPC encoding. The sender address is empty for a coinbase transaction.
+type RESTTransaction struct {
+	*Transaction
+	FromAddress string `json:"fromAddress"`
+	ToAddress   string `json:"toAddress"`
+}
+
+// RESTAccount is the state of an account after the last block
+type RESTAccount struct {
+	Address string `json:"address"`
+	Balance uint64 `json:"balance"`
+	Nonce   int64  `json:"nonce"`
+}
+
+// RESTMempool is the state of the mempool
+type RESTMempool struct {
+	Size int `json:"size"`
+}
+
+// SearchResult is the block, the transaction or the account found by a search, given by its type
+type SearchResult struct {
+	Type        string           `json:"type"`
+	Block       *RESTBlock       `json:"block,omitempty"`
+	Transaction *RESTTransaction `json:"transaction,omitempty"`
+	Account     *RESTAccount     `json:"account,omitempty"`
+}
+
+// BlockPage is a page of blocks in descending height. Next is the height the next page starts from,
+// null after the genesis block.
+type BlockPage struct {
+	Height uint64       `json:"height"`
+	Blocks []*RESTBlock `json:"blocks"`
+	Next   *uint64      `json:"next"`
+}
+
+// NewRESTBlock encodes the block, with or without its transactions
+func NewRESTBlock(b *proto.Block, fullTxs bool) (*RESTBlock, error) {
+	block, err := NewBlock(b, false)
+	if err != nil {
+		return nil, err
+	}
+	encoded := &RESTBlock{
+		Block:   block,
+		Time:    time.Unix(0, b.Header.GetTimestamp()).UTC().Format(time.RFC3339Nano),
+		TxCount: len(b.Transactions),
+	}
+	if publicKey, err := crypto.PublicKeyFromBytes(b.PublicKey); err == nil {
+		encoded.Producer = publicKey.Address().String()
+	}
+	if !fullTxs {
+		return encoded, nil
+	}
+
+	hash, err := types.HashBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	encoded.Transactions = make([]*RESTTransaction, 0, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		encodedTx, err := NewRESTTransaction(tx, &core.TxLocation{BlockHash: hash, Height: b.Header.GetHeight(), Index: i})
+		if err != nil {
+			return nil, err
+		}
+		encoded.Transactions = append(encoded.Transactions, encodedTx)
+	}
+	return encoded, nil
+}
+
+// NewRESTTransaction encodes the transaction at the location, a nil location for a pending transaction
+func NewRESTTransaction(tx *proto.Transaction, location *core.TxLocation) (*RESTTransaction, error) {
+	encoded, err := NewTransaction(tx, location)
+	if err != nil {
+		return nil, err
+	}
+	return &RESTTransaction{
+		Transaction: encoded,
+		FromAddress: accountString(tx.From),
+		ToAddress:   accountString(tx.To),
+	}, nil
+}
+
+// accountString returns the hex address of the account public key or address, empty if there is none
+func accountString(account []byte) string {
+	address, err := types.AccountAddress(account)
+	if err != nil {
+		return ""
+	}
+	return address.String()
+}
@@ -0,0 +1,381 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeTransactionRejected is returned when a submitted transaction is not accepted by the node
+	CodeTransactionRejected = -32000
+)
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// NewError creates an error with the code and the formatted message
+func NewError(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Request is a JSON-RPC request, a request without ID is a notification which gets no response
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response, holding either the result or the error of the request
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Handler handles the params of a method call. Returning an *Error sets its code in the response,
+// any other error is returned as an internal error.
+type Handler func(params json.RawMessage) (interface{}, error)
+
+// connHandler handles the params of a method only available on WebSocket connections
+type connHandler func(conn *wsConn, params json.RawMessage) (interface{}, error)
+
+// Config holds the RPC server parameters
+type Config struct {
+	// ListenAddr is the address of the HTTP server
+	ListenAddr string
+	// MaxRequestSize is the maximum size of a request body in bytes
+	MaxRequestSize int64
+	// MaxBatchSize is the maximum number of requests in a batch
+	MaxBatchSize int
+	// ReadTimeout is how long reading a request can take
+	ReadTimeout time.Duration
+	// WriteTimeout is how long handling a request and writing its response can take
+	WriteTimeout time.Duration
+}
+
+// DefaultConfig is the RPC server configuration used when none is provided
+var DefaultConfig = Config{
+	ListenAddr:     "127.0.0.1:8545",
+	MaxRequestSize: 5 * 1024 * 1024,
+	MaxBatchSize:   100,
+	ReadTimeout:    30 * time.Second,
+	WriteTimeout:   30 * time.Second,
+}
+
+// nullID is the ID of the responses to requests whose ID could not be read
+var nullID = json.RawMessage("null")
+
+// Server is a JSON-RPC 2.0 server over HTTP and WebSocket. Requests are POSTed as a single request object
+// or as a batch array of requests, which gets an array of the responses. WebSocket connections send
+// the same requests as messages, and can also subscribe to the events of the node.
+type Server struct {
+	config Config
+
+	lock          sync.RWMutex
+	methods       map[string]Handler
+	connMethods   map[string]connHandler
+	subscriptions *Subscriptions
+	conns         map[*wsConn]struct{}
+
+	mux      *http.ServeMux
+	upgrader websocket.Upgrader
+	listener net.Listener
+	http     *http.Server
+}
+
+// NewServer creates a server with no methods
+func NewServer(config Config) *Server {
+	s := &Server{
+		config:      config,
+		methods:     make(map[string]Handler),
+		connMethods: make(map[string]connHandler),
+		conns:       make(map[*wsConn]struct{}),
+		mux:         http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/", s.serveRPC)
+	return s
+}
+
+// Register registers the handler of the method, replacing any handler already registered
+func (s *Server) Register(method string, handler Handler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.methods[method] = handler
+}
+
+// registerConn registers the handler of a method only available on WebSocket connections
+func (s *Server) registerConn(method string, handler connHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.connMethods[method] = handler
+}
+
+// Handle registers the HTTP handler of the path pattern, served next to the JSON-RPC requests POSTed to the root path
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start starts serving the requests on the listen address
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.config.ListenAddr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.http = &http.Server{
+		Handler:      s,
+		ReadTimeout:  s.config.ReadTimeout,
+		WriteTimeout: s.config.WriteTimeout,
+	}
+
+	go func() {
+		if err := s.http.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("rpc server stopped")
+		}
+	}()
+
+	log.Info().Str("addr", s.Addr()).Msg("rpc server started")
+	return nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.config.ListenAddr
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop stops accepting requests, closes the WebSocket connections and waits for the requests being handled
+func (s *Server) Stop() error {
+	if s.http == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.WriteTimeout)
+	defer cancel()
+
+	err := s.http.Shutdown(ctx)
+	s.lock.RLock()
+	conns := make([]*wsConn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.lock.RUnlock()
+	for _, conn := range conns {
+		conn.close()
+	}
+	return err
+}
+
+// ServeHTTP routes the request to the handler of its path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// serveRPC handles the JSON-RPC requests POSTed to the server and the WebSocket connections
+func (s *Server) serveRPC(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		s.serveWebSocket(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.config.MaxRequestSize))
+	if err != nil {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	response := s.handleBody(body, nil)
+	// Notifications get no response
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Debug().Err(err).Msg("failed to write rpc response")
+	}
+}
+
+// handleBody handles the single request or the batch of the body, sent on the WebSocket connection
+// or over HTTP when nil, and returns the response, or nil if there is none
+func (s *Server) handleBody(body []byte, conn *wsConn) interface{} {
+	if batch := bytes.TrimSpace(body); len(batch) > 0 && batch[0] == '[' {
+		return s.handleBatch(batch, conn)
+	}
+	return s.handleSingle(body, conn)
+}
+
+// handleSingle handles a single request and returns its response, or nil for a notification
+func (s *Server) handleSingle(body []byte, conn *wsConn) interface{} {
+	if !json.Valid(body) {
+		return errorResponse(nullID, NewError(CodeParseError, "parse error"))
+	}
+	if response := s.handle(body, conn); response != nil {
+		return response
+	}
+	return nil
+}
+
+// handleBatch handles the requests of a batch and returns their responses, or nil if they are all notifications
+func (s *Server) handleBatch(body []byte, conn *wsConn) interface{} {
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return errorResponse(nullID, NewError(CodeParseError, "parse error"))
+	}
+	if len(batch) == 0 {
+		return errorResponse(nullID, NewError(CodeInvalidRequest, "empty batch"))
+	}
+	if len(batch) > s.config.MaxBatchSize {
+		return errorResponse(nullID, NewError(CodeInvalidRequest, "batch of %d requests is over the limit of %d", len(batch), s.config.MaxBatchSize))
+	}
+
+	responses := make([]*Response, 0, len(batch))
+	for _, raw := range batch {
+		if response := s.handle(raw, conn); response != nil {
+			responses = append(responses, response)
+		}
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses
+}
+
+// handle calls the method of the request and returns the response, or nil for a notification
+func (s *Server) handle(raw json.RawMessage, conn *wsConn) *Response {
+	var request Request
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return errorResponse(nullID, NewError(CodeInvalidRequest, "invalid request"))
+	}
+	if request.ID != nil && !validID(request.ID) {
+		return errorResponse(nullID, NewError(CodeInvalidRequest, "id must be a string, a number or null"))
+	}
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		return errorResponse(responseID(request.ID), NewError(CodeInvalidRequest, "invalid request"))
+	}
+
+	result, err := s.call(request, conn)
+	if request.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return errorResponse(request.ID, err)
+	}
+	return &Response{JSONRPC: "2.0", ID: request.ID, Result: result}
+}
+
+// call runs the handler of the method, converting its result to JSON
+func (s *Server) call(request Request, conn *wsConn) (json.RawMessage, *Error) {
+	s.lock.RLock()
+	handler, ok := s.methods[request.Method]
+	connHandler, connOk := s.connMethods[request.Method]
+	s.lock.RUnlock()
+	if connOk {
+		if conn == nil {
+			return nil, NewError(CodeInvalidRequest, "method (%s) requires a WebSocket connection", request.Method)
+		}
+		handler, ok = func(params json.RawMessage) (interface{}, error) {
+			return connHandler(conn, params)
+		}, true
+	}
+	if !ok {
+		return nil, NewError(CodeMethodNotFound, "method (%s) not found", request.Method)
+	}
+
+	result, err := handler(request.Params)
+	if err != nil {
+		var rpcErr *Error
+		if errors.As(err, &rpcErr) {
+			return nil, rpcErr
+		}
+		log.Debug().Err(err).Str("method", request.Method).Msg("rpc call failed")
+		return nil, NewError(CodeInternalError, "%v", err)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, NewError(CodeInternalError, "failed to encode result")
+	}
+	return data, nil
+}
+
+// errorResponse creates the response of a failed request
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	return &Response{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+// responseID returns the ID of the response to a request, null if the request has none
+func responseID(id json.RawMessage) json.RawMessage {
+	if id == nil {
+		return nullID
+	}
+	return id
+}
+
+// validID checks that a request ID is a string, a number or null
+func validID(id json.RawMessage) bool {
+	switch id[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	default:
+		return false
+	}
+}
+
+// DecodeParams decodes the positional params array into the arguments. The first required arguments
+// must be present, the others are optional and keep their value when missing.
+func DecodeParams(params json.RawMessage, required int, args ...interface{}) error {
+	var values []json.RawMessage
+	if len(params) > 0 && !bytes.Equal(params, nullID) {
+		if err := json.Unmarshal(params, &values); err != nil {
+			return NewError(CodeInvalidParams, "params must be an array")
+		}
+	}
+	if len(values) < required {
+		return NewError(CodeInvalidParams, "missing params, %d required", required)
+	}
+	if len(values) > len(args) {
+		return NewError(CodeInvalidParams, "too many params, %d accepted", len(args))
+	}
+	for i, value := range values {
+		if err := json.Unmarshal(value, args[i]); err != nil {
+			return NewError(CodeInvalidParams, "invalid param %d: %v", i, err)
+		}
+	}
+	return nil
+}
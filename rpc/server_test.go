@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer() *Server {
+	s := NewServer(DefaultConfig)
+	s.Register("add", func(params json.RawMessage) (interface{}, error) {
+		var a, b int
+		if err := DecodeParams(params, 2, &a, &b); err != nil {
+			return nil, err
+		}
+		return a + b, nil
+	})
+	s.Register("fail", func(params json.RawMessage) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	return s
+}
+
+// post sends the body to the server and returns the status code and the response body
+func post(t *testing.T, s *Server, body string) (int, string) {
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	return recorder.Code, strings.TrimSpace(recorder.Body.String())
+}
+
+func TestServerCall(t *testing.T) {
+	s := newTestServer()
+
+	_, body := post(t, s, `{"jsonrpc":"2.0","id":1,"method":"add","params":[1,2]}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":3}`, body)
+	_, body = post(t, s, `{"jsonrpc":"2.0","id":"a","method":"add","params":[1]}`)
+	assert.Contains(t, body, `"code":-32602`)
+	_, body = post(t, s, `{"jsonrpc":"2.0","id":null,"method":"unknown"}`)
+	assert.Contains(t, body, `"id":null`)
+	assert.Contains(t, body, `"code":-32601`)
+	_, body = post(t, s, `{"jsonrpc":"2.0","id":2,"method":"fail"}`)
+	assert.Contains(t, body, `"code":-32603`)
+
+	// Notifications get no response
+	code, body := post(t, s, `{"jsonrpc":"2.0","method":"add","params":[1,2]}`)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Empty(t, body)
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestServerInvalidRequests(t *testing.T) {
+	s := newTestServer()
+
+	_, body := post(t, s, `{"jsonrpc":"2.0","method":`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`, body)
+	_, body = post(t, s, `{"jsonrpc":"1.0","id":1,"method":"add"}`)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"invalid request"}}`, body)
+	_, body = post(t, s, `{"jsonrpc":"2.0","id":{},"method":"add"}`)
+	assert.Contains(t, body, `"code":-32600`)
+	_, body = post(t, s, `1`)
+	assert.Contains(t, body, `"code":-32600`)
+	_, body = post(t, s, `[]`)
+	assert.Contains(t, body, `"code":-32600`)
+}
+
+func TestServerBatch(t *testing.T) {
+	s := newTestServer()
+
+	_, body := post(t, s, `[
+		{"jsonrpc":"2.0","id":1,"method":"add","params":[1,2]},
+		{"jsonrpc":"2.0","method":"add","params":[3,4]},
+		1,
+		{"jsonrpc":"2.0","id":2,"method":"unknown"}
+	]`)
+	var responses []Response
+	assert.NoError(t, json.Unmarshal([]byte(body), &responses))
+	assert.Len(t, responses, 3)
+	assert.Equal(t, json.RawMessage("3"), responses[0].Result)
+	assert.Equal(t, CodeInvalidRequest, responses[1].Error.Code)
+	assert.Equal(t, CodeMethodNotFound, responses[2].Error.Code)
+
+	// A batch of notifications gets no response
+	code, _ := post(t, s, `[{"jsonrpc":"2.0","method":"add","params":[1,2]}]`)
+	assert.Equal(t, http.StatusNoContent, code)
+
+	s.config.MaxBatchSize = 1
+	_, body = post(t, s, `[{"jsonrpc":"2.0","id":1,"method":"add","params":[1,2]},{"jsonrpc":"2.0","id":2,"method":"add","params":[1,2]}]`)
+	assert.Contains(t, body, `"code":-32600`)
+}
@@ -0,0 +1,336 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// Kinds of subscriptions
+const (
+	subscriptionNewHeads   = "newHeads"
+	subscriptionPendingTxs = "newPendingTransactions"
+	subscriptionLogs       = "logs"
+)
+
+// maxConnSubscriptions is the maximum number of subscriptions of a WebSocket connection
+const maxConnSubscriptions = 100
+
+// SubscriptionChain is the blockchain the new heads and logs subscriptions follow
+type SubscriptionChain interface {
+	OnBlockAdded(handler func(b *proto.Block))
+}
+
+// SubscriptionPool is the mempool the pending transactions subscriptions follow
+type SubscriptionPool interface {
+	OnAdd(handler func(tx *proto.Transaction))
+}
+
+// subscription sends the events of its kind to a WebSocket connection
+type subscription struct {
+	id     string
+	kind   string
+	conn   *wsConn
+	filter *logFilter
+}
+
+// notification is the JSON-RPC notification of a subscription event
+type notification struct {
+	JSONRPC string             `json:"jsonrpc"`
+	Method  string             `json:"method"`
+	Params  notificationParams `json:"params"`
+}
+
+// notificationParams holds the subscription ID and the event
+type notificationParams struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+// Subscriptions serves the eth_subscribe and eth_unsubscribe methods on the WebSocket connections.
+// Clients subscribe to the headers of the new blocks (newHeads), the hashes of the transactions added
+// to the mempool (newPendingTransactions) and the logs of the new blocks matching a filter (logs).
+type Subscriptions struct {
+	lock sync.RWMutex
+	subs map[string]*subscription
+}
+
+// NewSubscriptions creates the subscriptions to the events of the chain and the mempool
+func NewSubscriptions(chain SubscriptionChain, mempool SubscriptionPool) *Subscriptions {
+	s := &Subscriptions{
+		subs: make(map[string]*subscription),
+	}
+	chain.OnBlockAdded(s.notifyBlock)
+	mempool.OnAdd(s.notifyTransaction)
+	return s
+}
+
+// Register registers the subscription methods on the server
+func (s *Subscriptions) Register(server *Server) {
+	server.lock.Lock()
+	server.subscriptions = s
+	server.lock.Unlock()
+
+	server.registerConn("eth_subscribe", s.subscribe)
+	server.registerConn("eth_unsubscribe", s.unsubscribe)
+}
+
+// subscribe creates a subscription and returns its ID. Params: [kind, filter?]
+func (s *Subscriptions) subscribe(conn *wsConn, params json.RawMessage) (interface{}, error) {
+	var kind string
+	var filterParam json.RawMessage
+	if err := DecodeParams(params, 1, &kind, &filterParam); err != nil {
+		return nil, err
+	}
+
+	sub := &subscription{kind: kind, conn: conn}
+	switch kind {
+	case subscriptionNewHeads, subscriptionPendingTxs:
+	case subscriptionLogs:
+		filter, err := parseLogFilter(filterParam)
+		if err != nil {
+			return nil, err
+		}
+		sub.filter = filter
+	default:
+		return nil, NewError(CodeInvalidParams, "unknown subscription (%s)", kind)
+	}
+
+	if s.connSubscriptions(conn)+len(conn.pending) >= maxConnSubscriptions {
+		return nil, NewError(CodeInvalidRequest, "too many subscriptions, %d allowed", maxConnSubscriptions)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	sub.id = encodeBytes(id)
+	conn.pending = append(conn.pending, sub)
+	return sub.id, nil
+}
+
+// unsubscribe cancels a subscription of the connection and returns true if it existed. Params: [subscription ID]
+func (s *Subscriptions) unsubscribe(conn *wsConn, params json.RawMessage) (interface{}, error) {
+	var id string
+	if err := DecodeParams(params, 1, &id); err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sub, ok := s.subs[id]
+	if !ok || sub.conn != conn {
+		return false, nil
+	}
+	delete(s.subs, id)
+	return true, nil
+}
+
+// activate starts sending the events to the subscriptions
+func (s *Subscriptions) activate(subs []*subscription) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, sub := range subs {
+		s.subs[sub.id] = sub
+	}
+}
+
+// connSubscriptions returns the number of subscriptions of the connection
+func (s *Subscriptions) connSubscriptions(conn *wsConn) int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	count := 0
+	for _, sub := range s.subs {
+		if sub.conn == conn {
+			count++
+		}
+	}
+	return count
+}
+
+// removeConn cancels the subscriptions of the closed connection
+func (s *Subscriptions) removeConn(conn *wsConn) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, sub := range s.subs {
+		if sub.conn == conn {
+			delete(s.subs, id)
+		}
+	}
+}
+
+// subscriptionsOf returns the subscriptions of the kind
+func (s *Subscriptions) subscriptionsOf(kind string) []*subscription {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var subs []*subscription
+	for _, sub := range s.subs {
+		if sub.kind == kind {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// notifyBlock sends the header of the block and its logs to the subscriptions
+func (s *Subscriptions) notifyBlock(b *proto.Block) {
+	if heads := s.subscriptionsOf(subscriptionNewHeads); len(heads) > 0 {
+		header, err := NewEthHeader(b)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to encode new head")
+			return
+		}
+		notify(heads, header)
+	}
+
+	logSubs := s.subscriptionsOf(subscriptionLogs)
+	if len(logSubs) == 0 {
+		return
+	}
+	logs, err := ethBlockLogs(b)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode block logs")
+		return
+	}
+	for _, sub := range logSubs {
+		for _, l := range logs {
+			if sub.filter.match(l) {
+				notify([]*subscription{sub}, l)
+			}
+		}
+	}
+}
+
+// notifyTransaction sends the hash of the transaction added to the mempool to the subscriptions
+func (s *Subscriptions) notifyTransaction(tx *proto.Transaction) {
+	subs := s.subscriptionsOf(subscriptionPendingTxs)
+	if len(subs) == 0 {
+		return
+	}
+	hash, err := types.HashTransaction(tx)
+	if err != nil {
+		return
+	}
+	notify(subs, encodeBytes(hash))
+}
+
+// notify sends the event to the subscriptions
+func notify(subs []*subscription, event interface{}) {
+	result, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode subscription event")
+		return
+	}
+	for _, sub := range subs {
+		data, err := json.Marshal(notification{
+			JSONRPC: "2.0",
+			Method:  "eth_subscription",
+			Params:  notificationParams{Subscription: sub.id, Result: result},
+		})
+		if err != nil {
+			continue
+		}
+		sub.conn.queue(data)
+	}
+}
+
+// logFilter matches the logs by address and topics. A log matches if its address is one of the addresses,
+// and each of its topics is one of the topics at the same position. No addresses or topics match any.
+type logFilter struct {
+	addresses [][]byte
+	topics    [][][]byte
+}
+
+// logFilterParams is the JSON filter of a logs subscription, the address and each topic are
+// a value, an array of values or null
+type logFilterParams struct {
+	Address json.RawMessage   `json:"address"`
+	Topics  []json.RawMessage `json:"topics"`
+}
+
+// parseLogFilter decodes the filter of a logs subscription, a missing filter matches every log
+func parseLogFilter(raw json.RawMessage) (*logFilter, error) {
+	filter := &logFilter{}
+	if len(raw) == 0 || bytes.Equal(raw, nullID) {
+		return filter, nil
+	}
+	var params logFilterParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, NewError(CodeInvalidParams, "invalid logs filter")
+	}
+
+	addresses, err := decodeHexList(params.Address)
+	if err != nil {
+		return nil, err
+	}
+	filter.addresses = addresses
+	for _, topic := range params.Topics {
+		topics, err := decodeHexList(topic)
+		if err != nil {
+			return nil, err
+		}
+		filter.topics = append(filter.topics, topics)
+	}
+	return filter, nil
+}
+
+// decodeHexList decodes a hex value, an array of hex values or null
+func decodeHexList(raw json.RawMessage) ([][]byte, error) {
+	if len(raw) == 0 || bytes.Equal(raw, nullID) {
+		return nil, nil
+	}
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, NewError(CodeInvalidParams, "invalid logs filter value")
+		}
+		values = []string{value}
+	}
+
+	list := make([][]byte, 0, len(values))
+	for _, value := range values {
+		data, err := decodeHex(value)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, data)
+	}
+	return list, nil
+}
+
+// match returns true if the log matches the filter
+func (f *logFilter) match(l *EthLog) bool {
+	if len(f.addresses) > 0 && !containsBytes(f.addresses, l.address) {
+		return false
+	}
+	if len(f.topics) > len(l.topics) {
+		return false
+	}
+	for i, topics := range f.topics {
+		if len(topics) > 0 && !containsBytes(topics, l.topics[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsBytes returns true if the value is in the list
+func containsBytes(list [][]byte, value []byte) bool {
+	for _, item := range list {
+		if bytes.Equal(item, value) {
+			return true
+		}
+	}
+	return false
+}
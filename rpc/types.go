@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+)
+
+// Block is the JSON encoding of a block. The transactions are their hashes, or the full transactions when requested.
+type Block struct {
+	Hash          string        `json:"hash"`
+	Height        uint64        `json:"height"`
+	PrevBlockHash string        `json:"prevBlockHash"`
+	TxHash        string        `json:"txHash"`
+	Version       uint32        `json:"version"`
+	Timestamp     int64         `json:"timestamp"`
+	Nonce         uint64        `json:"nonce"`
+	Difficulty    uint32        `json:"difficulty"`
+	ExtraData     string        `json:"extraData"`
+	PublicKey     string        `json:"publicKey"`
+	Signature     string        `json:"signature"`
+	Transactions  []interface{} `json:"transactions"`
+}
+
+// Transaction is the JSON encoding of a transaction. The block fields are null while the transaction is pending.
+type Transaction struct {
+	Hash        string  `json:"hash"`
+	Type        string  `json:"type"`
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Value       uint64  `json:"value"`
+	Fee         uint64  `json:"fee"`
+	Nonce       int64   `json:"nonce"`
+	Data        string  `json:"data"`
+	Signature   string  `json:"signature"`
+	BlockHash   *string `json:"blockHash"`
+	BlockHeight *uint64 `json:"blockHeight"`
+	Index       *int    `json:"index"`
+}
+
+// NewBlock encodes the block, with the full transactions or only their hashes
+func NewBlock(b *proto.Block, fullTxs bool) (*Block, error) {
+	hash, err := types.HashBlock(b)
+	if err != nil {
+		return nil, err
+	}
+	header := b.Header
+	block := &Block{
+		Hash:          hex.EncodeToString(hash),
+		Height:        header.GetHeight(),
+		PrevBlockHash: hex.EncodeToString(header.GetPrevBlockHash()),
+		TxHash:        hex.EncodeToString(header.GetTxHash()),
+		Version:       header.GetVersion(),
+		Timestamp:     header.GetTimestamp(),
+		Nonce:         header.GetNonce(),
+		Difficulty:    header.GetDifficulty(),
+		ExtraData:     hex.EncodeToString(header.GetExtraData()),
+		PublicKey:     hex.EncodeToString(b.PublicKey),
+		Signature:     hex.EncodeToString(b.Signature),
+		Transactions:  make([]interface{}, 0, len(b.Transactions)),
+	}
+
+	for i, tx := range b.Transactions {
+		if !fullTxs {
+			txHash, err := types.HashTransaction(tx)
+			if err != nil {
+				return nil, err
+			}
+			block.Transactions = append(block.Transactions, hex.EncodeToString(txHash))
+			continue
+		}
+		encoded, err := NewTransaction(tx, &core.TxLocation{BlockHash: hash, Height: header.GetHeight(), Index: i})
+		if err != nil {
+			return nil, err
+		}
+		block.Transactions = append(block.Transactions, encoded)
+	}
+	return block, nil
+}
+
+// NewTransaction encodes the transaction at the location, a nil location for a pending transaction
+func NewTransaction(tx *proto.Transaction, location *core.TxLocation) (*Transaction, error) {
+	hash, err := types.HashTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	encoded := &Transaction{
+		Hash:      hex.EncodeToString(hash),
+		Type:      strings.ToLower(strings.TrimPrefix(tx.Type.String(), "TX_TYPE_")),
+		From:      hex.EncodeToString(tx.From),
+		To:        hex.EncodeToString(tx.To),
+		Value:     tx.Value,
+		Fee:       tx.Fee,
+		Nonce:     tx.Nonce,
+		Data:      hex.EncodeToString(tx.Data),
+		Signature: hex.EncodeToString(tx.Signature),
+	}
+	if location != nil {
+		blockHash := hex.EncodeToString(location.BlockHash)
+		height, index := location.Height, location.Index
+		encoded.BlockHash, encoded.BlockHeight, encoded.Index = &blockHash, &height, &index
+	}
+	return encoded, nil
+}
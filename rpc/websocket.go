@@ -0,0 +1,151 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// wsWriteTimeout is how long writing a message to a WebSocket connection can take
+	wsWriteTimeout = 10 * time.Second
+	// wsPingInterval is the interval between the pings sent on a WebSocket connection
+	wsPingInterval = 30 * time.Second
+	// wsPongTimeout closes a WebSocket connection after not receiving anything for this long
+	wsPongTimeout = 2 * wsPingInterval
+	// wsSendQueueSize is the number of messages waiting to be written to a WebSocket connection,
+	// a connection too slow to read its messages is closed when its queue is full
+	wsSendQueueSize = 256
+)
+
+// wsConn is a WebSocket connection sending JSON-RPC requests and receiving their responses
+// and the notifications of its subscriptions
+type wsConn struct {
+	server *Server
+	conn   *websocket.Conn
+	send   chan []byte
+	quit   chan struct{}
+	once   sync.Once
+
+	// pending are the subscriptions created by the request being handled, they are activated
+	// once its response is queued so no notification is sent before the subscription ID
+	pending []*subscription
+}
+
+// serveWebSocket upgrades the request to a WebSocket connection and handles its requests until it is closed
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug().Err(err).Msg("websocket upgrade failed")
+		return
+	}
+
+	c := &wsConn{
+		server: s,
+		conn:   conn,
+		send:   make(chan []byte, wsSendQueueSize),
+		quit:   make(chan struct{}),
+	}
+	s.lock.Lock()
+	s.conns[c] = struct{}{}
+	s.lock.Unlock()
+
+	go c.writeLoop()
+	c.readLoop()
+}
+
+// readLoop handles the requests received on the connection
+func (c *wsConn) readLoop() {
+	defer c.close()
+
+	c.conn.SetReadLimit(c.server.config.MaxRequestSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+
+		if response := c.server.handleBody(data, c); response != nil {
+			encoded, err := json.Marshal(response)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to encode rpc response")
+				return
+			}
+			if !c.queue(encoded) {
+				return
+			}
+		}
+		if len(c.pending) > 0 {
+			c.server.subscriptions.activate(c.pending)
+			c.pending = nil
+		}
+	}
+}
+
+// writeLoop writes the queued messages to the connection and pings it periodically
+func (c *wsConn) writeLoop() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				c.close()
+				return
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// queue queues the message to be written, closing the connection if it does not keep up with its messages
+func (c *wsConn) queue(data []byte) bool {
+	select {
+	case <-c.quit:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- data:
+		return true
+	default:
+		log.Debug().Str("addr", c.conn.RemoteAddr().String()).Msg("closing slow websocket connection")
+		c.close()
+		return false
+	}
+}
+
+// close closes the connection and cancels its subscriptions
+func (c *wsConn) close() {
+	c.once.Do(func() {
+		close(c.quit)
+		c.conn.Close()
+
+		c.server.lock.Lock()
+		delete(c.server.conns, c)
+		subscriptions := c.server.subscriptions
+		c.server.lock.Unlock()
+		if subscriptions != nil {
+			subscriptions.removeConn(c)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/joaoh82/marvinblockchain/core"
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/joaoh82/marvinblockchain/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// wsCall sends the request on the connection and returns the response
+func wsCall(t *testing.T, conn *websocket.Conn, method string, params ...interface{}) Response {
+	assert.NoError(t, conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params}))
+	var response Response
+	assert.NoError(t, conn.ReadJSON(&response))
+	return response
+}
+
+// readNotification reads the next subscription notification from the connection
+func readNotification(t *testing.T, conn *websocket.Conn) notificationParams {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg struct {
+		Method string             `json:"method"`
+		Params notificationParams `json:"params"`
+	}
+	assert.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "eth_subscription", msg.Method)
+	return msg.Params
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	bc := core.NewBlockchain(core.NewMemorystore())
+	mempool := core.NewMempool()
+	s := NewServer(DefaultConfig)
+	NewAPI(bc, mempool).Register(s)
+	NewSubscriptions(bc, mempool).Register(s)
+	httpServer := httptest.NewServer(s)
+	defer httpServer.Close()
+
+	// Subscriptions need a WebSocket connection
+	assert.Equal(t, CodeInvalidRequest, call(t, s, "eth_subscribe", "newHeads").Error.Code)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	assert.Nil(t, err)
+	defer conn.Close()
+	assert.Equal(t, json.RawMessage("0"), wsCall(t, conn, "marvin_height").Result)
+	assert.Equal(t, CodeInvalidParams, wsCall(t, conn, "eth_subscribe", "unknown").Error.Code)
+
+	var heads, pending, logs, unused string
+	assert.NoError(t, json.Unmarshal(wsCall(t, conn, "eth_subscribe", "newHeads").Result, &heads))
+	assert.NoError(t, json.Unmarshal(wsCall(t, conn, "eth_subscribe", "newPendingTransactions").Result, &pending))
+	recipient := make([]byte, 20)
+	recipient[0] = 1
+	filter := map[string]interface{}{"topics": []interface{}{nil, nil, encodeBytes(leftPad32(recipient))}}
+	assert.NoError(t, json.Unmarshal(wsCall(t, conn, "eth_subscribe", "logs", filter).Result, &logs))
+	assert.NoError(t, json.Unmarshal(wsCall(t, conn, "eth_subscribe", "newHeads").Result, &unused))
+	assert.Equal(t, json.RawMessage("true"), wsCall(t, conn, "eth_unsubscribe", unused).Result)
+	assert.Equal(t, json.RawMessage("false"), wsCall(t, conn, "eth_unsubscribe", unused).Result)
+
+	producer, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	block, err := bc.BuildBlock(producer, nil)
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	// Only the head is notified, the coinbase log does not match the recipient
+	notification := readNotification(t, conn)
+	assert.Equal(t, heads, notification.Subscription)
+	var header EthHeader
+	assert.NoError(t, json.Unmarshal(notification.Result, &header))
+	assert.Equal(t, "0x1", header.Number)
+
+	tx := &proto.Transaction{From: producer.PublicKey().Bytes(), To: recipient, Value: 10, Fee: 1, Nonce: 1}
+	assert.NoError(t, types.SignTransaction(producer, tx))
+	assert.NoError(t, mempool.Add(tx))
+	hash, err := types.HashTransaction(tx)
+	assert.Nil(t, err)
+	notification = readNotification(t, conn)
+	assert.Equal(t, pending, notification.Subscription)
+	assert.Equal(t, json.RawMessage(`"`+encodeBytes(hash)+`"`), notification.Result)
+
+	block, err = bc.BuildBlock(producer, mempool.Pending())
+	assert.Nil(t, err)
+	assert.NoError(t, bc.AddBlock(block))
+	assert.Equal(t, heads, readNotification(t, conn).Subscription)
+	notification = readNotification(t, conn)
+	assert.Equal(t, logs, notification.Subscription)
+	var l EthLog
+	assert.NoError(t, json.Unmarshal(notification.Result, &l))
+	assert.Equal(t, encodeBytes(hash), l.TransactionHash)
+	assert.Equal(t, "0x2", l.BlockNumber)
+
+	// Closing the connection cancels its subscriptions
+	conn.Close()
+	assert.Eventually(t, func() bool {
+		return len(s.subscriptions.subscriptionsOf(subscriptionNewHeads)) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestLogFilter(t *testing.T) {
+	l := &EthLog{address: make([]byte, 20), topics: [][]byte{{1}, {2}, {3}}}
+
+	filter, err := parseLogFilter(nil)
+	assert.Nil(t, err)
+	assert.True(t, filter.match(l))
+	filter, err = parseLogFilter(json.RawMessage(`{"address":"0x0000000000000000000000000000000000000000","topics":["0x01",null,["0x04","0x03"]]}`))
+	assert.Nil(t, err)
+	assert.True(t, filter.match(l))
+	filter, err = parseLogFilter(json.RawMessage(`{"topics":["0x02"]}`))
+	assert.Nil(t, err)
+	assert.False(t, filter.match(l))
+	filter, err = parseLogFilter(json.RawMessage(`{"topics":[null,null,null,"0x01"]}`))
+	assert.Nil(t, err)
+	assert.False(t, filter.match(l))
+	_, err = parseLogFilter(json.RawMessage(`{"address":1}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,25 @@
+package types
+
+import (
+	"errors"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+)
+
+// AccountAddress returns the address of an account given either its public key or its address
+func AccountAddress(b []byte) (crypto.Address, error) {
+	switch len(b) {
+	case crypto.PublicKeySize:
+		publicKey, err := crypto.PublicKeyFromBytes(b)
+		if err != nil {
+			return crypto.Address{}, err
+		}
+		return publicKey.Address(), nil
+	default:
+		address, err := crypto.AddressFromBytes(b)
+		if err != nil {
+			return crypto.Address{}, errors.New("invalid account public key or address")
+		}
+		return address, nil
+	}
+}
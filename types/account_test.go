@@ -0,0 +1,25 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountAddress(t *testing.T) {
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	expected := privateKey.PublicKey().Address()
+
+	address, err := AccountAddress(privateKey.PublicKey().Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, expected, address)
+
+	address, err = AccountAddress(expected.Bytes())
+	assert.Nil(t, err)
+	assert.Equal(t, expected, address)
+
+	_, err = AccountAddress([]byte("invalid"))
+	assert.Error(t, err)
+}
@@ -36,11 +36,25 @@ func SerializeBlock(b *proto.Block) ([]byte, error) {
 	return data, nil
 }
 
+// DeserializeBlock deserializes a block enforcing the default consensus limits
 func DeserializeBlock(data []byte) (*proto.Block, error) {
+	return DeserializeBlockWithParams(data, DefaultConsensusParams)
+}
+
+// DeserializeBlockWithParams deserializes a block enforcing the limits of the given consensus parameters.
+// The size is checked before unmarshaling so oversized blocks are rejected without being parsed.
+func DeserializeBlockWithParams(data []byte, params ConsensusParams) (*proto.Block, error) {
+	if len(data) > params.MaxBlockSize {
+		return nil, ErrBlockTooLarge
+	}
+
 	b := &proto.Block{}
 	if err := pb.Unmarshal(data, b); err != nil {
 		return nil, errors.New("failed to unmarshal block")
 	}
+	if err := params.ValidateBlock(b); err != nil {
+		return nil, err
+	}
 
 	return b, nil
 }
@@ -64,6 +78,10 @@ func SignBlock(privateKey *crypto.PrivateKey, b *proto.Block) (*crypto.Signature
 func VerifyBlock(b *proto.Block) (bool, error) {
 	// Verify the transactions
 	for _, tx := range b.Transactions {
+		// Coinbase transactions are not signed, their value is validated by the blockchain
+		if IsCoinbase(tx) {
+			continue
+		}
 		isValid, err := VerifyTransaction(tx)
 		if err != nil {
 			return false, err
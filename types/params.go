@@ -0,0 +1,68 @@
+package types
+
+import (
+	"errors"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+	pb "google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrBlockTooLarge       = errors.New("block exceeds the maximum serialized block size")
+	ErrTooManyTransactions = errors.New("block exceeds the maximum number of transactions")
+	ErrTxDataTooLarge      = errors.New("transaction data exceeds the maximum data size")
+	ErrTxTooLarge          = errors.New("transaction exceeds the maximum serialized block size")
+)
+
+// ConsensusParams are the limits on blocks and transactions all nodes must agree on
+type ConsensusParams struct {
+	// MaxBlockSize is the maximum size in bytes of a serialized block
+	MaxBlockSize int
+	// MaxBlockTransactions is the maximum number of transactions in a block, including the coinbase
+	MaxBlockTransactions int
+	// MaxTxDataSize is the maximum length in bytes of the data of a transaction
+	MaxTxDataSize int
+}
+
+// DefaultConsensusParams are the consensus parameters used when none are configured
+var DefaultConsensusParams = ConsensusParams{
+	MaxBlockSize:         2 * 1024 * 1024,
+	MaxBlockTransactions: 10_000,
+	MaxTxDataSize:        32 * 1024,
+}
+
+// ValidateTransaction checks the transaction against the consensus limits. A transaction larger than a block
+// can never be included in one.
+func (p ConsensusParams) ValidateTransaction(tx *proto.Transaction) error {
+	if len(tx.GetData()) > p.MaxTxDataSize {
+		return ErrTxDataTooLarge
+	}
+	if BlockTransactionSize(tx) > p.MaxBlockSize {
+		return ErrTxTooLarge
+	}
+	return nil
+}
+
+// ValidateBlock checks the block against the consensus limits
+func (p ConsensusParams) ValidateBlock(b *proto.Block) error {
+	if len(b.GetTransactions()) > p.MaxBlockTransactions {
+		return ErrTooManyTransactions
+	}
+	for _, tx := range b.GetTransactions() {
+		if err := p.ValidateTransaction(tx); err != nil {
+			return err
+		}
+	}
+	if pb.Size(b) > p.MaxBlockSize {
+		return ErrBlockTooLarge
+	}
+	return nil
+}
+
+// BlockTransactionSize returns the number of bytes the transaction adds to a serialized block
+func BlockTransactionSize(tx *proto.Transaction) int {
+	// Transactions are encoded as a length delimited repeated field (number 2) of the block
+	size := pb.Size(tx)
+	return protowire.SizeTag(2) + protowire.SizeBytes(size)
+}
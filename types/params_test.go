@@ -0,0 +1,63 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsensusParamsValidateBlock(t *testing.T) {
+	params := ConsensusParams{MaxBlockSize: 4096, MaxBlockTransactions: 2, MaxTxDataSize: 8}
+
+	b := &proto.Block{
+		Header:       &proto.Header{Height: 1},
+		Transactions: []*proto.Transaction{{Data: []byte("data")}},
+	}
+	assert.NoError(t, params.ValidateBlock(b))
+
+	b.Transactions[0].Data = []byte("too much data")
+	assert.ErrorIs(t, params.ValidateBlock(b), ErrTxDataTooLarge)
+
+	b.Transactions = []*proto.Transaction{{}, {}, {}}
+	assert.ErrorIs(t, params.ValidateBlock(b), ErrTooManyTransactions)
+
+	b.Transactions = []*proto.Transaction{{To: make([]byte, 5000)}}
+	assert.ErrorIs(t, params.ValidateBlock(b), ErrTxTooLarge)
+
+	b.Transactions = []*proto.Transaction{{To: make([]byte, 2500)}, {To: make([]byte, 2500)}}
+	assert.ErrorIs(t, params.ValidateBlock(b), ErrBlockTooLarge)
+}
+
+func TestDeserializeBlockLimits(t *testing.T) {
+	params := ConsensusParams{MaxBlockSize: 1024, MaxBlockTransactions: 1, MaxTxDataSize: 8}
+
+	// Oversized data is rejected before it is parsed
+	_, err := DeserializeBlockWithParams(make([]byte, 1025), params)
+	assert.ErrorIs(t, err, ErrBlockTooLarge)
+
+	b := &proto.Block{
+		Header:       &proto.Header{Height: 1},
+		Transactions: []*proto.Transaction{{Data: []byte("data")}, {Data: []byte("data")}},
+	}
+	data, err := SerializeBlock(b)
+	assert.Nil(t, err)
+	_, err = DeserializeBlockWithParams(data, params)
+	assert.ErrorIs(t, err, ErrTooManyTransactions)
+
+	_, err = DeserializeBlock(data)
+	assert.Nil(t, err)
+}
+
+func TestDeserializeTransactionLimits(t *testing.T) {
+	params := ConsensusParams{MaxBlockSize: 1024, MaxBlockTransactions: 1, MaxTxDataSize: 8}
+
+	data, err := SerializeTransaction(&proto.Transaction{Data: []byte("too much data")})
+	assert.Nil(t, err)
+	_, err = DeserializeTransactionWithParams(data, params)
+	assert.ErrorIs(t, err, ErrTxDataTooLarge)
+
+	// Oversized data is rejected as a transaction error before it is parsed
+	_, err = DeserializeTransactionWithParams(make([]byte, 1025), params)
+	assert.ErrorIs(t, err, ErrTxTooLarge)
+}
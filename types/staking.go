@@ -0,0 +1,98 @@
+package types
+
+import (
+	"errors"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// NewBondTransaction creates an unsigned transaction bonding amount of the sender to the validator public key.
+// Bonding to the sender own public key makes the sender a validator.
+func NewBondTransaction(from []byte, validator []byte, amount uint64, nonce int64) *proto.Transaction {
+	return &proto.Transaction{
+		From:  from,
+		To:    validator,
+		Value: amount,
+		Nonce: nonce,
+		Type:  proto.TxType_TX_TYPE_BOND,
+	}
+}
+
+// NewUnbondTransaction creates an unsigned transaction unbonding amount of the sender from the validator public key
+func NewUnbondTransaction(from []byte, validator []byte, amount uint64, nonce int64) *proto.Transaction {
+	return &proto.Transaction{
+		From:  from,
+		To:    validator,
+		Value: amount,
+		Nonce: nonce,
+		Type:  proto.TxType_TX_TYPE_UNBOND,
+	}
+}
+
+// NewEvidenceTransaction creates an unsigned transaction submitting the double signing evidence
+func NewEvidenceTransaction(from []byte, evidence *proto.DoubleSignEvidence, nonce int64) (*proto.Transaction, error) {
+	data, err := pb.Marshal(evidence)
+	if err != nil {
+		return nil, errors.New("failed to marshal evidence")
+	}
+
+	return &proto.Transaction{
+		From:  from,
+		Data:  data,
+		Nonce: nonce,
+		Type:  proto.TxType_TX_TYPE_EVIDENCE,
+	}, nil
+}
+
+// DeserializeEvidence deserializes the double signing evidence of an evidence transaction
+func DeserializeEvidence(data []byte) (*proto.DoubleSignEvidence, error) {
+	evidence := &proto.DoubleSignEvidence{}
+	if err := pb.Unmarshal(data, evidence); err != nil {
+		return nil, errors.New("failed to unmarshal evidence")
+	}
+
+	return evidence, nil
+}
+
+// VerifyEvidence checks that the evidence holds two different headers at the same height both signed by its public key
+func VerifyEvidence(evidence *proto.DoubleSignEvidence) error {
+	if evidence.HeaderA == nil || evidence.HeaderB == nil {
+		return errors.New("evidence is missing a header")
+	}
+	if evidence.HeaderA.Height != evidence.HeaderB.Height {
+		return errors.New("evidence headers are at different heights")
+	}
+
+	publicKey, err := crypto.PublicKeyFromBytes(evidence.PublicKey)
+	if err != nil {
+		return err
+	}
+	hashA, err := HashHeader(evidence.HeaderA)
+	if err != nil {
+		return err
+	}
+	hashB, err := HashHeader(evidence.HeaderB)
+	if err != nil {
+		return err
+	}
+	if string(hashA) == string(hashB) {
+		return errors.New("evidence headers are the same")
+	}
+
+	for _, signed := range []struct {
+		hash      []byte
+		signature []byte
+	}{{hashA, evidence.SignatureA}, {hashB, evidence.SignatureB}} {
+		signature, err := crypto.SignatureFromBytes(signed.signature)
+		if err != nil {
+			return err
+		}
+		if !signature.Verify(publicKey, signed.hash) {
+			return errors.New("invalid evidence header signature")
+		}
+	}
+
+	return nil
+}
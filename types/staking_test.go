@@ -0,0 +1,55 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/joaoh82/marvinblockchain/crypto"
+	"github.com/joaoh82/marvinblockchain/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyEvidence(t *testing.T) {
+	privateKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	headerA := &proto.Header{Height: 10, Timestamp: 1}
+	headerB := &proto.Header{Height: 10, Timestamp: 2}
+	evidence := &proto.DoubleSignEvidence{
+		PublicKey:  privateKey.PublicKey().Bytes(),
+		HeaderA:    headerA,
+		SignatureA: signHeader(t, privateKey, headerA),
+		HeaderB:    headerB,
+		SignatureB: signHeader(t, privateKey, headerB),
+	}
+	assert.NoError(t, VerifyEvidence(evidence))
+
+	tx, err := NewEvidenceTransaction(privateKey.PublicKey().Bytes(), evidence, 1)
+	assert.Nil(t, err)
+	decoded, err := DeserializeEvidence(tx.Data)
+	assert.Nil(t, err)
+	assert.NoError(t, VerifyEvidence(decoded))
+
+	// The same header twice is not evidence
+	evidence.HeaderB, evidence.SignatureB = headerA, evidence.SignatureA
+	assert.Error(t, VerifyEvidence(evidence))
+
+	// Headers at different heights are not evidence
+	headerB.Height = 11
+	evidence.HeaderB, evidence.SignatureB = headerB, signHeader(t, privateKey, headerB)
+	assert.Error(t, VerifyEvidence(evidence))
+
+	// Headers signed by another key are not evidence
+	otherKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	headerB.Height = 10
+	evidence.SignatureB = signHeader(t, otherKey, headerB)
+	assert.Error(t, VerifyEvidence(evidence))
+}
+
+func signHeader(t *testing.T, privateKey *crypto.PrivateKey, h *proto.Header) []byte {
+	hash, err := HashHeader(h)
+	assert.Nil(t, err)
+	signature, err := privateKey.Sign(hash)
+	assert.Nil(t, err)
+	return signature.Bytes()
+}
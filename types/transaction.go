@@ -2,6 +2,7 @@ package types
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 
 	"github.com/joaoh82/marvinblockchain/crypto"
@@ -18,18 +19,34 @@ func SerializeTransaction(tx *proto.Transaction) ([]byte, error) {
 	return data, nil
 }
 
+// DeserializeTransaction deserializes a transaction enforcing the default consensus limits
 func DeserializeTransaction(data []byte) (*proto.Transaction, error) {
+	return DeserializeTransactionWithParams(data, DefaultConsensusParams)
+}
+
+// DeserializeTransactionWithParams deserializes a transaction enforcing the limits of the given consensus parameters
+func DeserializeTransactionWithParams(data []byte, params ConsensusParams) (*proto.Transaction, error) {
+	if len(data) > params.MaxBlockSize {
+		return nil, ErrTxTooLarge
+	}
+
 	tx := &proto.Transaction{}
 	if err := pb.Unmarshal(data, tx); err != nil {
 		return nil, errors.New("failed to unmarshal header")
 	}
+	if err := params.ValidateTransaction(tx); err != nil {
+		return nil, err
+	}
 
 	return tx, nil
 }
 
-// SignTransaction signs a transaction with a private key.
+// SignTransaction signs a transaction with a private key and sets its hash to its ID, the hash of the signed transaction.
 func SignTransaction(pk *crypto.PrivateKey, tx *proto.Transaction) error {
-	hash, err := HashTransaction(tx)
+	// The sender is part of the signed data
+	tx.From = pk.PublicKey().Bytes()
+
+	hash, err := hashTransaction(tx, false)
 	if err != nil {
 		return err
 	}
@@ -38,43 +55,48 @@ func SignTransaction(pk *crypto.PrivateKey, tx *proto.Transaction) error {
 	if err != nil {
 		return err
 	}
-
 	tx.Signature = sig.Bytes()
-	tx.Hash = hash
-	tx.From = pk.PublicKey().Bytes()
+
+	id, err := HashTransaction(tx)
+	if err != nil {
+		return err
+	}
+	tx.Hash = id
 
 	return nil
 }
 
-// HashTransaction hashes a transaction.
+// HashTransaction returns the ID of a transaction, the hash of the signed transaction without its hash field.
+// The transaction is not modified, so it can be hashed while it is shared between goroutines.
 func HashTransaction(tx *proto.Transaction) ([]byte, error) {
-	b, err := pb.Marshal(tx)
+	return hashTransaction(tx, true)
+}
+
+// hashTransaction hashes a clone of the transaction without its hash field,
+// and without its signature when the hash is the data being signed
+func hashTransaction(tx *proto.Transaction, signed bool) ([]byte, error) {
+	clone := pb.Clone(tx).(*proto.Transaction)
+	clone.Hash = nil
+	if !signed {
+		clone.Signature = nil
+	}
+
+	b, err := pb.Marshal(clone)
 	if err != nil {
 		return nil, err
 	}
 	hash := sha256.Sum256(b)
 
-	tx.Hash = hash[:]
-
 	return hash[:], nil
 }
 
 // VerifyTransaction verifies the signature of a transaction.
 func VerifyTransaction(tx *proto.Transaction) (bool, error) {
-	// Temporarily remove the signature to calculate the hash
-	tempSig := tx.Signature
-	tempHash := tx.Hash
-	tx.Signature = nil
-	tx.Hash = nil
-
 	// Calculate the hash without the signature and hash
-	hash, err := HashTransaction(tx)
+	hash, err := hashTransaction(tx, false)
 	if err != nil {
 		return false, err
 	}
-	// Restore the signature
-	tx.Signature = tempSig
-	tx.Hash = tempHash
 
 	signature, err := crypto.SignatureFromBytes(tx.Signature)
 	if err != nil {
@@ -88,3 +110,51 @@ func VerifyTransaction(tx *proto.Transaction) (bool, error) {
 
 	return isValid, nil
 }
+
+// NewCoinbaseTransaction creates the coinbase transaction of the block at the given height,
+// paying value to the block producer. The height is encoded in the data so every coinbase has a unique hash.
+func NewCoinbaseTransaction(to []byte, value uint64, height uint64) (*proto.Transaction, error) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, height)
+
+	tx := &proto.Transaction{
+		To:    to,
+		Value: value,
+		Data:  data,
+		Type:  proto.TxType_TX_TYPE_COINBASE,
+	}
+	hash, err := HashTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	tx.Hash = hash
+
+	return tx, nil
+}
+
+// IsCoinbase returns true if the transaction is a coinbase transaction.
+func IsCoinbase(tx *proto.Transaction) bool {
+	return tx.GetType() == proto.TxType_TX_TYPE_COINBASE
+}
+
+// NewVoteTransaction creates an unsigned vote transaction from an authority to add (authorize)
+// or remove the candidate public key from the authorities of a Proof-of-Authority chain.
+func NewVoteTransaction(from []byte, candidate []byte, authorize bool, nonce int64) *proto.Transaction {
+	vote := byte(0)
+	if authorize {
+		vote = 1
+	}
+
+	return &proto.Transaction{
+		From:  from,
+		To:    candidate,
+		Data:  []byte{vote},
+		Nonce: nonce,
+		Type:  proto.TxType_TX_TYPE_VOTE,
+	}
+}
+
+// IsVote returns true if the transaction is a Proof-of-Authority vote transaction.
+func IsVote(tx *proto.Transaction) bool {
+	return tx.GetType() == proto.TxType_TX_TYPE_VOTE
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/joaoh82/marvinblockchain/crypto"
 	"github.com/joaoh82/marvinblockchain/proto"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 func TestSignTransactionV2(t *testing.T) {
@@ -55,3 +56,112 @@ func TestSignTransactionV2InvalidSignature(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, isValid)
 }
+
+func TestHashTransactionIsStable(t *testing.T) {
+	privKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	tx := &proto.Transaction{Value: 42, Nonce: 1}
+	assert.Nil(t, SignTransaction(privKey, tx))
+	assert.Equal(t, privKey.PublicKey().Bytes(), tx.From)
+
+	// The ID set when signing is the hash of the signed transaction
+	hash, err := HashTransaction(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, tx.Hash, hash)
+	tx.Hash = []byte("forged")
+	again, err := HashTransaction(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, again)
+	assert.Equal(t, []byte("forged"), tx.Hash)
+
+	isValid, err := VerifyTransaction(tx)
+	assert.Nil(t, err)
+	assert.True(t, isValid)
+
+	// Signing again replaces the previous signature instead of signing it
+	assert.Nil(t, SignTransaction(privKey, tx))
+	isValid, err = VerifyTransaction(tx)
+	assert.Nil(t, err)
+	assert.True(t, isValid)
+}
+
+func TestHashTransactionCoversEveryField(t *testing.T) {
+	empty := &proto.Transaction{}
+	id, err := HashTransaction(empty)
+	assert.Nil(t, err)
+	signed, err := hashTransaction(empty, false)
+	assert.Nil(t, err)
+
+	// Every field but the hash is part of the ID, and every field but the hash and the signature is signed
+	fields := empty.ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		tx := &proto.Transaction{}
+		switch field.Kind() {
+		case protoreflect.BytesKind:
+			tx.ProtoReflect().Set(field, protoreflect.ValueOfBytes([]byte{1}))
+		case protoreflect.Uint64Kind:
+			tx.ProtoReflect().Set(field, protoreflect.ValueOfUint64(1))
+		case protoreflect.Int64Kind:
+			tx.ProtoReflect().Set(field, protoreflect.ValueOfInt64(1))
+		case protoreflect.EnumKind:
+			tx.ProtoReflect().Set(field, protoreflect.ValueOfEnum(1))
+		default:
+			t.Fatalf("no test value for field %s of kind %s", field.Name(), field.Kind())
+		}
+
+		txID, err := HashTransaction(tx)
+		assert.Nil(t, err)
+		txSigned, err := hashTransaction(tx, false)
+		assert.Nil(t, err)
+		switch field.Name() {
+		case "hash":
+			assert.Equal(t, id, txID)
+			assert.Equal(t, signed, txSigned)
+		case "signature":
+			assert.NotEqual(t, id, txID)
+			assert.Equal(t, signed, txSigned)
+		default:
+			assert.NotEqual(t, id, txID, "field %s", field.Name())
+			assert.NotEqual(t, signed, txSigned, "field %s", field.Name())
+		}
+	}
+}
+
+func TestNewCoinbaseTransaction(t *testing.T) {
+	privKey, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	tx, err := NewCoinbaseTransaction(privKey.PublicKey().Bytes(), 50, 1)
+	assert.Nil(t, err)
+	assert.True(t, IsCoinbase(tx))
+	assert.Equal(t, uint64(50), tx.Value)
+	assert.NotNil(t, tx.Hash)
+
+	// Coinbase transactions at different heights have different hashes
+	other, err := NewCoinbaseTransaction(privKey.PublicKey().Bytes(), 50, 2)
+	assert.Nil(t, err)
+	assert.NotEqual(t, tx.Hash, other.Hash)
+
+	assert.False(t, IsCoinbase(&proto.Transaction{}))
+}
+
+func TestNewVoteTransaction(t *testing.T) {
+	voter, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+	candidate, err := crypto.GeneratePrivateKey()
+	assert.Nil(t, err)
+
+	tx := NewVoteTransaction(voter.PublicKey().Bytes(), candidate.PublicKey().Bytes(), true, 1)
+	assert.True(t, IsVote(tx))
+	assert.Equal(t, []byte{1}, tx.Data)
+	assert.Nil(t, SignTransaction(voter, tx))
+
+	isValid, err := VerifyTransaction(tx)
+	assert.Nil(t, err)
+	assert.True(t, isValid)
+
+	tx = NewVoteTransaction(voter.PublicKey().Bytes(), candidate.PublicKey().Bytes(), false, 2)
+	assert.Equal(t, []byte{0}, tx.Data)
+}